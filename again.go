@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/antonmedv/countdown/logbook"
+)
+
+// againChoices is how many matches "countdown again" offers to pick from.
+const againChoices = 9
+
+// pastSession is a distinct tag, notes and duration from the log.
+type pastSession struct {
+	tag      string
+	notes    string
+	duration time.Duration
+	last     time.Time
+}
+
+func (s pastSession) String() string {
+	str := s.tag + "  " + s.duration.String()
+	if s.notes != "" {
+		str += "  " + s.notes
+	}
+	return str
+}
+
+// pastSessions lists the distinct sessions in the log, most recent first.
+// Durations are rounded to the minute, as a 25m timer is logged as
+// anything from 25m0s to 25m1s.
+func pastSessions(path string) ([]pastSession, error) {
+	events, err := logbook.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	sessions := logbook.Sessions(events)
+	seen := make(map[pastSession]bool)
+	var past []pastSession
+	for i := len(sessions) - 1; i >= 0; i-- {
+		s := sessions[i]
+		d := s.Active().Round(time.Second)
+		if d >= time.Minute {
+			d = d.Round(time.Minute)
+		}
+		if d <= 0 {
+			continue
+		}
+		key := pastSession{tag: s.Tag, notes: s.Notes, duration: d}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		key.last = s.Start
+		past = append(past, key)
+	}
+	return past, nil
+}
+
+// fuzzyMatch reports whether every word of query appears in s in order,
+// not necessarily contiguously, ignoring case: "rv 45" matches
+// "Review  45m0s".
+func fuzzyMatch(s, query string) bool {
+	s = strings.ToLower(s)
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		i := 0
+		for _, r := range word {
+			j := strings.IndexRune(s[i:], r)
+			if j < 0 {
+				return false
+			}
+			i += j + utf8.RuneLen(r)
+		}
+	}
+	return true
+}
+
+// pickSession finds the past sessions matching query and, on a terminal,
+// asks which one to run again; otherwise the most recent match is taken.
+func pickSession(path, query string) (pastSession, error) {
+	past, err := pastSessions(path)
+	if err != nil {
+		return pastSession{}, err
+	}
+	var matches []pastSession
+	for _, s := range past {
+		if fuzzyMatch(s.String(), query) {
+			matches = append(matches, s)
+		}
+	}
+	if len(matches) == 0 {
+		return pastSession{}, fmt.Errorf("no past session matches %q", query)
+	}
+	if len(matches) == 1 || !isInteractive() {
+		return matches[0], nil
+	}
+	if len(matches) > againChoices {
+		matches = matches[:againChoices]
+	}
+	for i, s := range matches {
+		stderr("%d) %s  (%s)\n", i+1, s, s.last.Format("Mon Jan 2 15:04"))
+	}
+	stderr("Run which session? [1] ")
+	answer, _ := stdin.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return matches[0], nil
+	}
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > len(matches) {
+		return pastSession{}, fmt.Errorf("expected a number from 1 to %d", len(matches))
+	}
+	return matches[n-1], nil
+}
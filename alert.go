@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	alertTimeout = 5 * time.Second
+	flashes      = 5
+	flashLength  = 300 * time.Millisecond
+)
+
+// AlertProfile is a set of channels that all signal completion at once,
+// for when one of them may go unnoticed: a flashing screen, the bell, a
+// desktop notification, speech and a webhook. Profiles are defined under
+// [alerts] in the config and picked with -alert.
+type AlertProfile struct {
+	Flash  bool `toml:"flash,omitempty"`
+	Bell   bool `toml:"bell,omitempty"`
+	Notify bool `toml:"notify,omitempty"`
+	Speak  bool `toml:"speak,omitempty"`
+	// Webhook gets a JSON POST with the event, tag, notes and time,
+	// queued in the outbox while it cannot be reached.
+	Webhook string `toml:"webhook,omitempty"`
+}
+
+// alertAll is the built-in "all" profile, every channel that needs no
+// setup; the config may redefine it, e.g. to add a webhook.
+var alertAll = AlertProfile{Flash: true, Bell: true, Notify: true, Speak: true}
+
+// alertProfile is the profile of -alert, the zero one without it.
+var alertProfile AlertProfile
+
+func lookupAlert(name string, profiles map[string]AlertProfile) (AlertProfile, error) {
+	if p, ok := profiles[name]; ok {
+		return p, nil
+	}
+	if name == "all" {
+		return alertAll, nil
+	}
+	return AlertProfile{}, fmt.Errorf("no alert profile %q in the config", name)
+}
+
+// alertEvents returns the subscriber that signals a completed session on
+// the profile's channels other than the screen, which finish flashes.
+func alertEvents(p AlertProfile) func(Event) {
+	var notes string
+	return func(e Event) {
+		switch e.Kind {
+		case SessionStarted:
+			notes = e.Notes
+			return
+		case SessionEnded:
+			if !e.Completed {
+				return
+			}
+		default:
+			return
+		}
+		if p.Bell {
+			ringBell(bells)
+		}
+		if p.Notify {
+			_ = desktopNotify("countdown: "+e.Tag+" is done", notes)
+		}
+		if p.Speak {
+			speak(e.Tag + " is done")
+		}
+		if p.Webhook != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+			_ = deliverJSON(ctx, http.MethodPost, p.Webhook, map[string]interface{}{
+				"event": "finish",
+				"tag":   e.Tag,
+				"notes": notes,
+				"time":  e.Time,
+			})
+			cancel()
+		}
+	}
+}
+
+// flashScreen inverts the whole screen a few times; any key stops it.
+func flashScreen() {
+	w, h := screen.Size()
+	for i := 0; i < 2*flashes; i++ {
+		clear()
+		if i%2 == 0 {
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					setCell(x, y, ' ', tcell.StyleDefault.Reverse(true))
+				}
+			}
+		}
+		flush()
+		select {
+		case <-time.After(flashLength):
+		case ev := <-queues:
+			if _, ok := ev.(*tcell.EventKey); ok {
+				return
+			}
+		}
+	}
+}
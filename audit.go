@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/antonmedv/countdown/logbook"
+)
+
+// verifyCommand checks the hash chain of an audited log, see -audit, and
+// prints the hash of its last event to keep as a receipt.
+func verifyCommand(logPath string) {
+	events, err := logbook.Read(logPath)
+	if err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(1)
+	}
+	covered, head, err := logbook.Verify(events)
+	if err != nil {
+		stderr("error: %s: %v\n", logPath, err)
+		os.Exit(1)
+	}
+	if covered == 0 {
+		stderr("error: %s has no hashed events, log with -audit\n", logPath)
+		os.Exit(1)
+	}
+	fmt.Printf("%s verified, last hash %s\n", plural(covered, "event"), head)
+	if before := len(events) - covered; before > 0 {
+		fmt.Printf("not covered: %s from before auditing started\n", plural(before, "event"))
+	}
+}
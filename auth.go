@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/term"
+)
+
+const authUsage = `
+ countdown auth add <service>
+ countdown auth get <service>
+ countdown auth remove <service>
+
+ Tokens of integrations, e.g. lifx, toggl, jira or pushover, are kept in the
+ macOS Keychain or the Secret Service (secret-tool) and else in a file
+ encrypted with a passphrase, from COUNTDOWN_PASSPHRASE or asked for.
+ add reads the token from stdin when it is not a terminal.
+`
+
+// keyringService names countdown's entries in the OS keyring; the
+// account is the integration.
+const keyringService = "countdown"
+
+// credentialRounds is the PBKDF2 iteration count of the credentials file.
+const credentialRounds = 210000
+
+var serviceName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// ErrNoToken is returned by a keyring without a token for the service.
+var ErrNoToken = errors.New("no token")
+
+// keyring keeps the tokens of the integrations.
+type keyring interface {
+	set(service, token string) error
+	get(service string) (string, error)
+	remove(service string) error
+}
+
+// openKeyring picks the OS keyring, and the credentials file where there
+// is none.
+func openKeyring() keyring {
+	if runtime.GOOS == "darwin" {
+		if _, err := exec.LookPath("security"); err == nil {
+			return keychain{}
+		}
+	}
+	if runtime.GOOS != "windows" && os.Getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+		if _, err := exec.LookPath("secret-tool"); err == nil {
+			return secretService{}
+		}
+	}
+	return &credentialFile{path: credentialsPath()}
+}
+
+// keyringAccount keeps every profile's tokens apart.
+func keyringAccount(service string) string {
+	if profile != "" {
+		return profile + "/" + service
+	}
+	return service
+}
+
+// keychain is the macOS Keychain, through the security command. Commands
+// go on stdin, so the token never shows in the process list.
+type keychain struct{}
+
+func (keychain) run(line string) ([]byte, error) {
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(line + "\n")
+	return cmd.Output()
+}
+
+func (k keychain) set(service, token string) error {
+	_, err := k.run(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s",
+		keychainQuote(keyringService), keychainQuote(keyringAccount(service)), keychainQuote(token)))
+	return err
+}
+
+func (keychain) get(service string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", keyringService, "-a", keyringAccount(service), "-w").Output()
+	if err != nil {
+		return "", ErrNoToken
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (keychain) remove(service string) error {
+	if err := exec.Command("security", "delete-generic-password", "-s", keyringService, "-a", keyringAccount(service)).Run(); err != nil {
+		return ErrNoToken
+	}
+	return nil
+}
+
+func keychainQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// secretService is the freedesktop Secret Service (GNOME Keyring,
+// KWallet), through secret-tool, which reads the token on stdin.
+type secretService struct{}
+
+func (secretService) set(service, token string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", "countdown "+service,
+		"application", keyringService, "service", keyringAccount(service))
+	cmd.Stdin = strings.NewReader(token)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool: %s", bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+func (secretService) get(service string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "application", keyringService, "service", keyringAccount(service)).Output()
+	if err != nil || len(out) == 0 {
+		return "", ErrNoToken
+	}
+	return string(out), nil
+}
+
+func (s secretService) remove(service string) error {
+	if _, err := s.get(service); err != nil {
+		return err
+	}
+	return exec.Command("secret-tool", "clear", "application", keyringService, "service", keyringAccount(service)).Run()
+}
+
+// credentialsPath is next to the config file, so every profile has its
+// own.
+func credentialsPath() string {
+	path := configPath()
+	if path == "" {
+		return ""
+	}
+	return strings.TrimSuffix(path, ".toml") + ".credentials"
+}
+
+// credentialFile keeps the tokens in a file sealed with AES-GCM, under a
+// key derived from the passphrase with PBKDF2-SHA256.
+type credentialFile struct {
+	path       string
+	passphrase []byte
+}
+
+type sealedCredentials struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// key derives the key of the file from the passphrase, asking for it
+// the first time; confirm asks twice, for a new file.
+func (f *credentialFile) key(salt []byte, confirm bool) ([]byte, error) {
+	if f.passphrase == nil {
+		p, err := readPassphrase(confirm)
+		if err != nil {
+			return nil, err
+		}
+		f.passphrase = p
+	}
+	return pbkdf2.Key(f.passphrase, salt, credentialRounds, 32, sha256.New), nil
+}
+
+func (f *credentialFile) load() (map[string]string, error) {
+	b, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s sealedCredentials
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("%s: %v", f.path, err)
+	}
+	key, err := f.key(s.Salt, false)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, s.Nonce, s.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: wrong passphrase", f.path)
+	}
+	tokens := map[string]string{}
+	return tokens, json.Unmarshal(plain, &tokens)
+}
+
+func (f *credentialFile) save(tokens map[string]string) error {
+	plain, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	s := sealedCredentials{Salt: make([]byte, 16)}
+	if _, err := rand.Read(s.Salt); err != nil {
+		return err
+	}
+	// The passphrase is only unknown here for a file that is new.
+	key, err := f.key(s.Salt, true)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	s.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(s.Nonce); err != nil {
+		return err
+	}
+	s.Data = gcm.Seal(nil, s.Nonce, plain, nil)
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, b, 0600)
+}
+
+func (f *credentialFile) set(service, token string) error {
+	tokens, err := f.load()
+	if err != nil {
+		return err
+	}
+	tokens[service] = token
+	return f.save(tokens)
+}
+
+func (f *credentialFile) get(service string) (string, error) {
+	tokens, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	token, ok := tokens[service]
+	if !ok {
+		return "", ErrNoToken
+	}
+	return token, nil
+}
+
+func (f *credentialFile) remove(service string) error {
+	tokens, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := tokens[service]; !ok {
+		return ErrNoToken
+	}
+	delete(tokens, service)
+	return f.save(tokens)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readPassphrase takes the credentials passphrase from
+// COUNTDOWN_PASSPHRASE or asks for it on the terminal, twice with confirm
+// for a new credentials file, so a typo does not lock the tokens away.
+func readPassphrase(confirm bool) ([]byte, error) {
+	if p := os.Getenv("COUNTDOWN_PASSPHRASE"); p != "" {
+		return []byte(p), nil
+	}
+	p, err := readSecret("Passphrase for the countdown credentials: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("the credentials need a passphrase, see COUNTDOWN_PASSPHRASE")
+	}
+	if confirm {
+		again, err := readSecret("Passphrase again: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(p, again) {
+			return nil, fmt.Errorf("the passphrases differ")
+		}
+	}
+	return p, nil
+}
+
+// readSecret reads a line without echo from the terminal, or plainly
+// from stdin when it is not one.
+func readSecret(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		stderr(prompt)
+		defer stderr("\n")
+		return term.ReadPassword(fd)
+	}
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return nil, err
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
+
+// lookupToken is the stored token of service, or empty without one.
+func lookupToken(service string) string {
+	token, err := openKeyring().get(service)
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+func authCommand(args []string) {
+	fs := flag.NewFlagSet("auth", flag.ExitOnError)
+	fs.StringVar(&profile, "profile", os.Getenv("COUNTDOWN_PROFILE"), "use the tokens of a profile")
+	fs.Usage = func() {
+		stderr(authUsage)
+	}
+	_ = fs.Parse(args)
+	args = fs.Args()
+	if profile != "" && !validProfile(profile) {
+		stderr("error: invalid profile %q\n", profile)
+		os.Exit(2)
+	}
+	if len(args) != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	service := args[1]
+	if !serviceName.MatchString(service) {
+		stderr("error: invalid service %q\n", service)
+		os.Exit(2)
+	}
+
+	k := openKeyring()
+	var err error
+	switch args[0] {
+	case "add":
+		var token []byte
+		if token, err = readSecret(fmt.Sprintf("Token for %s: ", service)); err == nil {
+			if len(token) == 0 {
+				stderr("error: empty token\n")
+				os.Exit(2)
+			}
+			err = k.set(service, string(token))
+		}
+	case "get":
+		var token string
+		if token, err = k.get(service); err == nil {
+			fmt.Println(token)
+		}
+	case "remove":
+		err = k.remove(service)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	if errors.Is(err, ErrNoToken) {
+		stderr("error: no token for %s\n", service)
+		os.Exit(1)
+	}
+	if err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(1)
+	}
+}
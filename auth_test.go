@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sealedByEarlierVersions is {"lifx":"c0ffee"} sealed under "correct
+// horse" by the PBKDF2 countdown had of its own.
+const sealedByEarlierVersions = `{"salt":"MDEyMzQ1Njc4OWFiY2RlZg==","nonce":"MDEyMzQ1Njc4OWFi","data":"0x4H0MZbN4QkSfONy30tl3YJoQhKs49tcKr11MsNrSHt"}`
+
+func TestCredentialFile(t *testing.T) {
+	t.Setenv("COUNTDOWN_PASSPHRASE", "correct horse")
+	path := filepath.Join(t.TempDir(), "config.credentials")
+	if err := os.WriteFile(path, []byte(sealedByEarlierVersions), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f := &credentialFile{path: path}
+	if token, err := f.get("lifx"); err != nil || token != "c0ffee" {
+		t.Fatalf("get lifx = %q, %v, want c0ffee", token, err)
+	}
+	if err := f.set("toggl", "t0ken"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := &credentialFile{path: path}
+	if token, err := reopened.get("toggl"); err != nil || token != "t0ken" {
+		t.Errorf("get toggl after set = %q, %v, want t0ken", token, err)
+	}
+
+	t.Setenv("COUNTDOWN_PASSPHRASE", "wrong horse")
+	if _, err := (&credentialFile{path: path}).get("toggl"); err == nil {
+		t.Error("get with the wrong passphrase: want an error")
+	}
+}
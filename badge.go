@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"time"
+
+	"github.com/antonmedv/countdown/render"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	badgeHeight   = 20
+	badgeFontSize = 11
+	// badgeCharWidth is the advance of the monospace font at
+	// badgeFontSize, so the SVG's halves fit their text.
+	badgeCharWidth = 6.6
+	badgePadding   = 6
+	// badgeRefresh is how often, in seconds, a badge shown on its own
+	// reloads.
+	badgeRefresh = "5"
+)
+
+// The colors of the value half, by the state of the timer.
+var (
+	badgeLabelColor   = color.RGBA{0x55, 0x55, 0x55, 0xff}
+	badgeRunningColor = color.RGBA{0x44, 0xcc, 0x11, 0xff}
+	badgePausedColor  = color.RGBA{0xdf, 0xb3, 0x17, 0xff}
+	badgeIdleColor    = color.RGBA{0x9f, 0x9f, 0x9f, 0xff}
+)
+
+// badge is what a badge says: the tag, or countdown, and the time left.
+func (s shareStatus) badge() (label, value string, c color.RGBA) {
+	label = s.Tag
+	if label == "" {
+		label = "countdown"
+	}
+	value = render.Format(time.Duration(s.Left * float64(time.Second)).Round(time.Second))
+	switch s.State {
+	case "running":
+		return label, value, badgeRunningColor
+	case "paused":
+		return label, value + " paused", badgePausedColor
+	}
+	return label, "idle", badgeIdleColor
+}
+
+// badgeSVG draws a flat badge like the ones of shields.io.
+func badgeSVG(label, value string, c color.RGBA) []byte {
+	lw := badgeTextWidth(label)
+	vw := badgeTextWidth(value)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" role="img" aria-label="%s: %s">`,
+		lw+vw, badgeHeight, html.EscapeString(label), html.EscapeString(value))
+	fmt.Fprintf(&b, `<title>%s: %s</title>`, html.EscapeString(label), html.EscapeString(value))
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`, lw, badgeHeight, hexColor(badgeLabelColor))
+	fmt.Fprintf(&b, `<rect x="%d" width="%d" height="%d" fill="%s"/>`, lw, vw, badgeHeight, hexColor(c))
+	fmt.Fprintf(&b, `<g fill="#fff" font-family="DejaVu Sans Mono,Menlo,Consolas,monospace" font-size="%d" font-weight="bold" text-anchor="middle">`, badgeFontSize)
+	fmt.Fprintf(&b, `<text x="%d" y="14">%s</text>`, lw/2, html.EscapeString(label))
+	fmt.Fprintf(&b, `<text x="%d" y="14">%s</text>`, lw+vw/2, html.EscapeString(value))
+	b.WriteString(`</g></svg>`)
+	return b.Bytes()
+}
+
+func badgeTextWidth(s string) int {
+	return int(float64(len([]rune(s)))*badgeCharWidth) + 2*badgePadding
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// badgePNG draws the same badge as badgeSVG, for pages that take no SVG.
+func badgePNG(label, value string, c color.RGBA) ([]byte, error) {
+	f, err := opentype.Parse(gomonobold.TTF)
+	if err != nil {
+		return nil, err
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: badgeFontSize, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	lw := font.MeasureString(face, label).Ceil() + 2*badgePadding
+	vw := font.MeasureString(face, value).Ceil() + 2*badgePadding
+	img := image.NewRGBA(image.Rect(0, 0, lw+vw, badgeHeight))
+	for y := 0; y < badgeHeight; y++ {
+		for x := 0; x < lw+vw; x++ {
+			if x < lw {
+				img.SetRGBA(x, y, badgeLabelColor)
+			} else {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+	metrics := face.Metrics()
+	baseline := (badgeHeight*64 + metrics.Ascent - metrics.Descent) / 2
+	d := &font.Drawer{Dst: img, Src: image.White, Face: face}
+	d.Dot = fixed.Point26_6{X: fixed.I(badgePadding), Y: baseline}
+	d.DrawString(label)
+	d.Dot = fixed.Point26_6{X: fixed.I(lw + badgePadding), Y: baseline}
+	d.DrawString(value)
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// badge serves the badge of the daemon's timer as SVG or PNG. It is never
+// cached, so a dashboard that reloads it shows the time left, and asks a
+// page that shows it on its own, e.g. in an iframe, to reload.
+func (s *shareServer) badge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	label, value, c := s.timer.share().badge()
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Expires", "0")
+	w.Header().Set("Refresh", badgeRefresh)
+	if r.URL.Path == s.path+"/badge.png" {
+		b, err := badgePNG(label, value, c)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(b)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	_, _ = w.Write(badgeSVG(label, value, c))
+}
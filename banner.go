@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/antonmedv/countdown/render"
+	"github.com/gdamore/tcell/v2"
+)
+
+const bannerStartDelay = 2 * time.Second
+
+// loadBanner reads the banner from a file (e.g. figlet output), falling
+// back to using the argument itself as the banner text.
+func loadBanner(arg string) render.Symbol {
+	text := arg
+	if b, err := os.ReadFile(arg); err == nil {
+		text = string(b)
+	}
+	text = strings.ReplaceAll(strings.TrimRight(text, "\n"), "\t", "    ")
+	lines := strings.Split(text, "\n")
+
+	width := 0
+	for _, line := range lines {
+		if n := utf8.RuneCountInString(line); n > width {
+			width = n
+		}
+	}
+	for i, line := range lines {
+		lines[i] = line + strings.Repeat(" ", width-utf8.RuneCountInString(line))
+	}
+	return lines
+}
+
+// showBanner draws the banner centered until the delay passes or a key is
+// pressed.
+func showBanner(s render.Symbol, delay time.Duration) {
+	w, h := screen.Size()
+	clear()
+	echo(s, w/2-s.Width()/2, h/2-s.Height()/2, tcell.StyleDefault)
+	flush()
+
+	select {
+	case <-time.After(delay):
+	case <-queues:
+	}
+}
+
+func printBanner(s render.Symbol) {
+	for _, line := range s {
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
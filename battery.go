@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antonmedv/countdown/render"
+)
+
+// batteryEvery is how often -battery-low looks at the battery.
+const batteryEvery = time.Minute
+
+// errNoBattery is for machines without a battery, or that do not tell.
+var errNoBattery = errors.New("no battery")
+
+// batteryStatus is the charge of the battery, and the time it has left
+// on it while discharging; left is 0 when the system has no estimate.
+type batteryStatus struct {
+	percent     int
+	discharging bool
+	left        time.Duration
+}
+
+// readBattery asks the system for the battery: /sys/class/power_supply on
+// Linux, pmset on macOS and Win32_Battery on Windows.
+func readBattery() (batteryStatus, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return linuxBattery()
+	case "darwin":
+		out, err := exec.Command("pmset", "-g", "batt").Output()
+		if err != nil {
+			return batteryStatus{}, err
+		}
+		return parsePmset(string(out))
+	case "windows":
+		out, err := exec.Command("powershell", "-NoProfile", "-Command",
+			`$b = Get-CimInstance Win32_Battery | Select-Object -First 1; if ($b) { "$($b.EstimatedChargeRemaining) $($b.EstimatedRunTime) $($b.BatteryStatus)" }`).Output()
+		if err != nil {
+			return batteryStatus{}, err
+		}
+		return parseWin32Battery(string(out))
+	}
+	return batteryStatus{}, errNoBattery
+}
+
+func linuxBattery() (batteryStatus, error) {
+	dirs, _ := filepath.Glob("/sys/class/power_supply/BAT*")
+	if len(dirs) == 0 {
+		return batteryStatus{}, errNoBattery
+	}
+	read := func(name string) string {
+		b, _ := os.ReadFile(filepath.Join(dirs[0], name))
+		return strings.TrimSpace(string(b))
+	}
+	number := func(name string) float64 {
+		n, _ := strconv.ParseFloat(read(name), 64)
+		return n
+	}
+	percent, err := strconv.Atoi(read("capacity"))
+	if err != nil {
+		return batteryStatus{}, fmt.Errorf("battery: %v", err)
+	}
+	s := batteryStatus{percent: percent, discharging: read("status") == "Discharging"}
+	// Energy is in µWh over µW, charge in µAh over µA; either way, hours.
+	hours := 0.0
+	if power := number("power_now"); power > 0 {
+		hours = number("energy_now") / power
+	} else if current := number("current_now"); current > 0 {
+		hours = number("charge_now") / current
+	}
+	if s.discharging && hours > 0 {
+		s.left = time.Duration(hours * float64(time.Hour))
+	}
+	return s, nil
+}
+
+// pmsetBattery is the line of pmset -g batt for the internal battery, e.g.
+// "-InternalBattery-0 (id=1234)	85%; discharging; 3:12 remaining present: true".
+var pmsetBattery = regexp.MustCompile(`(\d+)%; ([a-zA-Z ]+);(?: (\d+):(\d+) remaining)?`)
+
+func parsePmset(out string) (batteryStatus, error) {
+	m := pmsetBattery.FindStringSubmatch(out)
+	if m == nil {
+		return batteryStatus{}, errNoBattery
+	}
+	s := batteryStatus{discharging: m[2] == "discharging"}
+	s.percent, _ = strconv.Atoi(m[1])
+	if s.discharging && m[3] != "" {
+		h, _ := strconv.Atoi(m[3])
+		min, _ := strconv.Atoi(m[4])
+		s.left = time.Duration(h)*time.Hour + time.Duration(min)*time.Minute
+	}
+	return s, nil
+}
+
+// parseWin32Battery reads "percent minutes status" of Win32_Battery, where
+// a status of 1 is discharging and the minutes are meaningless otherwise.
+func parseWin32Battery(out string) (batteryStatus, error) {
+	fields := strings.Fields(out)
+	if len(fields) != 3 {
+		return batteryStatus{}, errNoBattery
+	}
+	var s batteryStatus
+	var err error
+	if s.percent, err = strconv.Atoi(fields[0]); err != nil {
+		return batteryStatus{}, fmt.Errorf("battery: %v", err)
+	}
+	s.discharging = fields[2] == "1"
+	if minutes, err := strconv.Atoi(fields[1]); err == nil && s.discharging && minutes > 0 {
+		s.left = time.Duration(minutes) * time.Minute
+	}
+	return s, nil
+}
+
+// batteryStatuses has the first reading of -battery-low, for the first
+// countdown to warn with. It is nil without -battery-low.
+var batteryStatuses chan batteryStatus
+
+// warning is the warning for a countdown with left to go that
+// would outlast the battery, or "" when it would not or there is no
+// telling.
+func (s batteryStatus) warning(left time.Duration) string {
+	if !s.discharging || s.left == 0 || s.left >= left {
+		return ""
+	}
+	return fmt.Sprintf("battery has about %s left, less than the %s countdown: plug in", render.Format(s.left), render.Format(left))
+}
+
+// watchBattery is -battery-low. It reads the battery in the background,
+// since that runs a command on macOS and Windows: once right away, handed
+// to the first countdown through batteryStatuses, and then every
+// batteryEvery to send a desktop notification when the battery discharges
+// below low percent, once until it is charged again. It returns when ctx
+// is done.
+func watchBattery(ctx context.Context, low int) {
+	if s, err := readBattery(); err == nil {
+		batteryStatuses <- s
+	}
+	ticker := time.NewTicker(batteryEvery)
+	defer ticker.Stop()
+	notified := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s, err := readBattery()
+		if err != nil {
+			continue
+		}
+		switch {
+		case !s.discharging || s.percent > low:
+			notified = false
+		case !notified:
+			notified = true
+			body := fmt.Sprintf("%d%% left, the countdown ends with the machine", s.percent)
+			if s.left > 0 {
+				body = fmt.Sprintf("%d%% (%s) left, the countdown ends with the machine", s.percent, render.Format(s.left))
+			}
+			_ = desktopNotify("countdown: battery low", body)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBattery(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func(string) (batteryStatus, error)
+		out   string
+		want  batteryStatus
+	}{
+		{"pmset", parsePmset, "Now drawing from 'Battery Power'\n -InternalBattery-0 (id=4653155)\t85%; discharging; 3:12 remaining present: true\n",
+			batteryStatus{85, true, 3*time.Hour + 12*time.Minute}},
+		{"pmset charged", parsePmset, " -InternalBattery-0 (id=4653155)\t100%; charged; 0:00 remaining present: true\n", batteryStatus{100, false, 0}},
+		{"pmset no estimate", parsePmset, " -InternalBattery-0 (id=1)\t40%; discharging; (no estimate) present: true\n", batteryStatus{40, true, 0}},
+		{"win32", parseWin32Battery, "85 192 1\r\n", batteryStatus{85, true, 192 * time.Minute}},
+		{"win32 on AC", parseWin32Battery, "100 71582788 2\r\n", batteryStatus{100, false, 0}},
+	}
+	for _, tt := range tests {
+		got, err := tt.parse(tt.out)
+		if err != nil || got != tt.want {
+			t.Errorf("%s = %+v, %v, want %+v", tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := parsePmset("Now drawing from 'AC Power'\n"); err != errNoBattery {
+		t.Errorf("pmset without a battery: %v, want errNoBattery", err)
+	}
+	if _, err := parseWin32Battery(""); err != errNoBattery {
+		t.Errorf("win32 without a battery: %v, want errNoBattery", err)
+	}
+}
+
+func TestBatteryWarning(t *testing.T) {
+	s := batteryStatus{percent: 30, discharging: true, left: time.Hour}
+	if s.warning(30*time.Minute) != "" {
+		t.Errorf("warned for a countdown the battery outlasts")
+	}
+	if s.warning(2*time.Hour) == "" {
+		t.Errorf("no warning for a countdown that outlasts the battery")
+	}
+	s.discharging = false
+	if s.warning(2*time.Hour) != "" {
+		t.Errorf("warned while charging")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// EventKind tells what an Event on the bus is about.
+type EventKind int
+
+const (
+	SessionStarted EventKind = iota
+	SessionPaused
+	SessionResumed
+	// SessionEnded is published when a session completed or was aborted;
+	// Completed tells which.
+	SessionEnded
+	// Tick is published by the countdown on every tick, with Left and Total.
+	Tick
+	// Input is published for every key the countdown receives, in Key.
+	Input
+	// SessionChanged is published when the duration was extended or reset,
+	// with the new Left and Total.
+	SessionChanged
+	// SessionLap is published by the stopwatch for every lap, with the
+	// time so far in Total and the lap in Notes.
+	SessionLap
+)
+
+// Event is something that happened to a session. Which fields are set
+// depends on Kind.
+type Event struct {
+	Kind EventKind
+	// Time is when the event was published.
+	Time    time.Time
+	Tag     string
+	Notes   string
+	LogPath string
+	// Completed is set when a session ran its course.
+	Completed bool
+	// Quiet asks subscribers not to draw attention to the end, for
+	// -bell-only and meditations.
+	Quiet bool
+	Left  time.Duration
+	Total time.Duration
+	Key   *tcell.EventKey
+}
+
+// Bus hands every published event to the subscribers, in the order they
+// subscribed. Publish waits for them, so a subscriber must not block for
+// long; the log and the lights rely on having run before the process
+// exits.
+type Bus struct {
+	mu   sync.Mutex
+	subs []*subscriber
+}
+
+type subscriber struct {
+	fn func(Event)
+}
+
+// bus is where sessions publish their events. main subscribes the log and
+// the lights to it.
+var bus = &Bus{}
+
+// Subscribe calls fn for every event published from now on, until the
+// returned cancel is called.
+func (b *Bus) Subscribe(fn func(Event)) (cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sub := &subscriber{fn}
+	b.subs = append(b.subs, sub)
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, other := range b.subs {
+			if other == sub {
+				b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (b *Bus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.mu.Lock()
+	subs := b.subs
+	b.mu.Unlock()
+	for _, sub := range subs {
+		sub.fn(e)
+	}
+}
+
+// sessionID identifies a session in the log and names its timeline: the
+// time the session started.
+const sessionID = "20060102-150405"
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/antonmedv/countdown/parse"
+	"github.com/antonmedv/countdown/render"
+	"github.com/gdamore/tcell/v2"
+)
+
+// parseChain reads the -chain arguments, a duration each.
+func parseChain(args []string, literal bool, unit string) ([]time.Duration, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("-chain needs two durations or more")
+	}
+	durations := make([]time.Duration, len(args))
+	for i, arg := range args {
+		d, err := parse.Arg(arg, literal, unit)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("-chain: invalid duration %q", arg)
+		}
+		durations[i] = d
+	}
+	return durations, nil
+}
+
+// runChain runs the -chain durations one after another, each logged as a
+// session, with the segment and the next duration under the digits. The
+// bell rings between segments; with wait, a key starts the next one.
+func runChain(ctx context.Context, durations []time.Duration, wait, countUp bool, tag, notes, logPath string) bool {
+	defer func() { caption = "" }()
+	for i, d := range durations {
+		caption = fmt.Sprintf("%d/%d", i+1, len(durations))
+		if i+1 < len(durations) {
+			caption += ", then " + render.Format(durations[i+1])
+		}
+		if !countdown(ctx, d, countUp, tag, notes, logPath) {
+			return false
+		}
+		if i+1 == len(durations) {
+			break
+		}
+		ringBell(1)
+		if wait && !waitForKey(ctx, fmt.Sprintf("%d/%d done, press a key to start %s", i+1, len(durations), render.Format(durations[i+1]))) {
+			return false
+		}
+	}
+	return true
+}
+
+// waitForKey shows message until a key is pressed, and reports false for
+// Esc, Ctrl+C or a cancelled ctx.
+func waitForKey(ctx context.Context, message string) bool {
+	draw := func() {
+		w, h := screen.Size()
+		clear()
+		echoString(message, w/2-utf8.RuneCountInString(message)/2, h/2, tcell.StyleDefault)
+		flush()
+	}
+	draw()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case ev := <-queues:
+			switch ev := ev.(type) {
+			case *tcell.EventResize:
+				draw()
+			case *tcell.EventKey:
+				return !pressed("quit", ev)
+			}
+		}
+	}
+}
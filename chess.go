@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/antonmedv/countdown/parse"
+	"github.com/antonmedv/countdown/render"
+	"github.com/antonmedv/countdown/timer"
+	"github.com/gdamore/tcell/v2"
+)
+
+const chessHints = "any key: end move  p: pause  Esc: quit"
+
+// chessClock is -chess: two countdowns of which one runs at a time. With
+// fischer a player gets that much time after every move; with bronstein
+// they get back what the move took, up to that much.
+type chessClock struct {
+	sides           [2]*timer.Timer
+	fischer, delay  time.Duration
+	moves           [2]int
+	turn            int
+	started, paused bool
+	// moveLeft is the time the player to move had when the move began.
+	moveLeft time.Duration
+}
+
+var chessSides = [2]string{"White", "Black"}
+
+// parseChess reads the -chess arguments: the time of both players, or of
+// White and of Black for a handicap.
+func parseChess(args []string, literal bool, unit string) ([2]time.Duration, error) {
+	var times [2]time.Duration
+	if len(args) == 0 || len(args) > 2 {
+		return times, fmt.Errorf("-chess needs one duration, or one per player")
+	}
+	for i, arg := range args {
+		d, err := parse.Arg(arg, literal, unit)
+		if err != nil || d <= 0 {
+			return times, fmt.Errorf("-chess: invalid duration %q", arg)
+		}
+		times[i] = d
+	}
+	if len(args) == 1 {
+		times[1] = times[0]
+	}
+	return times, nil
+}
+
+// move ends the move of the player whose clock runs and starts the other
+// clock. The first key starts White's clock.
+func (c *chessClock) move() {
+	if !c.started {
+		c.started = true
+		c.moveLeft = c.sides[0].Left()
+		c.sides[0].Resume()
+		return
+	}
+	t := c.sides[c.turn]
+	t.Pause()
+	c.moves[c.turn]++
+	switch {
+	case c.fischer > 0:
+		t.Extend(c.fischer)
+	case c.delay > 0:
+		used := c.moveLeft - t.Left()
+		if used > c.delay {
+			used = c.delay
+		}
+		t.Extend(used)
+	}
+	c.turn = 1 - c.turn
+	c.moveLeft = c.sides[c.turn].Left()
+	c.sides[c.turn].Resume()
+}
+
+// chess runs the chess clock until a flag falls, which completes the
+// session, or Esc. The game is logged as one session with the outcome,
+// such as "Black flagged after 31 moves", as notes.
+func chess(ctx context.Context, times [2]time.Duration, fischer, delay time.Duration, tag, logPath string) bool {
+	c := &chessClock{fischer: fischer, delay: delay}
+	for i := range c.sides {
+		c.sides[i] = timer.New(times[i], tick)
+		c.sides[i].Start()
+		c.sides[i].Pause()
+	}
+	defer func() {
+		for _, t := range c.sides {
+			t.Stop()
+		}
+	}()
+	bus.Publish(Event{Kind: SessionStarted, Tag: tag, LogPath: logPath, Left: times[0], Total: times[0] + times[1]})
+	end := func(completed bool, outcome string) bool {
+		elapsed := times[0] + times[1] - c.sides[0].Left() - c.sides[1].Left()
+		bus.Publish(Event{Kind: SessionEnded, Tag: tag, Notes: outcome, LogPath: logPath, Completed: completed, Total: elapsed})
+		return completed
+	}
+
+	var resized <-chan time.Time
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	w, h = screen.Size()
+	drawChess(c, w, h)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return end(false, "aborted")
+		case ev := <-queues:
+			switch ev := ev.(type) {
+			case *tcell.EventResize:
+				resized = time.After(resizeDelay)
+			case *tcell.EventKey:
+				switch {
+				case pressed("quit", ev):
+					return end(false, "aborted")
+				case pressed("chess_pause", ev) && c.started:
+					c.paused = !c.paused
+					if c.paused {
+						c.sides[c.turn].Pause()
+						bus.Publish(Event{Kind: SessionPaused, Tag: tag, LogPath: logPath})
+					} else {
+						c.sides[c.turn].Resume()
+						bus.Publish(Event{Kind: SessionResumed, Tag: tag, LogPath: logPath})
+					}
+				case !c.paused:
+					c.move()
+				}
+				drawChess(c, w, h)
+			}
+		case <-resized:
+			resized = nil
+			w, h = screen.Size()
+			drawChess(c, w, h)
+		case <-ticker.C:
+			for i, t := range c.sides {
+				select {
+				case <-t.Done():
+					drawChess(c, w, h)
+					ringBell(2)
+					return end(true, fmt.Sprintf("%s flagged after %s", chessSides[i], plural(c.moves[i], "move")))
+				default:
+				}
+			}
+			drawChess(c, w, h)
+			bus.Publish(Event{Kind: Tick, Tag: tag, LogPath: logPath, Left: c.sides[c.turn].Left(), Total: c.sides[c.turn].Total()})
+		}
+	}
+}
+
+// drawChess shows both clocks side by side, the waiting one dimmed, or a
+// line each when the digits do not fit.
+func drawChess(c *chessClock, w, h int) {
+	clear()
+	digits := render.Digits("00:00")
+	wide := w/2 >= digits.Width()+2 && h >= digits.Height()+6
+	for i, t := range c.sides {
+		style := tcell.StyleDefault
+		if !c.started || c.turn != i || c.paused {
+			style = style.Dim(true)
+		}
+		left := render.Format(t.Left())
+		label := fmt.Sprintf("%s  %d", chessSides[i], c.moves[i])
+		if !wide {
+			echoString(fmt.Sprintf("%s  %s", label, left), 1, i, style)
+			continue
+		}
+		text := render.Digits(left)
+		x := i*w/2 + w/4 - text.Width()/2
+		top := h/2 - text.Height()/2
+		for _, s := range text {
+			echo(s, x, top, style)
+			x += s.Width()
+		}
+		title := style
+		if c.started && c.turn == i {
+			title = title.Reverse(true)
+		}
+		echoString(" "+label+" ", i*w/2+w/4-(len(label)+2)/2, top-2, title)
+	}
+	hint := chessHints
+	switch {
+	case !c.started:
+		hint = "press a key to start White's clock"
+	case c.paused:
+		hint = "paused, p: resume"
+	}
+	echoString(hint, w/2-len(hint)/2, h-1, tcell.StyleDefault.Dim(true))
+	flush()
+}
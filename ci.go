@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Annotator marks the start and end of a run in a CI system's log.
+type Annotator interface {
+	begin(name string)
+	end(name string, status int, elapsed time.Duration)
+}
+
+func newAnnotator(name string) (Annotator, error) {
+	if name == "auto" {
+		name = detectCI()
+	}
+	switch name {
+	case "none":
+		return noAnnotator{}, nil
+	case "github":
+		return githubAnnotator{}, nil
+	case "teamcity":
+		return teamcityAnnotator{}, nil
+	}
+	return nil, fmt.Errorf("unknown CI format %q", name)
+}
+
+func detectCI() string {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return "github"
+	case os.Getenv("TEAMCITY_VERSION") != "":
+		return "teamcity"
+	}
+	return "none"
+}
+
+type noAnnotator struct{}
+
+func (noAnnotator) begin(string)                   {}
+func (noAnnotator) end(string, int, time.Duration) {}
+
+// githubAnnotator folds the output into a group and adds a notice, or an
+// error when the command failed.
+type githubAnnotator struct{}
+
+func (githubAnnotator) begin(name string) {
+	fmt.Printf("::group::%s\n", githubEscape(name))
+}
+
+func (githubAnnotator) end(name string, status int, elapsed time.Duration) {
+	fmt.Println("::endgroup::")
+	level := "notice"
+	if status != 0 {
+		level = "error"
+	}
+	fmt.Printf("::%s title=countdown::%s took %s (exit %d)\n", level, githubEscape(name), elapsed, status)
+}
+
+func githubEscape(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// teamcityAnnotator opens a block and reports the duration as a build
+// statistic, so it can be charted across builds.
+type teamcityAnnotator struct{}
+
+func (teamcityAnnotator) begin(name string) {
+	fmt.Printf("##teamcity[blockOpened name='%s']\n", teamcityEscape(name))
+}
+
+func (teamcityAnnotator) end(name string, status int, elapsed time.Duration) {
+	fmt.Printf("##teamcity[blockClosed name='%s']\n", teamcityEscape(name))
+	fmt.Printf("##teamcity[buildStatisticValue key='countdown.%s' value='%d']\n", teamcityEscape(name), elapsed.Milliseconds())
+	if status != 0 {
+		fmt.Printf("##teamcity[message text='%s exited with %d' status='ERROR']\n", teamcityEscape(name), status)
+	}
+}
+
+func teamcityEscape(s string) string {
+	return strings.NewReplacer("|", "||", "'", "|'", "\n", "|n", "\r", "|r", "[", "|[", "]", "|]").Replace(s)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/antonmedv/countdown/parse"
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	classroomExtension = 2 * time.Minute
+	classroomScale     = 2
+)
+
+// Classroom is the state of -classroom: number keys jump to the presets,
+// + adds two minutes, and a hint line lists the keys unless hidden.
+type Classroom struct {
+	presets []time.Duration
+	hints   bool
+}
+
+var classroom *Classroom
+
+// parsePresets reads "5m,10m,15m"; the presets are bound to keys 1 to 9.
+func parsePresets(s string) ([]time.Duration, error) {
+	var presets []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		d, err := parse.Literal(part)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid preset %q", part)
+		}
+		presets = append(presets, d)
+	}
+	if len(presets) == 0 || len(presets) > 9 {
+		return nil, fmt.Errorf("-presets needs between 1 and 9 durations")
+	}
+	return presets, nil
+}
+
+func (c *Classroom) preset(ch rune) (time.Duration, bool) {
+	i := int(ch - '1')
+	if i < 0 || i >= len(c.presets) {
+		return 0, false
+	}
+	return c.presets[i], true
+}
+
+func (c *Classroom) drawHints(w, h int) {
+	if !c.hints {
+		return
+	}
+	keys := make([]string, len(c.presets))
+	for i, d := range c.presets {
+		keys[i] = fmt.Sprintf("%d: %s", i+1, announcement(d))
+	}
+	hint := strings.Join(keys, "  ")
+	if add := keyLabel("classroom_add"); add != "" {
+		hint += "  " + add + ": 2 more minutes"
+	}
+	if pause := keyLabel("pause"); pause != "" {
+		hint += "  " + pause + ": pause"
+	}
+	echoString(hint, w/2-utf8.RuneCountInString(hint)/2, h-2, tcell.StyleDefault.Dim(true))
+}
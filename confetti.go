@@ -0,0 +1,72 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	confettiDuration = 3 * time.Second
+	confettiFrame    = 50 * time.Millisecond
+)
+
+var (
+	confettiRunes  = []rune{'*', '+', 'o', '•', '✦', '❄'}
+	confettiColors = []tcell.Color{
+		tcell.ColorRed,
+		tcell.ColorGreen,
+		tcell.ColorYellow,
+		tcell.ColorBlue,
+		tcell.ColorFuchsia,
+		tcell.ColorAqua,
+	}
+)
+
+type particle struct {
+	x, y   float64
+	vx, vy float64
+	r      rune
+	fg     tcell.Color
+}
+
+// celebrate plays a short confetti animation; any key skips it.
+func celebrate() {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	w, h := screen.Size()
+	particles := make([]particle, w)
+	for i := range particles {
+		particles[i] = particle{
+			x:  float64(rnd.Intn(w + 1)),
+			y:  -float64(rnd.Intn(h + 1)),
+			vx: rnd.Float64() - 0.5,
+			vy: 0.5 + rnd.Float64(),
+			r:  confettiRunes[rnd.Intn(len(confettiRunes))],
+			fg: confettiColors[rnd.Intn(len(confettiColors))],
+		}
+	}
+
+	frame := time.NewTicker(confettiFrame)
+	defer frame.Stop()
+	end := time.After(confettiDuration)
+	for {
+		select {
+		case <-end:
+			return
+		case ev := <-queues:
+			if _, ok := ev.(*tcell.EventKey); ok {
+				return
+			}
+		case <-frame.C:
+			clear()
+			for i := range particles {
+				p := &particles[i]
+				p.x += p.vx
+				p.y += p.vy
+				setCell(int(p.x), int(p.y), p.r, tcell.StyleDefault.Foreground(p.fg))
+			}
+			flush()
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Config holds the defaults. The command line overrides it, and it
+// overrides the COUNTDOWN_* environment variables.
+type Config struct {
+	LogPath string `toml:"log_path,omitempty"`
+	Tag     string `toml:"tag,omitempty"`
+	// LogFormat is text or jsonl, see -log-format.
+	LogFormat string `toml:"log_format,omitempty"`
+	// Log is a database the sessions are also logged to, see -log.
+	Log string `toml:"log,omitempty"`
+	// Audit chains the events of the log by hash, see -audit.
+	Audit    bool `toml:"audit,omitempty"`
+	Up       bool `toml:"up,omitempty"`
+	Gradient bool `toml:"gradient,omitempty"`
+	// Color tints the digits, a name or #rrggbb; a tag's color wins.
+	Color string `toml:"color,omitempty"`
+	// Font is the digit font, see -font.
+	Font string `toml:"font,omitempty"`
+	// Theme names a theme of Themes or a built-in one, see -theme.
+	Theme  string           `toml:"theme,omitempty"`
+	Themes map[string]Theme `toml:"themes,omitempty"`
+	// Warn and Critical are the defaults of -warn and -critical.
+	Warn     string `toml:"warn,omitempty"`
+	Critical string `toml:"critical,omitempty"`
+	BellOnly bool   `toml:"bell_only,omitempty"`
+	Confetti bool   `toml:"confetti,omitempty"`
+	// DimBreaks dims the breaks, see -break.
+	DimBreaks bool `toml:"dim_breaks,omitempty"`
+	// Progress shows the progress bar, see -progress.
+	Progress bool `toml:"progress,omitempty"`
+	// Details shows the tag, notes and end time, see -details.
+	Details bool `toml:"details,omitempty"`
+	Notify  bool `toml:"notify,omitempty"`
+	// BatteryLow is the default of -battery-low, a percent.
+	BatteryLow int `toml:"battery_low,omitempty"`
+	// ConfirmQuit asks for a second Esc before aborting, see -confirm-quit.
+	ConfirmQuit bool `toml:"confirm_quit,omitempty"`
+	// Alert names the profile in Alerts used without -alert.
+	Alert    string                  `toml:"alert,omitempty"`
+	Alerts   map[string]AlertProfile `toml:"alerts,omitempty"`
+	Timeline bool                    `toml:"timeline,omitempty"`
+	// Sound is played on completion at Volume, see -sound and -volume.
+	Sound  string `toml:"sound,omitempty"`
+	Volume int    `toml:"volume,omitempty"`
+	// Unit is appended to bare numbers, so "25" means 25m with Unit "m".
+	Unit string `toml:"unit,omitempty"`
+	// Duration is used when countdown is run without arguments.
+	Duration string                   `toml:"duration,omitempty"`
+	Light    LightConfig              `toml:"light,omitempty"`
+	Routines map[string][]RoutineStep `toml:"routines,omitempty"`
+	Cooking  map[string]Bundle        `toml:"cooking,omitempty"`
+	Rounds   map[string]Rounds        `toml:"rounds,omitempty"`
+	Kiosk    KioskConfig              `toml:"kiosk,omitempty"`
+	Pomodoro PomodoroConfig           `toml:"pomodoro,omitempty"`
+	Interval IntervalConfig           `toml:"interval,omitempty"`
+	Suggest  SuggestConfig            `toml:"suggest,omitempty"`
+	Presets  map[string]string        `toml:"presets,omitempty"`
+	Macros   map[string]Macro         `toml:"macros,omitempty"`
+	// Keys rebinds the keys of the actions, see defaultKeys.
+	Keys   map[string]string `toml:"keys,omitempty"`
+	Script ScriptConfig      `toml:"script,omitempty"`
+	// Plugins are run for the events, see -plugins.
+	Plugins []string `toml:"plugins,omitempty"`
+	// Sync is the countdown server of countdown sync.
+	Sync SyncConfig `toml:"sync,omitempty"`
+}
+
+type LightConfig struct {
+	Provider string   `toml:"provider,omitempty"`
+	Bridge   string   `toml:"bridge,omitempty"`
+	Username string   `toml:"username,omitempty"`
+	Lights   []string `toml:"lights,omitempty"`
+	Token    string   `toml:"token,omitempty"`
+	Selector string   `toml:"selector,omitempty"`
+}
+
+// profile selects a separate config (and through it a separate log), so
+// work and personal time never mix.
+var profile string
+
+func configPath() string {
+	path := os.Getenv("COUNTDOWN_CONFIG")
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return ""
+		}
+		path = filepath.Join(dir, "countdown", "config.toml")
+	}
+	if profile != "" {
+		return filepath.Join(filepath.Dir(path), "profiles", profile+".toml")
+	}
+	return path
+}
+
+func validProfile(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+func configExists() bool {
+	path := configPath()
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// loadConfig reads the config file. A missing file is not an error and
+// yields the zero Config.
+func loadConfig(path string) (Config, error) {
+	var config Config
+	if path == "" {
+		return config, nil
+	}
+	_, err := toml.DecodeFile(path, &config)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	switch config.Unit {
+	case "", "s", "m", "h":
+	default:
+		return config, fmt.Errorf("unit must be s, m or h, got %q", config.Unit)
+	}
+	if config.Color != "" && tcell.GetColor(config.Color) == tcell.ColorDefault {
+		return config, fmt.Errorf("unknown color %q", config.Color)
+	}
+	return config, nil
+}
+
+func saveConfig(path string, config Config) error {
+	return saveTOML(path, config)
+}
+
+func saveTOML(path string, v interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return toml.NewEncoder(f).Encode(v)
+}
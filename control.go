@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"text/template"
+	"time"
+)
+
+// daemonStartTimeout is how long "countdown start" waits for a daemon it
+// started itself.
+const daemonStartTimeout = 3 * time.Second
+
+// control sends a control command to the daemon and prints the status it
+// answers with, through tmpl if one is given, or the share link or the job
+// scheduled. "start" and "schedule" launch a daemon in the background when
+// none is running.
+func control(req daemonRequest, tmpl *template.Template, daemonArgs []string) {
+	resp, err := sendDaemon(req)
+	if err != nil && (req.Command == "start" || req.Command == "schedule") {
+		if err = spawnDaemon(daemonArgs); err == nil {
+			resp, err = sendDaemon(req)
+		}
+	}
+	if err != nil {
+		stderr("error: no daemon running (%v), start one with: countdown daemon\n", err)
+		os.Exit(exitUsage)
+	}
+	if resp.Error != "" {
+		stderr("error: %s\n", resp.Error)
+		os.Exit(exitUsage)
+	}
+	if req.Command == "schedule" {
+		fmt.Printf("scheduled %s\n", formatJob(resp.Jobs[0]))
+		return
+	}
+	if req.Command == "share" {
+		fmt.Println(resp.URL)
+		return
+	}
+	if tmpl != nil {
+		printStatus(tmpl, *resp.Status)
+		return
+	}
+	s := resp.Status
+	if s.State == "idle" {
+		fmt.Println("idle")
+		return
+	}
+	fmt.Printf("%s %s %s left of %s", s.State, s.Tag, s.Remaining, s.Total)
+	if s.Notes != "" {
+		fmt.Printf(" (%s)", s.Notes)
+	}
+	fmt.Println()
+}
+
+func sendDaemon(req daemonRequest) (daemonResponse, error) {
+	var resp daemonResponse
+	conn, err := net.Dial("unix", daemonSocket())
+	if err != nil {
+		return resp, err
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return resp, err
+	}
+	err = json.NewDecoder(conn).Decode(&resp)
+	return resp, err
+}
+
+// spawnDaemon starts "countdown daemon" detached and waits for its socket.
+func spawnDaemon(args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, append([]string{"daemon"}, args...)...)
+	cmd.SysProcAttr = detachAttr()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	_ = cmd.Process.Release()
+	deadline := time.Now().Add(daemonStartTimeout)
+	for {
+		conn, err := net.Dial("unix", daemonSocket())
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
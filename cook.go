@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/antonmedv/countdown/parse"
+	"github.com/antonmedv/countdown/render"
+	"github.com/antonmedv/countdown/timer"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Bundle is a set of cooking timers in the config file. With
+// finish_together, shorter items start later so everything is ready at
+// once; otherwise all items start right away.
+//
+//	[cooking.dinner]
+//	finish_together = true
+//	items = [
+//	  {name = "rice", duration = "12m"},
+//	  {name = "eggs", duration = "7m", bell = 2},
+//	]
+type Bundle struct {
+	FinishTogether bool         `toml:"finish_together,omitempty"`
+	Items          []BundleItem `toml:"items"`
+}
+
+type BundleItem struct {
+	Name     string `toml:"name"`
+	Duration string `toml:"duration"`
+	// Bell is how many times the terminal bell rings for this item.
+	Bell int `toml:"bell,omitempty"`
+}
+
+// cookItem is a bundle item placed on the bundle's timeline.
+type cookItem struct {
+	name       string
+	bell       int
+	start, end time.Duration
+}
+
+func scheduleBundle(name string, b Bundle) ([]cookItem, time.Duration, error) {
+	if len(b.Items) == 0 {
+		return nil, 0, fmt.Errorf("cooking bundle %q is not defined", name)
+	}
+	items := make([]cookItem, len(b.Items))
+	var total time.Duration
+	for i, item := range b.Items {
+		d, err := parse.Literal(item.Duration)
+		if err != nil || d <= 0 {
+			return nil, 0, fmt.Errorf("cooking bundle %q item %q: invalid duration %q", name, item.Name, item.Duration)
+		}
+		bell := item.Bell
+		if bell == 0 {
+			bell = 1
+		}
+		items[i] = cookItem{name: item.Name, bell: bell, end: d}
+		if d > total {
+			total = d
+		}
+	}
+	if b.FinishTogether {
+		for i := range items {
+			items[i].start = total - items[i].end
+			items[i].end = total
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].start < items[j].start
+	})
+	return items, total, nil
+}
+
+// cook runs a bundle, ringing each item's alarm when it should go in (for
+// staggered starts) and when it is done. The bundle is logged as one
+// session with its name as notes.
+func cook(ctx context.Context, items []cookItem, total time.Duration, tag string, notes string, logPath string) bool {
+	t := timer.New(total, tick)
+	events := t.Subscribe()
+	defer t.Stop()
+	var elapsed time.Duration
+	var resized <-chan time.Time
+
+	w, h = screen.Size()
+	t.Start()
+	bus.Publish(Event{Kind: SessionStarted, Tag: tag, Notes: notes, LogPath: logPath, Left: total, Total: total})
+	drawCook(items, elapsed, total, false, w, h)
+
+	// bells counts the alarms of the items that went in or got done since
+	// prev, to ring in one go.
+	bells := func(prev time.Duration) int {
+		n := 0
+		for _, item := range items {
+			if item.start > 0 && prev < item.start && elapsed >= item.start {
+				n += item.bell
+			}
+			if prev < item.end && elapsed >= item.end {
+				n += item.bell
+			}
+		}
+		return n
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			bus.Publish(Event{Kind: SessionEnded, Tag: tag, LogPath: logPath})
+			return false
+		case ev := <-queues:
+			if _, ok := ev.(*tcell.EventResize); ok {
+				resized = time.After(resizeDelay)
+				break
+			}
+			key, ok := ev.(*tcell.EventKey)
+			if !ok {
+				break
+			}
+			if pressed("quit", key) {
+				bus.Publish(Event{Kind: SessionEnded, Tag: tag, LogPath: logPath})
+				return false
+			}
+			if pressTime := time.Now(); pressed("pause", key) && pressTime.Sub(inputStartTime) > inputDelayMS {
+				if t.Paused() {
+					t.Resume()
+					bus.Publish(Event{Kind: SessionResumed, Tag: tag, LogPath: logPath})
+				} else {
+					t.Pause()
+					bus.Publish(Event{Kind: SessionPaused, Tag: tag, LogPath: logPath})
+				}
+				inputStartTime = pressTime
+				drawCook(items, elapsed, total, t.Paused(), w, h)
+			}
+		case <-resized:
+			resized = nil
+			w, h = screen.Size()
+			drawCook(items, elapsed, total, t.Paused(), w, h)
+		case ev := <-events:
+			if ev.Kind != timer.Tick {
+				break
+			}
+			prev := elapsed
+			elapsed = total - ev.Left
+			// Ringing waits between the bells, which would hold up the keys.
+			if n := bells(prev); n > 0 {
+				go ringBell(n)
+			}
+			drawCook(items, elapsed, total, false, w, h)
+		case <-t.Done():
+			prev := elapsed
+			elapsed = total
+			ringBell(bells(prev))
+			bus.Publish(Event{Kind: SessionEnded, Tag: tag, LogPath: logPath, Completed: true})
+			return true
+		}
+	}
+}
+
+func drawCook(items []cookItem, elapsed, total time.Duration, paused bool, w, h int) {
+	clear()
+	renderer.drawTime(render.Format(total-elapsed), tcell.StyleDefault, w, h)
+
+	y := h/2 + render.Digits("0").Height()/2 + 1
+	for _, item := range items {
+		var line string
+		switch {
+		case elapsed < item.start:
+			line = fmt.Sprintf("%-12s starts in %s", item.name, render.Format(item.start-elapsed))
+		case elapsed < item.end:
+			line = fmt.Sprintf("%-12s %s left", item.name, render.Format(item.end-elapsed))
+		default:
+			line = fmt.Sprintf("%-12s done", item.name)
+		}
+		echoString(line, w/2-utf8.RuneCountInString(line)/2, y, tcell.StyleDefault)
+		y++
+	}
+
+	if paused {
+		echo(render.PausedText, w/2-render.PausedText.Width()/2, y+1, tcell.StyleDefault)
+	}
+	flush()
+	renderer.present()
+}
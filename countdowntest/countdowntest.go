@@ -0,0 +1,206 @@
+// Package countdowntest helps programs that integrate with countdown test
+// that integration deterministically. A Clock is a fake clock that runs
+// timers of the timer package at the test's pace, a Recorder writes
+// sessions in countdown's log format on it, and ReadLog parses a log back
+// into events, whether countdown or a Recorder wrote it.
+package countdowntest
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antonmedv/countdown/logbook"
+	"github.com/antonmedv/countdown/timer"
+)
+
+// The states of a log event.
+const (
+	Start  = logbook.Start
+	End    = logbook.End
+	Pause  = logbook.Pause
+	Resume = logbook.Resume
+	Lap    = logbook.Lap
+)
+
+// Event is one line of the log.
+type Event = logbook.Event
+
+// Clock is a fake clock that only moves when told to. It is a
+// timer.Clock, so a timer made with timer.NewWithClock counts down as the
+// test advances it:
+//
+//	clock := countdowntest.NewClock(time.Now())
+//	t := timer.NewWithClock(time.Minute, time.Second, clock)
+//	t.Start()
+//	clock.Advance(time.Minute)
+//	<-t.Done()
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	running time.Duration
+	alarms  []*alarm
+}
+
+var _ timer.Clock = (*Clock)(nil)
+
+func NewClock(t time.Time) *Clock {
+	return &Clock{now: t}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Running is the time advanced so far, without the time suspended.
+func (c *Clock) Running() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.running
+}
+
+// Advance moves the clock on by d and fires the timers and tickers that
+// are due, a ticker once however many of its ticks went by.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.running += d
+	alarms := c.alarms[:0]
+	for _, a := range c.alarms {
+		if a.stopped {
+			continue
+		}
+		if a.due <= c.running {
+			select {
+			case a.c <- c.now:
+			default:
+			}
+			if a.every == 0 {
+				continue
+			}
+			for a.due <= c.running {
+				a.due += a.every
+			}
+		}
+		alarms = append(alarms, a)
+	}
+	c.alarms = alarms
+}
+
+// Suspend moves the wall time on by d but not the running time, as a
+// machine that sleeps does, and fires nothing.
+func (c *Clock) Suspend(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *Clock) NewTimer(d time.Duration) timer.Alarm {
+	return c.add(d, 0)
+}
+
+func (c *Clock) NewTicker(d time.Duration) timer.Alarm {
+	return c.add(d, d)
+}
+
+func (c *Clock) add(d, every time.Duration) *alarm {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	a := &alarm{clock: c, c: make(chan time.Time, 1), due: c.running + d, every: every}
+	if d <= 0 && every == 0 {
+		// Due already, as a time.Timer of 0 is.
+		a.c <- c.now
+		return a
+	}
+	c.alarms = append(c.alarms, a)
+	return a
+}
+
+// alarm is a timer of a Clock, or a ticker when every is set.
+type alarm struct {
+	clock   *Clock
+	c       chan time.Time
+	due     time.Duration
+	every   time.Duration
+	stopped bool
+}
+
+func (a *alarm) C() <-chan time.Time {
+	return a.c
+}
+
+func (a *alarm) Stop() {
+	a.clock.mu.Lock()
+	defer a.clock.mu.Unlock()
+	a.stopped = true
+}
+
+// Recorder writes a log the way countdown does, with timestamps from its
+// Clock, which starts at 2024-01-01 09:00 local time.
+type Recorder struct {
+	Clock *Clock
+	tb    testing.TB
+	path  string
+}
+
+// NewRecorder creates an empty log in a temporary directory that is
+// removed when the test ends.
+func NewRecorder(tb testing.TB) *Recorder {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "countdown.log")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		tb.Fatal(err)
+	}
+	return &Recorder{
+		Clock: NewClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local)),
+		tb:    tb,
+		path:  path,
+	}
+}
+
+// Path is the log path, to pass to countdown with -f or
+// COUNTDOWN_LOG_PATH.
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+func (r *Recorder) Start(tag, notes string) { r.write(Start, tag, notes) }
+func (r *Recorder) Pause(tag string)        { r.write(Pause, tag, "") }
+func (r *Recorder) Resume(tag string)       { r.write(Resume, tag, "") }
+func (r *Recorder) End(tag, notes string)   { r.write(End, tag, notes) }
+
+// Session records a whole session of d without pauses.
+func (r *Recorder) Session(tag, notes string, d time.Duration) {
+	r.Start(tag, notes)
+	r.Clock.Advance(d)
+	r.End(tag, "")
+}
+
+// Events reads back everything in the log.
+func (r *Recorder) Events() []Event {
+	r.tb.Helper()
+	events, err := ReadLog(r.path)
+	if err != nil {
+		r.tb.Fatal(err)
+	}
+	return events
+}
+
+func (r *Recorder) write(state, tag, notes string) {
+	r.tb.Helper()
+	e := Event{State: state, Time: r.Clock.Now(), Tag: tag, Notes: notes}
+	if err := logbook.Append(r.path, e); err != nil {
+		r.tb.Fatal(err)
+	}
+}
+
+// ReadLog parses a countdown log. Timestamps are read in local time, as
+// countdown writes them.
+func ReadLog(path string) ([]Event, error) {
+	return logbook.Read(path)
+}
@@ -0,0 +1,72 @@
+package countdowntest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/antonmedv/countdown/timer"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder(t)
+	start := rec.Clock.Now()
+	rec.Session("Work", "review", 25*time.Minute)
+	rec.Start("Home", "")
+	rec.Clock.Advance(time.Minute)
+	rec.Pause("Home")
+
+	events := rec.Events()
+	want := []struct {
+		state string
+		tag   string
+		at    time.Duration
+	}{
+		{Start, "Work", 0},
+		{End, "Work", 25 * time.Minute},
+		{Start, "Home", 25 * time.Minute},
+		{Pause, "Home", 26 * time.Minute},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("%d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.State != w.state || e.Tag != w.tag || !e.Time.Equal(start.Add(w.at)) {
+			t.Errorf("event %d = %s %s at %v, want %s %s at %v", i, e.State, e.Tag, e.Time, w.state, w.tag, start.Add(w.at))
+		}
+	}
+	if events[0].Notes != "review" {
+		t.Errorf("notes = %q, want review", events[0].Notes)
+	}
+}
+
+func TestClockRunsTimer(t *testing.T) {
+	clock := NewClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	tm := timer.NewWithClock(3*time.Second, time.Second, clock)
+	events := tm.Subscribe()
+	tm.Start()
+
+	clock.Advance(time.Second)
+	if ev := <-events; ev.Kind != timer.Tick || ev.Left != 2*time.Second {
+		t.Errorf("after a second: %+v, want a tick with 2s left", ev)
+	}
+
+	tm.Pause()
+	<-events
+	clock.Advance(time.Hour)
+	if left := tm.Left(); left != 2*time.Second {
+		t.Errorf("paused an hour: %v left, want 2s", left)
+	}
+
+	tm.Resume()
+	<-events
+	clock.Advance(2 * time.Second)
+	select {
+	case <-tm.Done():
+	case <-time.After(time.Second):
+		t.Fatal("not done after the time ran out")
+	}
+	if !tm.Completed() {
+		t.Error("not completed")
+	}
+}
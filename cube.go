@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/antonmedv/countdown/render"
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	// armDelay is how long space must be held before the timer is armed.
+	armDelay = 550 * time.Millisecond
+	// releaseGap is how long after the last repeat of a held space it
+	// counts as released; terminals do not report key releases.
+	releaseGap  = 120 * time.Millisecond
+	cubeRefresh = 10 * time.Millisecond
+)
+
+type cubeState int
+
+const (
+	cubeIdle cubeState = iota
+	cubeHolding
+	cubeArmed
+	cubeRunning
+)
+
+// cube runs a speedcubing session: hold space to arm, release to start,
+// any key to stop. Each solve is logged with its time as notes; Esc ends
+// the session.
+func cube(ctx context.Context, tag string, logPath string) bool {
+	state := cubeIdle
+	var solves []time.Duration
+	var started, holdStart, ignoreUntil time.Time
+	var released <-chan time.Time
+	var refresh *time.Ticker
+	var refreshC <-chan time.Time
+	var elapsed time.Duration
+	w, h = screen.Size()
+	drawCube(state, elapsed, solves, w, h)
+
+	for {
+		select {
+		case <-ctx.Done():
+			if state == cubeRunning {
+				bus.Publish(Event{Kind: SessionEnded, Tag: tag, LogPath: logPath})
+			}
+			return len(solves) > 0
+		case ev := <-queues:
+			now := time.Now()
+			if _, ok := ev.(*tcell.EventResize); ok {
+				w, h = screen.Size()
+				break
+			}
+			key, ok := ev.(*tcell.EventKey)
+			if !ok {
+				break
+			}
+			if state == cubeRunning {
+				elapsed = now.Sub(started)
+				refresh.Stop()
+				refreshC = nil
+				solves = append(solves, elapsed)
+				bus.Publish(Event{Kind: SessionEnded, Tag: tag, Notes: "solve " + formatSolve(elapsed), LogPath: logPath, Completed: true, Quiet: true})
+				state, ignoreUntil = cubeIdle, now.Add(inputDelayMS)
+				break
+			}
+			if now.Before(ignoreUntil) {
+				break
+			}
+			if pressed("quit", key) {
+				return len(solves) > 0
+			}
+			if !pressed("cube_timer", key) {
+				break
+			}
+			if state == cubeIdle {
+				state, holdStart, elapsed = cubeHolding, now, 0
+				// The first repeat of a held key takes longer than the rest.
+				released = time.After(keyRepeatGap)
+				break
+			}
+			if state == cubeHolding && now.Sub(holdStart) >= armDelay {
+				state = cubeArmed
+			}
+			released = time.After(releaseGap)
+		case <-released:
+			released = nil
+			if state == cubeArmed {
+				state, started = cubeRunning, time.Now()
+				bus.Publish(Event{Kind: SessionStarted, Tag: tag, LogPath: logPath})
+				refresh = time.NewTicker(cubeRefresh)
+				refreshC = refresh.C
+			} else {
+				state = cubeIdle
+			}
+		case <-refreshC:
+			elapsed = time.Since(started)
+		}
+		drawCube(state, elapsed, solves, w, h)
+	}
+}
+
+func drawCube(state cubeState, elapsed time.Duration, solves []time.Duration, w, h int) {
+	clear()
+	style := tcell.StyleDefault
+	switch state {
+	case cubeHolding:
+		style = style.Foreground(tcell.ColorRed)
+	case cubeArmed:
+		style = style.Foreground(tcell.ColorGreen)
+	}
+	renderer.drawTime(formatSolve(elapsed), style, w, h)
+
+	y := h/2 + render.Digits("0").Height()/2 + 1
+	stats := fmt.Sprintf("solves %d  ao5 %s  ao12 %s", len(solves), average(solves, 5), average(solves, 12))
+	echoString(stats, w/2-utf8.RuneCountInString(stats)/2, y, tcell.StyleDefault)
+	if len(solves) > 0 {
+		recent := solves
+		if len(recent) > 5 {
+			recent = recent[len(recent)-5:]
+		}
+		list := make([]string, len(recent))
+		for i, d := range recent {
+			list[i] = formatSolve(d)
+		}
+		line := strings.Join(list, "  ")
+		echoString(line, w/2-utf8.RuneCountInString(line)/2, y+1, tcell.StyleDefault.Dim(true))
+	}
+	if state == cubeIdle {
+		hint := "hold space, release to start, any key to stop, Esc to quit"
+		echoString(hint, w/2-utf8.RuneCountInString(hint)/2, h-2, tcell.StyleDefault.Dim(true))
+	}
+	flush()
+	renderer.present()
+}
+
+// formatSolve shows a solve to the centisecond: "12.34" or "1:02.34".
+func formatSolve(d time.Duration) string {
+	cs := int64(d / (10 * time.Millisecond))
+	if m := cs / 6000; m > 0 {
+		return fmt.Sprintf("%d:%02d.%02d", m, cs/100%60, cs%100)
+	}
+	return fmt.Sprintf("%d.%02d", cs/100, cs%100)
+}
+
+// average is the WCA average of the last n solves: the best and worst are
+// dropped and the rest are averaged.
+func average(solves []time.Duration, n int) string {
+	if len(solves) < n {
+		return "-"
+	}
+	last := append([]time.Duration(nil), solves[len(solves)-n:]...)
+	sort.Slice(last, func(i, j int) bool { return last[i] < last[j] })
+	var sum time.Duration
+	for _, d := range last[1 : n-1] {
+		sum += d
+	}
+	return formatSolve(sum / time.Duration(n-2))
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/antonmedv/countdown/timer"
+)
+
+// daemonRequest is what the control commands send to the daemon, one JSON
+// object per connection; the daemon answers with one daemonResponse.
+type daemonRequest struct {
+	Command  string        `json:"command"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Tag      string        `json:"tag,omitempty"`
+	Notes    string        `json:"notes,omitempty"`
+	// At is when a "schedule" job starts.
+	At time.Time `json:"at,omitempty"`
+	// ID is the job to "cancel".
+	ID int `json:"id,omitempty"`
+}
+
+type daemonResponse struct {
+	Error  string  `json:"error,omitempty"`
+	Status *Status `json:"status,omitempty"`
+	// URL is the share link, answered to "share".
+	URL string `json:"url,omitempty"`
+	// Jobs are the jobs scheduled, or the one scheduled or cancelled.
+	Jobs []daemonJob `json:"jobs,omitempty"`
+}
+
+// daemonSocket is the daemon's socket; each profile has its own daemon.
+func daemonSocket() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	name := "daemon.sock"
+	if profile != "" {
+		name = "daemon-" + profile + ".sock"
+	}
+	return filepath.Join(dir, "countdown", name)
+}
+
+// daemonTimer is the timer a daemon runs. Its state is "idle", "running"
+// or "paused".
+type daemonTimer struct {
+	mu       sync.Mutex
+	logPath  string
+	state    string
+	tag      string
+	notes    string
+	timer    *timer.Timer // nil while idle
+	sessions int
+	// shareURL is the read-only link of -share, if on.
+	shareURL string
+	jobs     jobQueue
+	// alarm wakes the daemon when the next job is due.
+	alarm *time.Timer
+	// closing keeps jobs from starting while the daemon shuts down.
+	closing bool
+	// events wait for unlock to publish them, so a slow hook or
+	// subscriber does not hold up the daemon's clients.
+	events []Event
+	// publishing keeps the events of one unlock before the next's.
+	publishing sync.Mutex
+}
+
+// publish has unlock publish e; the lock must be held.
+func (t *daemonTimer) publish(e Event) {
+	t.events = append(t.events, e)
+}
+
+// unlock releases the lock, then publishes the events of the hold.
+func (t *daemonTimer) unlock() {
+	events := t.events
+	t.events = nil
+	if len(events) == 0 {
+		t.mu.Unlock()
+		return
+	}
+	t.publishing.Lock()
+	defer t.publishing.Unlock()
+	t.mu.Unlock()
+	for _, e := range events {
+		bus.Publish(e)
+	}
+}
+
+func (t *daemonTimer) status() *Status {
+	var left, total time.Duration
+	if t.timer != nil {
+		left, total = t.timer.Left(), t.timer.Total()
+	}
+	s := newStatus(left, total, t.tag, t.notes)
+	s.State = t.state
+	return &s
+}
+
+func (t *daemonTimer) handle(req daemonRequest) daemonResponse {
+	t.mu.Lock()
+	defer t.unlock()
+
+	switch req.Command {
+	case "start":
+		if t.state != "idle" {
+			return daemonResponse{Error: "a timer is already " + t.state}
+		}
+		if req.Duration <= 0 {
+			return daemonResponse{Error: "duration must be positive"}
+		}
+		t.start(req.Duration, req.Tag, req.Notes)
+	case "pause":
+		if t.state != "running" {
+			return daemonResponse{Error: "no running timer"}
+		}
+		t.timer.Pause()
+		t.state = "paused"
+		t.publish(Event{Kind: SessionPaused, Tag: t.tag, LogPath: t.logPath})
+	case "resume":
+		if t.state != "paused" {
+			return daemonResponse{Error: "no paused timer"}
+		}
+		t.timer.Resume()
+		t.state = "running"
+		t.publish(Event{Kind: SessionResumed, Tag: t.tag, LogPath: t.logPath})
+	case "stop":
+		if t.state == "idle" {
+			return daemonResponse{Error: "no timer"}
+		}
+		t.end(false)
+	case "schedule":
+		return t.schedule(req)
+	case "jobs":
+		return daemonResponse{Jobs: t.jobs.Jobs}
+	case "cancel":
+		return t.cancel(req)
+	case "share":
+		if t.shareURL == "" {
+			return daemonResponse{Error: "sharing is off, start the daemon with -share <address>"}
+		}
+		return daemonResponse{URL: t.shareURL}
+	case "status":
+	default:
+		return daemonResponse{Error: fmt.Sprintf("unknown command %q", req.Command)}
+	}
+	return daemonResponse{Status: t.status()}
+}
+
+// start runs a timer for d; the lock must be held and the daemon idle.
+func (t *daemonTimer) start(d time.Duration, tag, notes string) {
+	t.state, t.tag, t.notes = "running", tag, notes
+	t.timer = timer.New(d, tick)
+	t.sessions++
+	t.watch()
+	t.timer.Start()
+	t.publish(Event{Kind: SessionStarted, Tag: t.tag, Notes: t.notes, LogPath: t.logPath, Left: d, Total: d})
+}
+
+// watch ends the current session once its timer runs out.
+func (t *daemonTimer) watch() {
+	current, session := t.timer, t.sessions
+	go func() {
+		<-current.Done()
+		if !current.Completed() {
+			return
+		}
+		t.mu.Lock()
+		if t.sessions != session || t.state != "running" {
+			t.mu.Unlock()
+			return
+		}
+		tag, notes := t.tag, t.notes
+		t.end(true)
+		t.unlock()
+		_ = desktopNotify("countdown: "+tag+" is done", notes)
+	}()
+}
+
+func (t *daemonTimer) end(completed bool) {
+	t.timer.Stop()
+	t.publish(Event{Kind: SessionEnded, Tag: t.tag, LogPath: t.logPath, Completed: completed})
+	t.state, t.tag, t.notes, t.timer = "idle", "", "", nil
+	if !t.closing {
+		t.wake()
+	}
+}
+
+// serveDaemon runs timers for the control commands until ctx is done,
+// which ends a running timer first. With shareAddr, it also serves a
+// read-only share link of the timer there.
+func serveDaemon(ctx context.Context, logPath, shareAddr string) {
+	path := daemonSocket()
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		stderr("error: a daemon is already listening on %s\n", path)
+		os.Exit(exitUsage)
+	}
+	_ = os.Remove(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	t := &daemonTimer{logPath: logPath, state: "idle", jobs: loadJobs(time.Now())}
+	if shareAddr != "" {
+		if t.shareURL, err = startShare(t, shareAddr); err != nil {
+			ln.Close()
+			stderr("error: -share: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+
+	t.mu.Lock()
+	t.wake()
+	t.unlock()
+
+	go func() {
+		<-ctx.Done()
+		t.mu.Lock()
+		t.closing = true
+		if t.alarm != nil {
+			t.alarm.Stop()
+		}
+		if t.state != "idle" {
+			t.end(false)
+		}
+		t.unlock()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			var req daemonRequest
+			if err := json.NewDecoder(conn).Decode(&req); err != nil {
+				return
+			}
+			_ = json.NewEncoder(conn).Encode(t.handle(req))
+		}()
+	}
+}
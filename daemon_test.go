@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDaemonPublishesUnlocked(t *testing.T) {
+	ended, release := make(chan struct{}), make(chan struct{})
+	cancel := bus.Subscribe(func(e Event) {
+		if e.Kind == SessionEnded {
+			close(ended)
+			<-release
+		}
+	})
+	defer cancel()
+
+	d := &daemonTimer{state: "idle"}
+	if res := d.handle(daemonRequest{Command: "start", Duration: time.Hour, Tag: "work"}); res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	go d.handle(daemonRequest{Command: "stop"})
+	<-ended
+
+	// The end's subscriber still blocks; the status does not wait for it.
+	status := make(chan daemonResponse)
+	go func() { status <- d.handle(daemonRequest{Command: "status"}) }()
+	select {
+	case res := <-status:
+		if res.Status == nil || res.Status.State != "idle" {
+			t.Errorf("status = %+v, want idle", res.Status)
+		}
+	case <-time.After(time.Second):
+		t.Error("status waited for the end's subscriber")
+	}
+	close(release)
+}
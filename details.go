@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var (
+	// showDetails is -details, toggled with its key: the tag, the notes
+	// and the time the countdown ends, under the digits.
+	showDetails bool
+	// details is the session on screen, set by countdown. The tag is its
+	// own, which the palette may change.
+	details struct {
+		tag   *string
+		notes string
+	}
+)
+
+// detailsLine is e.g. "Writing · chapter 3 · ends 14:35", with the day
+// when the countdown ends on another one.
+func detailsLine(left time.Duration) string {
+	var parts []string
+	if *details.tag != "" {
+		parts = append(parts, *details.tag)
+	}
+	if details.notes != "" {
+		parts = append(parts, details.notes)
+	}
+	if !openEnded {
+		now := time.Now()
+		end := now.Add(left)
+		layout := "15:04"
+		if end.YearDay() != now.YearDay() || end.Year() != now.Year() {
+			layout = "Mon 15:04"
+		}
+		parts = append(parts, "ends "+end.Format(layout))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// drawDetails draws the details line centered on row y, if there is one.
+func drawDetails(left time.Duration, w, y int) {
+	if details.tag == nil {
+		return
+	}
+	line := detailsLine(left)
+	echoString(line, w/2-utf8.RuneCountInString(line)/2, y, tcell.StyleDefault.Foreground(theme.text))
+}
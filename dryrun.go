@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Plan is what -dry-run reports: the resolved duration and the settings
+// the countdown would run with.
+type Plan struct {
+	Duration string    `json:"duration"`
+	Seconds  float64   `json:"seconds"`
+	End      time.Time `json:"end"`
+	Up       bool      `json:"up"`
+	Break    bool      `json:"break"`
+	Tag      string    `json:"tag"`
+	Notes    string    `json:"notes"`
+	LogPath  string    `json:"log_path"`
+	Profile  string    `json:"profile,omitempty"`
+	Config   string    `json:"config"`
+	Light    string    `json:"light,omitempty"`
+	Renderer string    `json:"renderer"`
+	Format   string    `json:"format,omitempty"`
+}
+
+func printPlan(p Plan) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(p); err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envNames spells out the variables for short flags.
+var envNames = map[string]string{
+	"t": "COUNTDOWN_TAG",
+	"n": "COUNTDOWN_NOTES",
+}
+
+// envSkip lists flags that read the environment on their own (-f from
+// COUNTDOWN_LOG_PATH, -profile from COUNTDOWN_PROFILE) and aliases.
+var envSkip = map[string]bool{
+	"f":           true,
+	"profile":     true,
+	"d":           true,
+	"as-duration": true,
+}
+
+// envSet lists the flags applyEnv set.
+var envSet = make(map[string]bool)
+
+func envName(flagName string) string {
+	if name, ok := envNames[flagName]; ok {
+		return name
+	}
+	return "COUNTDOWN_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnv sets every flag not given on the command line from its
+// COUNTDOWN_* variable. Flags set this way count as set, but the config
+// file still overrides them, see fromCommandLine.
+func applyEnv() error {
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		if err != nil || given[f.Name] || envSkip[f.Name] {
+			return
+		}
+		name := envName(f.Name)
+		if value, ok := os.LookupEnv(name); ok {
+			if e := flag.Set(f.Name, value); e != nil {
+				err = fmt.Errorf("%s: %v", name, e)
+			}
+			envSet[f.Name] = true
+		}
+	})
+	return err
+}
+
+// fromCommandLine reports whether the flag was given on the command line
+// rather than set from the environment.
+func fromCommandLine(name string) bool {
+	return isFlagSet(name) && !envSet[name]
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/antonmedv/countdown/logbook"
+	"github.com/antonmedv/countdown/render"
+)
+
+// Exit statuses, so wrappers can tell a mistake in the arguments from a
+// broken environment:
+//
+//	0    the countdown completed
+//	1    it was aborted
+//	2    invalid arguments, flags or config (parse.ErrInvalid among them)
+//	3    the log can't be opened or written (logbook.ErrUnavailable)
+//	4    the terminal can't be initialized (render.ErrTerminalInit)
+//	124  the command of "countdown run" timed out
+const (
+	exitAborted  = 1
+	exitUsage    = 2
+	exitLog      = 3
+	exitTerminal = 4
+)
+
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, logbook.ErrUnavailable):
+		return exitLog
+	case errors.Is(err, render.ErrTerminalInit):
+		return exitTerminal
+	}
+	return exitUsage
+}
+
+// fail hands back the terminal if needed, writes out the -cpuprofile so
+// far, reports err and exits with its status.
+func fail(err error) {
+	if screen != nil {
+		if stopEvents != nil {
+			stopEvents()
+		}
+		closeScreen()
+	}
+	stopProfile()
+	stderr("error: %v\n", err)
+	os.Exit(exitCode(err))
+}
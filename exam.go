@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antonmedv/countdown/render"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Exam is the state of -exam: no pausing, announcements as time runs low
+// and a read-only record of the exam next to the log.
+type Exam struct {
+	announce []time.Duration
+	tts      bool
+	end      time.Time
+	message  string
+	audit    *os.File
+	sum      hash.Hash
+	record   io.Writer
+}
+
+var exam *Exam
+
+// parseAnnouncements reads "30m,15m,5m" into durations, longest first.
+func parseAnnouncements(s string) ([]time.Duration, error) {
+	var list []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid announcement %q", part)
+		}
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] > list[j] })
+	return list, nil
+}
+
+// startExam creates the record of the exam, which is read-only from the
+// start and never overwritten. That guards it against mistakes, not
+// against its owner: the session in a log chained with -audit is what
+// shows the times were not changed.
+func startExam(announce []time.Duration, tts bool, d time.Duration, tag, logPath string) (*Exam, error) {
+	now := time.Now()
+	name := filepath.Join(filepath.Dir(logPath), "exam-"+now.Format("20060102-150405")+".txt")
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		return nil, err
+	}
+	e := &Exam{announce: announce, tts: tts, end: now.Add(d), audit: f, sum: sha256.New()}
+	e.record = io.MultiWriter(f, e.sum)
+	e.log(now, "start %s, duration %s, ends %s", tag, d, e.end.Format("15:04:05"))
+	// Drop announcements that would fire immediately.
+	for len(e.announce) > 0 && e.announce[0] >= d {
+		e.announce = e.announce[1:]
+	}
+	return e, nil
+}
+
+func (e *Exam) log(t time.Time, f string, a ...interface{}) {
+	fmt.Fprintf(e.record, "%s "+f+"\n", append([]interface{}{t.Format("2006-01-02 15:04:05")}, a...)...)
+}
+
+// announcement writes d without its zero units, e.g. 30m, 1h or 1m30s.
+func announcement(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// check makes the next announcement once timeLeft reaches it.
+func (e *Exam) check(timeLeft time.Duration) {
+	if len(e.announce) == 0 || timeLeft > e.announce[0] {
+		return
+	}
+	e.message = announcement(e.announce[0]) + " remaining"
+	e.announce = e.announce[1:]
+	e.log(time.Now(), "announce %s", e.message)
+	if e.tts {
+		speak(e.message)
+	}
+}
+
+// finish closes the record with a checksum of everything above it, which
+// tells a damaged record from a whole one. Anyone who can rewrite the
+// record can rewrite the checksum too.
+func (e *Exam) finish(completed bool) {
+	outcome := "completed"
+	if !completed {
+		outcome = "aborted"
+	}
+	e.log(time.Now(), "%s", outcome)
+	fmt.Fprintf(e.audit, "sha256 %s\n", hex.EncodeToString(e.sum.Sum(nil)))
+	e.audit.Close()
+}
+
+// drawEnd shows the end time in big digits above the remaining time, when
+// there is room for it.
+func (e *Exam) drawEnd(w, h int) {
+	text := render.Digits(e.end.Format("15:04"))
+	y := h/2 - text.Height()/2 - text.Height() - 2
+	if y < 1 {
+		return
+	}
+	echoString("ends at", w/2-len("ends at")/2, y-1, tcell.StyleDefault.Dim(true))
+	x := w/2 - text.Width()/2
+	for _, s := range text {
+		echo(s, x, y, tcell.StyleDefault)
+		x += s.Width()
+	}
+}
+
+// speak reads text aloud with the platform's text-to-speech, if any.
+func speak(text string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("say", text)
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-Command",
+			"Add-Type -AssemblyName System.Speech; (New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak("+powershellString(text)+")")
+	default:
+		cmd = exec.Command("espeak", text)
+		if path, err := exec.LookPath("spd-say"); err == nil {
+			cmd = exec.Command(path, text)
+		}
+	}
+	if cmd.Start() == nil {
+		go cmd.Wait()
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnnouncement(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{30 * time.Minute, "30m"},
+		{15 * time.Minute, "15m"},
+		{10 * time.Minute, "10m"},
+		{5 * time.Minute, "5m"},
+		{90 * time.Second, "1m30s"},
+		{10 * time.Second, "10s"},
+		{time.Hour, "1h"},
+		{90 * time.Minute, "1h30m"},
+		{time.Hour + 10*time.Second, "1h0m10s"},
+	}
+	for _, tt := range tests {
+		if got := announcement(tt.d); got != tt.want {
+			t.Errorf("announcement(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
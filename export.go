@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/antonmedv/countdown/logbook"
+)
+
+// dateLayout is how -from and -to are given.
+const dateLayout = "2006-01-02"
+
+// exportCSVHeader is one row per ended session, with times in the log's
+// local time and durations as H:MM:SS, which spreadsheets read as times.
+var exportCSVHeader = []string{"start", "end", "duration", "paused", "tag", "notes", "outcome"}
+
+// icsTimeLayout is how times are written in iCalendar, in UTC.
+const icsTimeLayout = "20060102T150405Z"
+
+// icsLineLength is how long a line of iCalendar may be, in bytes, before
+// it is folded.
+const icsLineLength = 75
+
+// exportCommand writes the sessions that started from the day from to the
+// day to, both included and either optional, to stdout as CSV, or as
+// iCalendar with ics.
+func exportCommand(logPath, from, to string, ics bool) {
+	var since, until time.Time
+	var err error
+	if from != "" {
+		if since, err = time.ParseInLocation(dateLayout, from, time.Local); err != nil {
+			stderr("error: -from: want a date like 2024-01-31\n")
+			os.Exit(2)
+		}
+	}
+	if to != "" {
+		if until, err = time.ParseInLocation(dateLayout, to, time.Local); err != nil {
+			stderr("error: -to: want a date like 2024-01-31\n")
+			os.Exit(2)
+		}
+		until = until.AddDate(0, 0, 1)
+	}
+	events, err := logbook.Read(logPath)
+	if err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(1)
+	}
+	var sessions []logbook.Session
+	for _, s := range logbook.Sessions(events) {
+		if s.Start.Before(since) || (!until.IsZero() && !s.Start.Before(until)) {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	if ics {
+		err = writeICS(os.Stdout, sessions)
+	} else {
+		err = writeCSV(os.Stdout, sessions)
+	}
+	if err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func writeCSV(w io.Writer, sessions []logbook.Session) error {
+	out := csv.NewWriter(w)
+	_ = out.Write(exportCSVHeader)
+	for _, s := range sessions {
+		_ = out.Write([]string{
+			s.Start.Format(logbook.TimeLayout), s.End.Format(logbook.TimeLayout),
+			clockDuration(s.Active()), clockDuration(s.Paused), s.Tag, s.Notes, s.Outcome,
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeICS writes the sessions as events of a calendar, named by their
+// tags. A session keeps its UID from one export to the next, so a
+// calendar that imports the file again updates rather than duplicates it.
+func writeICS(w io.Writer, sessions []logbook.Session) error {
+	out := bufio.NewWriter(w)
+	line := func(s string) {
+		// Lines are folded at icsLineLength bytes, between runes.
+		for len(s) > icsLineLength {
+			cut := icsLineLength
+			for cut > 0 && s[cut]&0xc0 == 0x80 {
+				cut--
+			}
+			out.WriteString(s[:cut] + "\r\n")
+			s = " " + s[cut:]
+		}
+		out.WriteString(s + "\r\n")
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//countdown//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:countdown")
+	for _, s := range sessions {
+		uid := sha256.Sum256([]byte(s.Start.UTC().Format(time.RFC3339Nano) + " " + s.Tag))
+		description := []string{"active " + clockDuration(s.Active())}
+		if s.Paused > 0 {
+			description = append(description, "paused "+clockDuration(s.Paused))
+		}
+		if s.Notes != "" {
+			description = append(description, s.Notes)
+		}
+		if s.Outcome != "" {
+			description = append(description, s.Outcome)
+		}
+		line("BEGIN:VEVENT")
+		line("UID:" + hex.EncodeToString(uid[:16]) + "@countdown")
+		line("DTSTAMP:" + s.End.UTC().Format(icsTimeLayout))
+		line("DTSTART:" + s.Start.UTC().Format(icsTimeLayout))
+		line("DTEND:" + s.End.UTC().Format(icsTimeLayout))
+		line("SUMMARY:" + icsText(s.Tag))
+		line("DESCRIPTION:" + icsText(strings.Join(description, "\n")))
+		line("CATEGORIES:" + icsText(s.Tag))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return out.Flush()
+}
+
+// icsText escapes s as an iCalendar TEXT value.
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "").Replace(s)
+}
+
+// clockDuration writes d as H:MM:SS.
+func clockDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
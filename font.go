@@ -1,97 +0,0 @@
-package main
-
-var defaultFont = Font{
-	':': {
-		"   ",
-		"██╗",
-		"╚═╝",
-		"██╗",
-		"╚═╝",
-		"   ",
-	},
-	'0': {
-		" ██████╗ ",
-		"██╔═████╗",
-		"██║██╔██║",
-		"████╔╝██║",
-		"╚██████╔╝",
-		" ╚═════╝ ",
-	},
-	'1': {
-		" ██╗",
-		"███║",
-		"╚██║",
-		" ██║",
-		" ██║",
-		" ╚═╝",
-	},
-	'2': {
-		"██████╗ ",
-		"╚════██╗",
-		" █████╔╝",
-		"██╔═══╝ ",
-		"███████╗",
-		"╚══════╝",
-	},
-	'3': {
-		"██████╗ ",
-		"╚════██╗",
-		" █████╔╝",
-		" ╚═══██╗",
-		"██████╔╝",
-		"╚═════╝ ",
-	},
-	'4': {
-		"██╗  ██╗",
-		"██║  ██║",
-		"███████║",
-		"╚════██║",
-		"     ██║",
-		"     ╚═╝",
-	},
-	'5': {
-		"███████╗",
-		"██╔════╝",
-		"███████╗",
-		"╚════██║",
-		"███████║",
-		"╚══════╝",
-	},
-	'6': {
-		" ██████╗ ",
-		"██╔════╝ ",
-		"███████╗ ",
-		"██╔═══██╗",
-		"╚██████╔╝",
-		" ╚═════╝ ",
-	},
-	'7': {
-		"███████╗",
-		"╚════██║",
-		"    ██╔╝",
-		"   ██╔╝ ",
-		"   ██║  ",
-		"   ╚═╝  ",
-	},
-	'8': {
-		" █████╗ ",
-		"██╔══██╗",
-		"╚█████╔╝",
-		"██╔══██╗",
-		"╚█████╔╝",
-		" ╚════╝ ",
-	},
-	'9': {
-		" █████╗ ",
-		"██╔══██╗",
-		"╚██████║",
-		" ╚═══██║",
-		" █████╔╝",
-		" ╚════╝ ",
-	},
-}
-
-var pausedText = Symbol{
-	"█▀▄ ▄▀▄ █ █ ▄▀▀ ██▀ █▀▄",
-	"█▀  █▀█ ▀▄█ ▄██ █▄▄ █▄▀",
-}
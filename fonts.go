@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/antonmedv/countdown/render"
+)
+
+// loadFont finds the digit font -font names: a built-in one, or a FIGlet
+// font file.
+func loadFont(name string) (render.Font, error) {
+	if f, ok := render.Fonts[name]; ok {
+		return f, nil
+	}
+	if !strings.HasSuffix(name, ".flf") && !strings.ContainsRune(name, os.PathSeparator) {
+		var names []string
+		for n := range render.Fonts {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown font %q, expected one of %s or a .flf file", name, strings.Join(names, ", "))
+	}
+	file, err := os.Open(expandHome(name))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	f, err := render.ParseFiglet(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import "github.com/gdamore/tcell/v2"
+
+// frame is an off-screen copy of the screen. Everything is drawn into the
+// back frame, and flush hands tcell only the cells that differ from the
+// front frame, the one last shown.
+type frame struct {
+	w, h  int
+	cells []cell
+}
+
+type cell struct {
+	r     rune
+	style tcell.Style
+}
+
+var back, front frame
+
+func (f *frame) reset(w, h int) {
+	if f.w != w || f.h != h {
+		f.w, f.h = w, h
+		f.cells = make([]cell, w*h)
+	}
+	for i := range f.cells {
+		f.cells[i] = cell{r: ' '}
+	}
+}
+
+func (f *frame) cell(x, y int) *cell {
+	if x < 0 || y < 0 || x >= f.w || y >= f.h {
+		return nil
+	}
+	return &f.cells[y*f.w+x]
+}
+
+func setCell(x, y int, r rune, style tcell.Style) {
+	if c := back.cell(x, y); c != nil {
+		*c = cell{r: r, style: style}
+	}
+}
+
+func setBg(x, y int, bg tcell.Color) {
+	if c := back.cell(x, y); c != nil {
+		c.style = c.style.Background(bg)
+	}
+}
+
+// present copies the changed cells of the back frame to the screen and
+// makes it the front frame. A size change repaints everything.
+func present() {
+	full := front.w != back.w || front.h != back.h
+	if full {
+		screen.Clear()
+		front.w, front.h = back.w, back.h
+		front.cells = make([]cell, len(back.cells))
+	}
+	for i, c := range back.cells {
+		if full || c != front.cells[i] {
+			screen.SetContent(i%back.w, i/back.w, c.r, nil, c.style)
+			front.cells[i] = c
+		}
+	}
+}
@@ -1,8 +1,21 @@
 module github.com/antonmedv/countdown
 
-go 1.14
+go 1.18
 
 require (
-	github.com/mattn/go-runewidth v0.0.13 // indirect
-	github.com/nsf/termbox-go v1.1.1
+	github.com/BurntSushi/toml v1.4.0
+	github.com/gdamore/tcell/v2 v2.8.1
+	github.com/mattn/go-runewidth v0.0.16
+	golang.org/x/crypto v0.32.0
+	golang.org/x/image v0.18.0
+	golang.org/x/sys v0.29.0
+	golang.org/x/term v0.28.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/gdamore/encoding v1.0.1 // indirect
+	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
+	github.com/rivo/uniseg v0.4.3 // indirect
+	golang.org/x/text v0.21.0 // indirect
 )
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// keyHelp is a line of the help overlay opened with ?.
+type keyHelp struct {
+	keys   string
+	action string
+}
+
+// activeKeys lists the keys the countdown answers to right now, which
+// depend on the mode it runs in and on [keys] in the config.
+func activeKeys(paused bool) []keyHelp {
+	var list []keyHelp
+	add := func(action, help string) {
+		if label := keyLabel(action); label != "" {
+			list = append(list, keyHelp{label, help})
+		}
+	}
+	if exam == nil {
+		if paused {
+			add("pause", "resume")
+		} else {
+			add("pause", "pause")
+		}
+	}
+	switch {
+	case openEnded:
+		add("quit", "stop and log the time")
+	case confirmQuit:
+		add("quit", "twice within 2s to quit, logged as aborted")
+	default:
+		add("quit", "quit, logged as aborted")
+	}
+	if exam == nil {
+		add("restart", "restart with the whole duration")
+	}
+	if classroom != nil {
+		list = append(list, keyHelp{fmt.Sprintf("1-%d", len(classroom.presets)), "jump to a preset"})
+		add("classroom_add", "add 2 minutes")
+	} else if exam == nil && !openEnded {
+		add("add_10s", "add 10 seconds")
+		add("take_10s", "take off 10 seconds")
+		add("add_1m", "add a minute")
+		add("take_1m", "take off a minute")
+	}
+	if !openEnded {
+		add("progress", "show or hide the progress bar")
+	}
+	add("details", "show or hide the tag, notes and end time")
+	if stepControls {
+		add("next", "skip to the next step")
+	}
+	if exam == nil {
+		add("palette", "open the command palette")
+		var bound []keyHelp
+		for name, m := range macros {
+			if m.Key != "" {
+				bound = append(bound, keyHelp{m.Key, "macro " + name})
+			}
+		}
+		sort.Slice(bound, func(i, j int) bool { return bound[i].keys < bound[j].keys })
+		list = append(list, bound...)
+	}
+	add("help", "show or hide this help")
+	return list
+}
+
+// drawHelp draws the keys in a box in the middle of the screen, over
+// whatever is there.
+func drawHelp(keys []keyHelp, w, h int) {
+	keyWidth, width := 0, 0
+	for _, k := range keys {
+		if n := utf8.RuneCountInString(k.keys); n > keyWidth {
+			keyWidth = n
+		}
+	}
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = k.keys + strings.Repeat(" ", keyWidth-utf8.RuneCountInString(k.keys)) + "  " + k.action
+		if n := utf8.RuneCountInString(lines[i]); n > width {
+			width = n
+		}
+	}
+	x0, y0 := w/2-width/2-2, h/2-len(lines)/2-1
+	style := tcell.StyleDefault.Reverse(true)
+	for y := y0; y < y0+len(lines)+2; y++ {
+		for x := x0; x < x0+width+4; x++ {
+			setCell(x, y, ' ', style)
+		}
+	}
+	for i, line := range lines {
+		echoString(line, x0+2, y0+1+i, style)
+	}
+	flush()
+}
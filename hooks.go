@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/antonmedv/countdown/render"
+)
+
+// Hooks are the shell commands of -on-start, -on-pause, -on-resume,
+// -on-finish and -on-abort.
+type Hooks struct {
+	Start, Pause, Resume, Finish, Abort string
+}
+
+func (h Hooks) empty() bool {
+	return h == Hooks{}
+}
+
+// hookData is what a hook's {{...}} can use, such as
+// 'notify-send "{{.Tag}} done after {{.Actual}}"'. Planned, Actual (pauses
+// excluded) and Left are formatted like the digits, Elapsed is in seconds.
+type hookData struct {
+	Event, Tag, Notes, Outcome string
+	Planned, Actual, Left      string
+	Elapsed                    string
+}
+
+// parse compiles the hooks, keyed by event name.
+func (h Hooks) parse() (map[string]*template.Template, error) {
+	templates := map[string]*template.Template{}
+	for name, command := range map[string]string{
+		"start": h.Start, "pause": h.Pause, "resume": h.Resume, "finish": h.Finish, "abort": h.Abort,
+	} {
+		if command == "" {
+			continue
+		}
+		t, err := template.New(name).Parse(command)
+		if err == nil {
+			// Unknown fields only show when the template runs.
+			_, err = expandHook(t, hookData{})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("-on-%s: %v", name, err)
+		}
+		templates[name] = t
+	}
+	return templates, nil
+}
+
+var hookPlaceholder = regexp.MustCompile("\x00([0-9]+)\x00")
+
+// expandHook runs the template with every value replaced by a
+// placeholder, then quotes each value for the shell where its placeholder
+// landed: unquoted, in single quotes or in double quotes. So a tag or note
+// cannot break out into a command of its own.
+func expandHook(t *template.Template, d hookData) (string, error) {
+	var values []string
+	hold := func(v string) string {
+		values = append(values, v)
+		return fmt.Sprintf("\x00%d\x00", len(values)-1)
+	}
+	held := hookData{
+		Event: hold(d.Event), Tag: hold(d.Tag), Notes: hold(d.Notes), Outcome: hold(d.Outcome),
+		Planned: hold(d.Planned), Actual: hold(d.Actual), Left: hold(d.Left), Elapsed: hold(d.Elapsed),
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, held); err != nil {
+		return "", err
+	}
+	command := b.String()
+	var out strings.Builder
+	var quote byte
+	for i := 0; i < len(command); i++ {
+		if m := hookPlaceholder.FindStringSubmatchIndex(command[i:]); command[i] == 0 && m != nil && m[0] == 0 {
+			n, _ := strconv.Atoi(command[i+m[2] : i+m[3]])
+			out.WriteString(quoteIn(values[n], quote))
+			i += m[1] - 1
+			continue
+		}
+		c := command[i]
+		switch {
+		case c == '\\' && quote != '\'' && runtime.GOOS != "windows" && i+1 < len(command):
+			out.WriteByte(c)
+			i++
+			c = command[i]
+		case quote == 0 && (c == '\'' && runtime.GOOS != "windows" || c == '"'):
+			quote = c
+		case c == quote:
+			quote = 0
+		}
+		out.WriteByte(c)
+	}
+	return out.String(), nil
+}
+
+// quoteIn quotes v for sh inside the quote it is in, 0 for none. cmd has
+// no escape inside double quotes, so there " and % are dropped.
+func quoteIn(v string, quote byte) string {
+	if runtime.GOOS == "windows" {
+		v = strings.NewReplacer(`"`, "", "%", "").Replace(v)
+		if quote == 0 {
+			return `"` + v + `"`
+		}
+		return v
+	}
+	switch quote {
+	case '\'':
+		return strings.ReplaceAll(v, "'", `'\''`)
+	case '"':
+		return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(v)
+	}
+	return shellQuote(v)
+}
+
+// hookEvents returns the subscriber that runs the hooks, compiled by
+// Hooks.parse. A hook gets the session in its template (see hookData) and
+// in COUNTDOWN_EVENT, COUNTDOWN_TAG, COUNTDOWN_NOTES, COUNTDOWN_ELAPSED
+// (whole seconds, pauses excluded) and, at the end, COUNTDOWN_OUTCOME. The
+// end hooks are waited for so they run before countdown exits; the others
+// run in the background. Sessions side by side with -parallel are told
+// apart by tag, as in the log.
+func hookEvents(hooks map[string]*template.Template) func(Event) {
+	sessions := make(map[string]*hookSession)
+	last := &hookSession{}
+	return func(e Event) {
+		now := time.Now()
+		s, ok := sessions[e.Tag]
+		if !ok {
+			s = last
+		}
+		var name string
+		switch e.Kind {
+		case SessionStarted:
+			s = &hookSession{notes: e.Notes, started: now, planned: e.Total}
+			sessions[e.Tag], last = s, s
+			name = "start"
+		case SessionPaused:
+			s.pausedAt = now
+			name = "pause"
+		case SessionResumed:
+			if !s.pausedAt.IsZero() {
+				s.paused += now.Sub(s.pausedAt)
+				s.pausedAt = time.Time{}
+			}
+			name = "resume"
+		case SessionChanged:
+			s.planned = e.Total
+		case SessionEnded:
+			delete(sessions, e.Tag)
+			name = "abort"
+			if e.Completed {
+				name = "finish"
+			}
+		}
+		t, ok := hooks[name]
+		if !ok {
+			return
+		}
+		elapsed := s.elapsed(now)
+		d := hookData{
+			Event: name, Tag: e.Tag, Notes: s.notes,
+			Planned: render.Format(s.planned), Actual: render.Format(elapsed), Left: render.Format(s.planned - elapsed),
+			Elapsed: strconv.Itoa(int(elapsed.Seconds())),
+		}
+		if e.Kind == SessionEnded {
+			d.Outcome = e.Notes
+		}
+		command, err := expandHook(t, d)
+		if err != nil {
+			return
+		}
+		cmd := shellCommand(command)
+		cmd.Env = append(os.Environ(),
+			"COUNTDOWN_EVENT="+name,
+			"COUNTDOWN_TAG="+e.Tag,
+			"COUNTDOWN_NOTES="+s.notes,
+			fmt.Sprintf("COUNTDOWN_ELAPSED=%d", int(elapsed.Seconds())),
+		)
+		if e.Kind == SessionEnded {
+			cmd.Env = append(cmd.Env, "COUNTDOWN_OUTCOME="+e.Notes)
+			_ = cmd.Run()
+			return
+		}
+		if cmd.Start() == nil {
+			go cmd.Wait()
+		}
+	}
+}
+
+// hookSession is what the hooks keep of a session.
+type hookSession struct {
+	notes             string
+	started, pausedAt time.Time
+	paused, planned   time.Duration
+}
+
+// elapsed is the time s has run by now, pauses excluded.
+func (s *hookSession) elapsed(now time.Time) time.Duration {
+	d := now.Sub(s.started) - s.paused
+	if !s.pausedAt.IsZero() {
+		d -= now.Sub(s.pausedAt)
+	}
+	return d
+}
+
+// shellCommand runs command with sh, or cmd on Windows.
+func shellCommand(command string) *exec.Cmd {
+	return shellCommandContext(context.Background(), command)
+}
+
+// shellCommandContext is shellCommand, killed once ctx is done.
+func shellCommandContext(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestHookEventsParallel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook is a sh command")
+	}
+	out := filepath.Join(t.TempDir(), "hooks")
+	templates, err := Hooks{Finish: `echo "{{.Tag}} {{.Notes}} {{.Planned}}" >> ` + shellQuote(out)}.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := hookEvents(templates)
+	hook(Event{Kind: SessionStarted, Tag: "tea", Notes: "green", Total: 3 * time.Minute})
+	hook(Event{Kind: SessionStarted, Tag: "eggs", Notes: "soft", Total: 6 * time.Minute})
+	hook(Event{Kind: SessionChanged, Tag: "tea", Total: 4 * time.Minute})
+	hook(Event{Kind: SessionEnded, Tag: "tea", Completed: true})
+	hook(Event{Kind: SessionEnded, Tag: "eggs", Completed: true})
+
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "tea green 04:00\neggs soft 06:00\n"; string(b) != want {
+		t.Errorf("hooks ran %q, want %q", b, want)
+	}
+}
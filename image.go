@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	imageWidthRatio  = 0.7
+	imageHeightRatio = 0.5
+	sixelLevels      = 16
+	kittyChunkSize   = 4096
+)
+
+// imageRenderer draws antialiased digits as an inline image using one of
+// the terminal graphics protocols.
+type imageRenderer struct {
+	font    *opentype.Font
+	encode  func(img *image.RGBA) []byte
+	pending []byte
+	x, y    int
+	w, h    int
+}
+
+func newImageRenderer(encode func(img *image.RGBA) []byte) (*imageRenderer, error) {
+	f, err := opentype.Parse(gomonobold.TTF)
+	if err != nil {
+		return nil, err
+	}
+	return &imageRenderer{font: f, encode: encode}, nil
+}
+
+func (r *imageRenderer) drawTime(str string, style tcell.Style, w, h int) {
+	pxW, pxH, ok := terminalPixels()
+	if !ok || w == 0 || h == 0 {
+		r.pending = nil
+		return
+	}
+	cellW, cellH := float64(pxW)/float64(w), float64(pxH)/float64(h)
+
+	widthRatio := math.Min(0.95, imageWidthRatio*float64(digitScale))
+	heightRatio := math.Min(0.8, imageHeightRatio*float64(digitScale))
+	size := r.fitSize(str, float64(pxW)*widthRatio, float64(pxH)*heightRatio)
+	face, err := opentype.NewFace(r.font, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		r.pending = nil
+		return
+	}
+	defer face.Close()
+
+	metrics := face.Metrics()
+	width := font.MeasureString(face, str).Ceil()
+	height := (metrics.Ascent + metrics.Descent).Ceil()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(imageColor(style)),
+		Face: face,
+		Dot:  fixed.Point26_6{Y: metrics.Ascent},
+	}
+	d.DrawString(str)
+
+	cols := int(math.Ceil(float64(width) / cellW))
+	rows := int(math.Ceil(float64(height) / cellH))
+	r.x, r.y = w/2-cols/2, h/2-rows/2
+	r.pending = r.encode(img)
+	if w != r.w || h != r.h {
+		// Old pixels outside of tcell's knowledge must be wiped.
+		r.w, r.h = w, h
+		screen.Sync()
+	}
+}
+
+// fitSize returns the largest font size at which str fits in maxW x maxH.
+func (r *imageRenderer) fitSize(str string, maxW, maxH float64) float64 {
+	const probe = 100
+	face, err := opentype.NewFace(r.font, &opentype.FaceOptions{Size: probe, DPI: 72})
+	if err != nil {
+		return probe
+	}
+	defer face.Close()
+	metrics := face.Metrics()
+	width := float64(font.MeasureString(face, str).Ceil())
+	height := float64((metrics.Ascent + metrics.Descent).Ceil())
+	return math.Max(1, probe*math.Min(maxW/width, maxH/height))
+}
+
+func (r *imageRenderer) present() {
+	if r.pending == nil {
+		return
+	}
+	// Save and restore the cursor so tcell's idea of it stays right.
+	fmt.Fprintf(os.Stdout, "\0337\033[%d;%dH", r.y+1, r.x+1)
+	os.Stdout.Write(r.pending)
+	os.Stdout.WriteString("\0338")
+	r.pending = nil
+}
+
+func imageColor(style tcell.Style) color.RGBA {
+	fg, _, _ := style.Decompose()
+	if fg == tcell.ColorDefault {
+		return color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	}
+	r, g, b := fg.RGB()
+	return color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 0xff}
+}
+
+func encodeKitty(img *image.RGBA) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil
+	}
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	var out bytes.Buffer
+	// Delete the previous frame before placing the new one.
+	out.WriteString("\033_Ga=d,q=2\033\\")
+	for i := 0; i < len(data); i += kittyChunkSize {
+		end := i + kittyChunkSize
+		more := 1
+		if end >= len(data) {
+			end, more = len(data), 0
+		}
+		if i == 0 {
+			fmt.Fprintf(&out, "\033_Ga=T,f=100,q=2,C=1,m=%d;%s\033\\", more, data[i:end])
+		} else {
+			fmt.Fprintf(&out, "\033_Gm=%d;%s\033\\", more, data[i:end])
+		}
+	}
+	return out.Bytes()
+}
+
+// encodeSixel quantizes the image by alpha into a few shades of its color,
+// which is all antialiased single-color text needs.
+func encodeSixel(img *image.RGBA) []byte {
+	b := img.Bounds()
+	var fg color.RGBA
+	levels := make([]int, b.Dx()*b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := img.RGBAAt(x, y)
+			if c.A > 0 {
+				fg = color.RGBA{
+					R: uint8(int(c.R) * 0xff / int(c.A)),
+					G: uint8(int(c.G) * 0xff / int(c.A)),
+					B: uint8(int(c.B) * 0xff / int(c.A)),
+				}
+			}
+			levels[y*b.Dx()+x] = int(c.A) * (sixelLevels - 1) / 0xff
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "\033P0;1;0q\"1;1;%d;%d", b.Dx(), b.Dy())
+	for l := 1; l < sixelLevels; l++ {
+		fmt.Fprintf(&out, "#%d;2;%d;%d;%d", l,
+			int(fg.R)*100*l/(sixelLevels-1)/0xff,
+			int(fg.G)*100*l/(sixelLevels-1)/0xff,
+			int(fg.B)*100*l/(sixelLevels-1)/0xff)
+	}
+	for band := 0; band < b.Dy(); band += 6 {
+		for l := 1; l < sixelLevels; l++ {
+			fmt.Fprintf(&out, "#%d", l)
+			last, run := byte(0), 0
+			for x := 0; x < b.Dx(); x++ {
+				bits := 0
+				for i := 0; i < 6 && band+i < b.Dy(); i++ {
+					if levels[(band+i)*b.Dx()+x] == l {
+						bits |= 1 << i
+					}
+				}
+				ch := byte(63 + bits)
+				if ch == last {
+					run++
+					continue
+				}
+				writeSixelRun(&out, last, run)
+				last, run = ch, 1
+			}
+			writeSixelRun(&out, last, run)
+			out.WriteByte('$')
+		}
+		out.WriteByte('-')
+	}
+	out.WriteString("\033\\")
+	return out.Bytes()
+}
+
+func writeSixelRun(out *bytes.Buffer, ch byte, run int) {
+	switch {
+	case run == 0:
+	case run > 3:
+		fmt.Fprintf(out, "!%d%c", run, ch)
+	default:
+		out.Write(bytes.Repeat([]byte{ch}, run))
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antonmedv/countdown/parse"
+	"github.com/gdamore/tcell/v2"
+)
+
+// IntervalConfig is the [interval] section of the config file: the colors
+// of the work and rest digits and the sounds their phases start with.
+// Without a sound the bell rings once for work and twice for rest.
+//
+//	[interval]
+//	work_color = "red"
+//	rest_color = "#00aa88"
+//	work_sound = "/home/me/sounds/whistle.wav"
+type IntervalConfig struct {
+	WorkColor string `toml:"work_color,omitempty"`
+	RestColor string `toml:"rest_color,omitempty"`
+	WorkSound string `toml:"work_sound,omitempty"`
+	RestSound string `toml:"rest_sound,omitempty"`
+}
+
+// intervalPlan is a parsed -interval, 20s of work and 10s of rest for 8
+// rounds by default, as in Tabata.
+type intervalPlan struct {
+	work, rest time.Duration
+	rounds     int
+}
+
+// parseInterval reads the -interval arguments: work=40s rest=20s rounds=8.
+func parseInterval(args []string, unit string) (*intervalPlan, error) {
+	p := &intervalPlan{work: 20 * time.Second, rest: 10 * time.Second, rounds: 8}
+	for _, arg := range args {
+		i := strings.Index(arg, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("-interval: %q is not work=, rest= or rounds=", arg)
+		}
+		key, value := arg[:i], arg[i+1:]
+		switch key {
+		case "work", "rest":
+			d, err := parse.Arg(value, true, unit)
+			if err != nil || d < 0 || key == "work" && d == 0 {
+				return nil, fmt.Errorf("-interval: invalid %s %q", key, value)
+			}
+			if key == "work" {
+				p.work = d
+			} else {
+				p.rest = d
+			}
+		case "rounds":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("-interval: invalid rounds %q", value)
+			}
+			p.rounds = n
+		default:
+			return nil, fmt.Errorf("-interval: unknown %q, use work=, rest= or rounds=", key)
+		}
+	}
+	return p, nil
+}
+
+// runInterval alternates work and rest, in their own colors, with the
+// rounds left under the digits. Phases are logged with notes such as
+// "work 3/8". There is no rest after the last round.
+func runInterval(ctx context.Context, p *intervalPlan, c IntervalConfig, tag, logPath string) bool {
+	color := digitsColor
+	defer func() {
+		caption = ""
+		digitsColor = color
+	}()
+	workColor, restColor := tcell.ColorRed, tcell.ColorGreen
+	if c.WorkColor != "" {
+		workColor = tcell.GetColor(c.WorkColor)
+	}
+	if c.RestColor != "" {
+		restColor = tcell.GetColor(c.RestColor)
+	}
+
+	for i := 1; i <= p.rounds; i++ {
+		caption = fmt.Sprintf("WORK  round %d/%d, %s", i, p.rounds, roundsLeft(p.rounds-i))
+		digitsColor = workColor
+		phaseSound(c.WorkSound, 1)
+		if !countdown(ctx, p.work, false, tag, fmt.Sprintf("work %d/%d", i, p.rounds), logPath) {
+			return false
+		}
+		if i == p.rounds || p.rest == 0 {
+			continue
+		}
+		caption = fmt.Sprintf("REST  %s", roundsLeft(p.rounds-i))
+		digitsColor = restColor
+		phaseSound(c.RestSound, 2)
+		if !countdown(ctx, p.rest, false, tag, fmt.Sprintf("rest %d/%d", i, p.rounds), logPath) {
+			return false
+		}
+	}
+	return true
+}
+
+func roundsLeft(n int) string {
+	if n == 1 {
+		return "1 round left"
+	}
+	return fmt.Sprintf("%d rounds left", n)
+}
+
+// phaseSound plays path without waiting for it, or rings the bell
+// n times, also without waiting, when there is no path or no player.
+func phaseSound(path string, n int) {
+	if path != "" {
+		if cmd := soundCommand(path, volume); cmd != nil && cmd.Start() == nil {
+			go cmd.Wait()
+			return
+		}
+	}
+	go ringBell(n)
+}
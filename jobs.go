@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/antonmedv/countdown/render"
+)
+
+// daemonJob is a timer the daemon starts at a time of day, scheduled with
+// countdown at. A job that comes due while a timer runs starts once that
+// timer is done.
+type daemonJob struct {
+	ID       int           `json:"id"`
+	At       time.Time     `json:"at"`
+	Duration time.Duration `json:"duration"`
+	Tag      string        `json:"tag,omitempty"`
+	Notes    string        `json:"notes,omitempty"`
+}
+
+// jobQueue is what the daemon keeps in jobsPath, so jobs outlive it.
+type jobQueue struct {
+	NextID int         `json:"next_id"`
+	Jobs   []daemonJob `json:"jobs"`
+}
+
+func jobsPath() string {
+	name := "jobs.json"
+	if profile != "" {
+		name = "jobs-" + profile + ".json"
+	}
+	return filepath.Join(filepath.Dir(daemonSocket()), name)
+}
+
+// loadJobs reads the queue, dropping jobs that would have ended while no
+// daemon ran.
+func loadJobs(now time.Time) jobQueue {
+	q := jobQueue{NextID: 1}
+	b, err := os.ReadFile(jobsPath())
+	if err != nil {
+		return q
+	}
+	if err := json.Unmarshal(b, &q); err != nil {
+		stderr("warning: %s: %v\n", jobsPath(), err)
+		return jobQueue{NextID: 1}
+	}
+	jobs := q.Jobs[:0]
+	for _, j := range q.Jobs {
+		if end := j.At.Add(j.Duration); end.After(now) {
+			jobs = append(jobs, j)
+		} else {
+			stderr("dropped job %d at %s, it would have ended by now\n", j.ID, j.At.Format(jobTimeLayout))
+		}
+	}
+	q.Jobs = jobs
+	return q
+}
+
+func (q jobQueue) save() error {
+	b, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(jobsPath(), b, 0600)
+}
+
+// jobTimeLayout is how jobs show their time.
+const jobTimeLayout = "Mon Jan 2 15:04"
+
+// schedule adds a job; the lock must be held.
+func (t *daemonTimer) schedule(req daemonRequest) daemonResponse {
+	if req.Duration <= 0 {
+		return daemonResponse{Error: "duration must be positive"}
+	}
+	if !req.At.After(time.Now()) {
+		return daemonResponse{Error: "the time has passed"}
+	}
+	j := daemonJob{ID: t.jobs.NextID, At: req.At, Duration: req.Duration, Tag: req.Tag, Notes: req.Notes}
+	t.jobs.NextID++
+	t.jobs.Jobs = append(t.jobs.Jobs, j)
+	sort.SliceStable(t.jobs.Jobs, func(a, b int) bool { return t.jobs.Jobs[a].At.Before(t.jobs.Jobs[b].At) })
+	if err := t.jobs.save(); err != nil {
+		return daemonResponse{Error: err.Error()}
+	}
+	t.wake()
+	return daemonResponse{Jobs: []daemonJob{j}}
+}
+
+// cancel removes the job req.ID; the lock must be held.
+func (t *daemonTimer) cancel(req daemonRequest) daemonResponse {
+	for i, j := range t.jobs.Jobs {
+		if j.ID == req.ID {
+			t.jobs.Jobs = append(t.jobs.Jobs[:i], t.jobs.Jobs[i+1:]...)
+			if err := t.jobs.save(); err != nil {
+				return daemonResponse{Error: err.Error()}
+			}
+			t.wake()
+			return daemonResponse{Jobs: []daemonJob{j}}
+		}
+	}
+	return daemonResponse{Error: fmt.Sprintf("no job %d", req.ID)}
+}
+
+// wake starts the first job if it is due and no timer runs, and sets an
+// alarm for when the next one is; the lock must be held. The daemon does
+// not wake up otherwise.
+func (t *daemonTimer) wake() {
+	if t.alarm != nil {
+		t.alarm.Stop()
+		t.alarm = nil
+	}
+	if len(t.jobs.Jobs) == 0 {
+		return
+	}
+	j := t.jobs.Jobs[0]
+	if wait := time.Until(j.At); wait > 0 {
+		t.alarm = time.AfterFunc(wait, func() {
+			t.mu.Lock()
+			defer t.unlock()
+			t.wake()
+		})
+		return
+	}
+	if t.state != "idle" {
+		// end calls wake again.
+		return
+	}
+	t.jobs.Jobs = t.jobs.Jobs[1:]
+	if err := t.jobs.save(); err != nil {
+		stderr("error: %v\n", err)
+	}
+	t.start(j.Duration, j.Tag, j.Notes)
+	t.wake()
+}
+
+// jobsCommand lists the daemon's jobs, or cancels one with "cancel <id>".
+func jobsCommand(args []string) {
+	req := daemonRequest{Command: "jobs"}
+	switch {
+	case len(args) == 0:
+	case len(args) == 2 && args[0] == "cancel":
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			stderr("error: invalid job %q\n", args[1])
+			os.Exit(exitUsage)
+		}
+		req = daemonRequest{Command: "cancel", ID: id}
+	default:
+		stderr("usage: countdown jobs [cancel <id>]\n")
+		os.Exit(exitUsage)
+	}
+	resp, err := sendDaemon(req)
+	if err != nil {
+		stderr("error: no daemon running (%v), start one with: countdown daemon\n", err)
+		os.Exit(exitUsage)
+	}
+	if resp.Error != "" {
+		stderr("error: %s\n", resp.Error)
+		os.Exit(exitUsage)
+	}
+	if req.Command == "cancel" {
+		fmt.Printf("cancelled %s\n", formatJob(resp.Jobs[0]))
+		return
+	}
+	if len(resp.Jobs) == 0 {
+		fmt.Println("no jobs")
+	}
+	for _, j := range resp.Jobs {
+		fmt.Println(formatJob(j))
+	}
+}
+
+func formatJob(j daemonJob) string {
+	s := fmt.Sprintf("%d  %s  %s  %s", j.ID, j.At.Format(jobTimeLayout), render.Format(j.Duration), j.Tag)
+	if j.Notes != "" {
+		s += " (" + j.Notes + ")"
+	}
+	return s
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// defaultKeys bind the actions of a countdown to keys, as [keys] in the
+// config does: key names separated by spaces, "none" for no key at all.
+// The actions of a mode of keyModes start with its name; the others are
+// those of a countdown, quit among them, which the modes share.
+//
+//	[keys]
+//	pause = "p"
+//	quit = "q esc"
+//	restart = "none"
+var defaultKeys = map[string]string{
+	"pause":    "space",
+	"quit":     "esc ctrl-c",
+	"restart":  "r",
+	"palette":  ":",
+	"help":     "?",
+	"next":     "n",
+	"lap":      "l enter",
+	"progress": "p",
+	"details":  "i",
+	"add_10s":  "=",
+	"take_10s": "-",
+	"add_1m":   "+",
+	"take_1m":  "_",
+
+	"chess_pause":   "p",
+	"classroom_add": "+",
+	"cube_timer":    "space",
+	"parallel_stop": "x",
+	"parallel_add":  "a",
+	"replay_quit":   "q",
+	"replay_play":   "space",
+}
+
+// keyModes are the modes with actions of their own, and the actions of a
+// countdown each answers to as well. The keys of a mode have to differ
+// from each other and from those, as the keys of a countdown do.
+var keyModes = map[string][]string{
+	"chess":     {"quit"},
+	"classroom": {"pause", "quit", "restart", "palette", "help", "next", "progress", "details"},
+	"cube":      {"quit"},
+	"parallel":  {"pause", "quit"},
+	"replay":    {"quit"},
+}
+
+// keyScope is the mode of action, or "" for an action of a countdown.
+func keyScope(action string) string {
+	for mode := range keyModes {
+		if strings.HasPrefix(action, mode+"_") {
+			return mode
+		}
+	}
+	return ""
+}
+
+// keyOwner is the action that has k where action is pressed: an action
+// of its own mode, or one of a countdown that the mode answers to too.
+func keyOwner(owners map[boundKey]string, k boundKey, action string) (string, bool) {
+	scope := keyScope(action)
+	if other, ok := owners[boundKey{key: k.key, r: k.r, name: scope}]; ok {
+		return other, true
+	}
+	shares := func(mode, action string) bool {
+		for _, shared := range keyModes[mode] {
+			if shared == action {
+				return true
+			}
+		}
+		return false
+	}
+	if scope != "" {
+		other, ok := owners[boundKey{key: k.key, r: k.r}]
+		return other, ok && shares(scope, other)
+	}
+	for mode := range keyModes {
+		if other, ok := owners[boundKey{key: k.key, r: k.r, name: mode}]; ok && shares(mode, action) {
+			return other, true
+		}
+	}
+	return "", false
+}
+
+// adjustActions add time to the running countdown or take it off.
+var adjustActions = map[string]time.Duration{
+	"add_10s":  10 * time.Second,
+	"take_10s": -10 * time.Second,
+	"add_1m":   time.Minute,
+	"take_1m":  -time.Minute,
+}
+
+// keyNames are the keys that are not written as themselves.
+var keyNames = map[string]tcell.Key{
+	"esc":       tcell.KeyEscape,
+	"enter":     tcell.KeyEnter,
+	"tab":       tcell.KeyTab,
+	"backspace": tcell.KeyBackspace2,
+	"delete":    tcell.KeyDelete,
+	"up":        tcell.KeyUp,
+	"down":      tcell.KeyDown,
+	"left":      tcell.KeyLeft,
+	"right":     tcell.KeyRight,
+}
+
+// boundKey is a key an action is bound to: a rune, or a special key.
+type boundKey struct {
+	key  tcell.Key
+	r    rune
+	name string
+}
+
+// keys are the bindings in effect, by action.
+var keys map[string][]boundKey
+
+func init() {
+	keys, _ = parseKeys(nil, nil)
+}
+
+// parseKeys binds the actions to the keys of custom, and the others to
+// their defaults. A key may only do one thing; a default key that custom
+// or a macro uses is left to them, so a new default never breaks a config.
+func parseKeys(custom map[string]string, macros map[string]Macro) (map[string][]boundKey, error) {
+	bindings := make(map[string][]boundKey, len(defaultKeys))
+	owners := make(map[boundKey]string)
+	actions := make([]string, 0, len(defaultKeys))
+	for action := range defaultKeys {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for action := range custom {
+		if _, ok := defaultKeys[action]; !ok {
+			return nil, fmt.Errorf("keys: unknown action %q, expected one of %s", action, strings.Join(actions, ", "))
+		}
+	}
+	// The keys of custom first, so the defaults know which are taken.
+	for _, defaults := range []bool{false, true} {
+		for _, action := range actions {
+			names, customized := custom[action]
+			if customized == defaults {
+				continue
+			}
+			if defaults {
+				names = defaultKeys[action]
+			}
+			if strings.TrimSpace(names) == "none" {
+				continue
+			}
+			for _, name := range strings.Fields(names) {
+				k, err := parseKey(name)
+				if err != nil {
+					return nil, fmt.Errorf("keys: %s: %v", action, err)
+				}
+				// Macros run in a countdown only.
+				scope := keyScope(action)
+				other, taken := keyOwner(owners, k, action)
+				if defaults && (taken || scope == "" && k.key == tcell.KeyRune && macroForRune(macros, k.r)) {
+					continue
+				}
+				if taken {
+					return nil, fmt.Errorf("keys: %s and %s share the key %s", other, action, name)
+				}
+				owners[boundKey{key: k.key, r: k.r, name: scope}] = action
+				bindings[action] = append(bindings[action], k)
+			}
+		}
+	}
+	return bindings, nil
+}
+
+// parseKey reads a key name: a single character, space, a name of
+// keyNames, or ctrl- and a letter.
+func parseKey(name string) (boundKey, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case len([]rune(name)) == 1:
+		return boundKey{key: tcell.KeyRune, r: []rune(name)[0], name: name}, nil
+	case lower == "space":
+		return boundKey{key: tcell.KeyRune, r: ' ', name: lower}, nil
+	case strings.HasPrefix(lower, "ctrl-") && len(lower) == len("ctrl-")+1 && lower[5] >= 'a' && lower[5] <= 'z':
+		return boundKey{key: tcell.KeyCtrlA + tcell.Key(lower[5]-'a'), name: lower}, nil
+	}
+	if k, ok := keyNames[lower]; ok {
+		return boundKey{key: k, name: lower}, nil
+	}
+	return boundKey{}, fmt.Errorf("unknown key %q", name)
+}
+
+// pressed tells whether ev is one of the keys of action.
+func pressed(action string, ev *tcell.EventKey) bool {
+	for _, k := range keys[action] {
+		if k.key == tcell.KeyRune {
+			if ev.Key() == tcell.KeyRune && ev.Rune() == k.r {
+				return true
+			}
+		} else if ev.Key() == k.key {
+			return true
+		}
+	}
+	return false
+}
+
+// keyAction is the action of a countdown bound to the rune r, if any.
+func keyAction(r rune) (string, bool) {
+	for action, bound := range keys {
+		if keyScope(action) != "" {
+			continue
+		}
+		for _, k := range bound {
+			if k.key == tcell.KeyRune && k.r == r {
+				return action, true
+			}
+		}
+	}
+	return "", false
+}
+
+// keyLabel names the keys of action for the help, "" when it has none.
+func keyLabel(action string) string {
+	names := make([]string, len(keys[action]))
+	for i, k := range keys[action] {
+		names[i] = k.name
+	}
+	return strings.Join(names, " ")
+}
+
+// macroForRune tells whether one of macros is bound to r.
+func macroForRune(macros map[string]Macro, r rune) bool {
+	for _, m := range macros {
+		if m.Key == string(r) {
+			return true
+		}
+	}
+	return false
+}
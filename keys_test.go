@@ -0,0 +1,128 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseKeysMacroTakesDefault(t *testing.T) {
+	bindings, err := parseKeys(nil, map[string]Macro{"rest": {Key: "p"}, "info": {Key: "i"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bindings["progress"]) != 0 || len(bindings["details"]) != 0 {
+		t.Errorf("progress %v and details %v kept the keys of the macros", bindings["progress"], bindings["details"])
+	}
+	if len(bindings["pause"]) != 1 || bindings["pause"][0].r != ' ' {
+		t.Errorf("pause = %v, want space", bindings["pause"])
+	}
+
+	// A key set in [keys] is not given up.
+	bindings, err = parseKeys(map[string]string{"progress": "p"}, map[string]Macro{"rest": {Key: "p"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bindings["progress"]) != 1 || bindings["progress"][0].key != tcell.KeyRune {
+		t.Errorf("progress = %v, want p", bindings["progress"])
+	}
+}
+
+func TestParseKeysErrors(t *testing.T) {
+	for _, custom := range []map[string]string{
+		{"jump": "j"},
+		{"pause": "r", "restart": "r"},
+		{"pause": "ctrl-"},
+	} {
+		if _, err := parseKeys(custom, nil); err == nil {
+			t.Errorf("parseKeys(%v): want an error", custom)
+		}
+	}
+}
+
+func TestParseKeysCustomTakesDefault(t *testing.T) {
+	// The example of the README: p is the default of progress.
+	bindings, err := parseKeys(map[string]string{"pause": "p", "quit": "q esc", "restart": "none"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bindings["pause"]) != 1 || bindings["pause"][0].r != 'p' {
+		t.Errorf("pause = %v, want p", bindings["pause"])
+	}
+	if len(bindings["progress"]) != 0 || len(bindings["restart"]) != 0 {
+		t.Errorf("progress = %v and restart = %v, want none", bindings["progress"], bindings["restart"])
+	}
+}
+
+func TestParseKeysScopes(t *testing.T) {
+	// p is both progress and chess_pause, space both pause and cube_timer.
+	bindings, err := parseKeys(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bindings["chess_pause"]) != 1 || len(bindings["progress"]) != 1 || len(bindings["cube_timer"]) != 1 {
+		t.Errorf("chess_pause = %v, progress = %v, cube_timer = %v", bindings["chess_pause"], bindings["progress"], bindings["cube_timer"])
+	}
+	if _, err := parseKeys(map[string]string{"parallel_add": "x", "parallel_stop": "x"}, nil); err == nil {
+		t.Errorf("parallel_add and parallel_stop share x, want an error")
+	}
+	if _, err := parseKeys(map[string]string{"replay_play": "?"}, nil); err != nil {
+		t.Errorf("replay_play on the key of help: %v", err)
+	}
+	if _, err := parseKeys(map[string]string{"chess_pause": "+"}, nil); err != nil {
+		t.Errorf("chess_pause on the key of add_1m: %v", err)
+	}
+}
+
+func TestParseKeysShadowed(t *testing.T) {
+	// The modes answer to some actions of a countdown too, which would
+	// win the key.
+	for _, custom := range []map[string]string{
+		{"parallel_stop": "space", "pause": "space"},
+		{"pause": "x", "parallel_stop": "x"},
+		{"quit": "q", "replay_quit": "q"},
+		{"cube_timer": "esc", "quit": "esc"},
+		{"classroom_add": "r", "restart": "r"},
+	} {
+		if _, err := parseKeys(custom, nil); err == nil {
+			t.Errorf("parseKeys(%v): want an error", custom)
+		}
+	}
+
+	// A default key goes to the action set to it.
+	bindings, err := parseKeys(map[string]string{"pause": "x"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bindings["parallel_stop"]) != 0 || len(bindings["pause"]) != 1 {
+		t.Errorf("pause = %v, parallel_stop = %v, want x and none", bindings["pause"], bindings["parallel_stop"])
+	}
+	bindings, err = parseKeys(map[string]string{"cube_timer": "esc"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := bindings["quit"]; len(keys) != 1 || keys[0].key != tcell.KeyCtrlC {
+		t.Errorf("quit = %v, want ctrl-c", keys)
+	}
+}
+
+func TestMeditationHint(t *testing.T) {
+	defer func(saved map[string][]boundKey) { keys = saved }(keys)
+	tests := []struct {
+		quit string
+		want string
+	}{
+		{"esc ctrl-c", "hold esc to end"},
+		{"q esc", "hold q to end"},
+		{"none", ""},
+	}
+	for _, tt := range tests {
+		var err error
+		if keys, err = parseKeys(map[string]string{"quit": tt.quit}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := meditationHint(); got != tt.want {
+			t.Errorf("quit = %q: hint %q, want %q", tt.quit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/antonmedv/countdown/parse"
+	"github.com/antonmedv/countdown/timer"
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	// kioskSync repaints the whole screen, so a terminal that was reset or
+	// reconnected recovers without anyone touching it.
+	kioskSync         = time.Minute
+	kioskRestartDelay = 5 * time.Second
+	kioskRetryDelay   = time.Second
+)
+
+// KioskConfig is the [kiosk] section of the config file. Without a
+// schedule the countdown from the command line restarts forever.
+//
+//	[kiosk]
+//	passphrase = "open sesame"
+//	schedule = [
+//	  {at = "09:00", duration = "3h", tag = "Morning"},
+//	  {at = "13:00", duration = "4h"},
+//	]
+type KioskConfig struct {
+	Passphrase string       `toml:"passphrase,omitempty"`
+	Schedule   []KioskEntry `toml:"schedule,omitempty"`
+}
+
+type KioskEntry struct {
+	At       string `toml:"at"`
+	Duration string `toml:"duration"`
+	Tag      string `toml:"tag,omitempty"`
+}
+
+// kioskSlot is a schedule entry as minutes into the day.
+type kioskSlot struct {
+	hour, minute int
+	duration     time.Duration
+	tag          string
+}
+
+func parseKiosk(k KioskConfig) ([]kioskSlot, error) {
+	slots := make([]kioskSlot, len(k.Schedule))
+	for i, e := range k.Schedule {
+		h, m, err := parse.Clock(e.At)
+		if err != nil {
+			return nil, fmt.Errorf("kiosk schedule: %v", err)
+		}
+		d, err := parse.Literal(e.Duration)
+		if err != nil || d <= 0 || d > 24*time.Hour {
+			return nil, fmt.Errorf("kiosk schedule: invalid duration %q", e.Duration)
+		}
+		slots[i] = kioskSlot{hour: h, minute: m, duration: d, tag: e.Tag}
+	}
+	return slots, nil
+}
+
+// nextSlot returns the slot running at now, or else the one starting next,
+// with its start time.
+func nextSlot(slots []kioskSlot, now time.Time) (kioskSlot, time.Time) {
+	var next kioskSlot
+	var nextStart time.Time
+	for _, s := range slots {
+		// A slot started yesterday may still be running.
+		for _, day := range []int{-1, 0, 1} {
+			start := time.Date(now.Year(), now.Month(), now.Day()+day, s.hour, s.minute, 0, 0, now.Location())
+			if now.Before(start.Add(s.duration)) && (nextStart.IsZero() || start.Before(nextStart)) {
+				next, nextStart = s, start
+				break
+			}
+		}
+	}
+	return next, nextStart
+}
+
+// kiosk keeps countdowns on screen until the passphrase is typed followed
+// by Enter, or ctx is done. Esc and Ctrl-C do nothing, and hanging up the
+// terminal does not stop it.
+func kiosk(ctx context.Context, slots []kioskSlot, passphrase string, d time.Duration, tag string, logPath string) bool {
+	signal.Ignore(syscall.SIGHUP)
+	repaint := time.NewTicker(kioskSync)
+	defer repaint.Stop()
+
+	var typed []rune
+	var timeLeft, total time.Duration
+	var t *timer.Timer
+	var events <-chan timer.Event
+	var done <-chan struct{}
+	defer func() {
+		if t != nil {
+			t.Stop()
+		}
+	}()
+	var wait <-chan time.Time
+	var idleUntil time.Time
+	running := false
+	sessionTag := tag
+	w, h = screen.Size()
+
+	redraw := func() {
+		if !running && slots != nil {
+			drawKioskIdle(idleUntil, w, h)
+		} else {
+			draw(timeLeft, total, false, w, h)
+		}
+	}
+
+	// schedule starts the countdown due now, or waits for the next one.
+	schedule := func() {
+		if slots == nil {
+			timeLeft, total = d, d
+		} else {
+			slot, at := nextSlot(slots, time.Now())
+			if until := time.Until(at); until > 0 {
+				wait, idleUntil = time.After(until), at
+				redraw()
+				return
+			}
+			total = slot.duration
+			timeLeft = time.Until(at.Add(slot.duration)).Round(time.Second)
+			sessionTag = tag
+			if slot.tag != "" {
+				sessionTag = slot.tag
+			}
+		}
+		running = true
+		t = timer.New(timeLeft, tick)
+		events, done = t.Subscribe(), t.Done()
+		t.Start()
+		bus.Publish(Event{Kind: SessionStarted, Tag: sessionTag, LogPath: logPath})
+		redraw()
+	}
+	schedule()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if running {
+				bus.Publish(Event{Kind: SessionEnded, Tag: sessionTag, LogPath: logPath})
+			}
+			return true
+		case ev := <-queues:
+			switch ev := ev.(type) {
+			case *tcell.EventError:
+				// The terminal went away; keep trying until it is back.
+				time.Sleep(kioskRetryDelay)
+				screen.Sync()
+			case *tcell.EventResize:
+				w, h = screen.Size()
+				screen.Sync()
+			case *tcell.EventKey:
+				if ev.Key() == tcell.KeyEnter {
+					if passphrase != "" && string(typed) == passphrase {
+						if running {
+							bus.Publish(Event{Kind: SessionEnded, Tag: sessionTag, LogPath: logPath})
+						}
+						return true
+					}
+					typed = typed[:0]
+					break
+				}
+				if ev.Key() != tcell.KeyRune {
+					continue
+				}
+				if len(typed) > 2*len(passphrase) {
+					typed = typed[:0]
+				}
+				typed = append(typed, ev.Rune())
+				continue
+			default:
+				continue
+			}
+		case <-repaint.C:
+			screen.Sync()
+		case <-wait:
+			wait = nil
+			schedule()
+			continue
+		case ev := <-events:
+			timeLeft = ev.Left
+		case <-done:
+			events, done = nil, nil
+			running = false
+			bus.Publish(Event{Kind: SessionEnded, Tag: sessionTag, LogPath: logPath, Completed: true})
+			ringBell(1)
+			if slots != nil {
+				schedule()
+				continue
+			}
+			timeLeft = 0
+			wait = time.After(kioskRestartDelay)
+		}
+		redraw()
+	}
+}
+
+func drawKioskIdle(at time.Time, w, h int) {
+	clear()
+	str := "next at " + at.Format("Mon 15:04")
+	echoString(str, w/2-utf8.RuneCountInString(str)/2, h/2, tcell.StyleDefault.Dim(true))
+	flush()
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const lightTimeout = 3 * time.Second
+
+// Light is a smart bulb used to signal focus: red while the countdown runs,
+// off while paused and flashing once it completes.
+type Light interface {
+	focus(ctx context.Context) error
+	off(ctx context.Context) error
+	flash(ctx context.Context) error
+}
+
+func newLight(c LightConfig) (Light, error) {
+	switch c.Provider {
+	case "":
+		return nil, nil
+	case "hue":
+		if c.Bridge == "" || c.Username == "" {
+			return nil, fmt.Errorf("hue light requires bridge and username")
+		}
+		return &hueLight{c}, nil
+	case "lifx":
+		if c.Token == "" {
+			c.Token = lookupToken("lifx")
+		}
+		if c.Token == "" {
+			return nil, fmt.Errorf("lifx light requires token, or countdown auth add lifx")
+		}
+		if c.Selector == "" {
+			c.Selector = "all"
+		}
+		return &lifxLight{c}, nil
+	}
+	return nil, fmt.Errorf("unknown light provider %q", c.Provider)
+}
+
+func sendJSON(ctx context.Context, method, url, token string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return sendBody(ctx, method, url, token, "", b)
+}
+
+// sendBody sends a JSON body, with the Idempotency-Key header when key is
+// set; a response other than a success is a *statusError.
+func sendBody(ctx context.Context, method, url, token, key string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return &statusError{method: method, url: url, status: resp.Status, code: resp.StatusCode}
+	}
+	return nil
+}
+
+type hueLight struct {
+	config LightConfig
+}
+
+func (l *hueLight) setState(ctx context.Context, state map[string]interface{}) error {
+	for _, id := range l.config.Lights {
+		url := fmt.Sprintf("http://%s/api/%s/lights/%s/state", l.config.Bridge, l.config.Username, id)
+		if err := sendJSON(ctx, http.MethodPut, url, "", state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *hueLight) focus(ctx context.Context) error {
+	return l.setState(ctx, map[string]interface{}{"on": true, "hue": 0, "sat": 254, "bri": 254})
+}
+
+func (l *hueLight) off(ctx context.Context) error {
+	return l.setState(ctx, map[string]interface{}{"on": false})
+}
+
+func (l *hueLight) flash(ctx context.Context) error {
+	return l.setState(ctx, map[string]interface{}{"on": true, "alert": "lselect"})
+}
+
+type lifxLight struct {
+	config LightConfig
+}
+
+func (l *lifxLight) url(path string) string {
+	return "https://api.lifx.com/v1/lights/" + l.config.Selector + path
+}
+
+func (l *lifxLight) focus(ctx context.Context) error {
+	return sendJSON(ctx, http.MethodPut, l.url("/state"), l.config.Token, map[string]interface{}{"power": "on", "color": "red", "brightness": 1.0})
+}
+
+func (l *lifxLight) off(ctx context.Context) error {
+	return sendJSON(ctx, http.MethodPut, l.url("/state"), l.config.Token, map[string]interface{}{"power": "off"})
+}
+
+func (l *lifxLight) flash(ctx context.Context) error {
+	return sendJSON(ctx, http.MethodPost, l.url("/effects/pulse"), l.config.Token, map[string]interface{}{"color": "red", "cycles": 5, "period": 1.0, "power_on": true})
+}
+
+// lightEvents shows the session on the light: on while it runs, off
+// while paused and flashing once it completed.
+func lightEvents(e Event) {
+	switch e.Kind {
+	case SessionStarted, SessionResumed:
+		notifyLight(Light.focus)
+	case SessionPaused:
+		notifyLight(Light.off)
+	case SessionEnded:
+		if e.Completed && !e.Quiet {
+			notifyLightSync(Light.flash)
+		} else {
+			notifyLightSync(Light.off)
+		}
+	}
+}
+
+// notifyLight runs a light action in the background so a slow bridge never
+// stalls the countdown.
+func notifyLight(action func(Light, context.Context) error) {
+	if light == nil {
+		return
+	}
+	go func() {
+		defer restoreOnPanic()
+		notifyLightSync(action)
+	}()
+}
+
+// notifyLightSync is like notifyLight but waits for the bridge, for use right
+// before the process exits. Either gives up after lightTimeout.
+func notifyLightSync(action func(Light, context.Context) error) {
+	if light == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), lightTimeout)
+	defer cancel()
+	// A light state is stale by the time the network is back, so it is
+	// retried but never queued like the webhook.
+	_ = retry(ctx, func(ctx context.Context) error { return action(light, ctx) })
+}
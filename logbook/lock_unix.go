@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package logbook
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lock holds f for this process alone until unlock, waiting for other
+// processes that hold it.
+func lock(f *os.File) error {
+	return unix.Flock(int(f.Fd()), unix.LOCK_EX)
+}
+
+func unlock(f *os.File) error {
+	return unix.Flock(int(f.Fd()), unix.LOCK_UN)
+}
@@ -0,0 +1,297 @@
+// Package logbook reads and writes countdown's log, one event per line,
+// either as text or as JSON Lines:
+//
+//	i 2024-01-01 09:00:00 tag  notes
+//	{"state":"i","time":"2024-01-01T09:00:00+01:00","tag":"tag","notes":"notes","session":"20240101-090000"}
+//
+// Read takes both, even mixed in one log.
+//
+// An audited log chains its events by hash, each over the one before, so
+// that an edit to an event breaks the chain from there on; see Chain and
+// Verify. Only JSON Lines keeps the hashes.
+package logbook
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The states of an event.
+const (
+	Start  = "i"
+	End    = "o"
+	Pause  = "p"
+	Resume = "u"
+	// Lap is a lap of the stopwatch; Sessions skips it.
+	Lap = "l"
+)
+
+// ErrUnavailable is wrapped by the errors of the functions that write to
+// the log, when it can't be opened or written.
+var ErrUnavailable = errors.New("log unavailable")
+
+// TimeLayout is how event times are written, in local time.
+const TimeLayout = "2006-01-02 15:04:05"
+
+// Event is one line of the log. Session is only kept in JSON Lines, and
+// is the same for all events of a session. Hash is only set in an
+// audited log.
+type Event struct {
+	State   string    `json:"state"`
+	Time    time.Time `json:"time"`
+	Tag     string    `json:"tag"`
+	Notes   string    `json:"notes,omitempty"`
+	Session string    `json:"session,omitempty"`
+	Hash    string    `json:"hash,omitempty"`
+}
+
+// Format is how events are written.
+type Format string
+
+const (
+	Text  Format = "text"
+	JSONL Format = "jsonl"
+)
+
+// ParseFormat checks the name of a format.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case Text, JSONL:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown log format %q, expected text or jsonl", name)
+}
+
+func (e Event) String() string {
+	return e.State + " " + e.Time.Format(TimeLayout) + " " + e.Tag + "  " + e.Notes
+}
+
+// Append adds e to the log at path as text, creating the log if needed.
+func Append(path string, e Event) error {
+	return Text.Append(path, e)
+}
+
+// Append adds e to the log at path in format f, creating the log if
+// needed.
+func (f Format) Append(path string, e Event) error {
+	line := e.String()
+	if f == JSONL {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		line = string(b)
+	}
+	return appendLine(path, line)
+}
+
+// heads caches the hash of the last event of the audited logs written
+// so far, by path, along with the size of the log it was read at.
+var heads = struct {
+	sync.Mutex
+	logs map[string]chainHead
+}{logs: make(map[string]chainHead)}
+
+type chainHead struct {
+	size int64
+	hash string
+}
+
+// AppendChained adds e to the log at path in JSON Lines, chained to the
+// last event of the log by its hash. The log is locked while it is
+// appended to, so that the events of other processes, such as the daemon,
+// do not fork the chain.
+func AppendChained(path string, e Event) error {
+	heads.Lock()
+	defer heads.Unlock()
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer f.Close()
+	if err := lock(f); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer unlock(f)
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	// Another process appended to the log since, or it was never read.
+	head, ok := heads.logs[path]
+	if !ok || head.size != info.Size() {
+		line, err := lastLine(f, info.Size())
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		head = chainHead{size: info.Size()}
+		if line != "" {
+			last, err := Parse(line)
+			if err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrUnavailable, path, err)
+			}
+			head.hash = last.Hash
+		}
+	}
+
+	e.Hash = Chain(head.hash, e)
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	n, err := f.Write(append(b, '\n'))
+	if err != nil {
+		delete(heads.logs, path)
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	heads.logs[path] = chainHead{size: head.size + int64(n), hash: e.Hash}
+	return nil
+}
+
+// lastLine reads the last line of the size bytes of f that is not empty,
+// from the end, without reading the rest.
+func lastLine(f *os.File, size int64) (string, error) {
+	const chunk = 4096
+	var tail []byte
+	for end := size; end > 0; {
+		start := end - chunk
+		if start < 0 {
+			start = 0
+		}
+		buf := make([]byte, end-start)
+		if _, err := f.ReadAt(buf, start); err != nil {
+			return "", err
+		}
+		tail = append(buf, tail...)
+		if trimmed := strings.TrimRight(string(tail), "\r\n"); strings.Contains(trimmed, "\n") || start == 0 {
+			return trimmed[strings.LastIndex(trimmed, "\n")+1:], nil
+		}
+		end = start
+	}
+	return "", nil
+}
+
+// Chain is the hash of e after the event with the hash prev, or after
+// none for "". Times are hashed in UTC, so that the chain holds in any
+// time zone.
+func Chain(prev string, e Event) string {
+	b, _ := json.Marshal([]string{prev, e.State, e.Time.UTC().Format(time.RFC3339Nano), e.Tag, e.Notes, e.Session})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks the chain of an audited log. The events before the first
+// one with a hash predate auditing and are not covered; from there on,
+// every event must have the hash of Chain. It returns the number of
+// events covered and the hash of the last one, which vouches for the
+// whole chain when kept elsewhere.
+func Verify(events []Event) (covered int, head string, err error) {
+	start := -1
+	for i, e := range events {
+		if e.Hash != "" {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return 0, "", nil
+	}
+	for i, e := range events[start:] {
+		if e.Hash == "" {
+			return i, head, fmt.Errorf("event %d (%s) has no hash", start+i+1, e)
+		}
+		if want := Chain(head, e); e.Hash != want {
+			return i, head, fmt.Errorf("event %d (%s) does not match its hash, it or one before it was changed", start+i+1, e)
+		}
+		head = e.Hash
+	}
+	return len(events) - start, head, nil
+}
+
+// Create creates the log at path if it does not exist yet, so that a
+// log that can't be written fails before the first event.
+func Create(path string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	f.Close()
+	return nil
+}
+
+func appendLine(path, line string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		f.Close()
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// Read parses the log at path. Times are read in local time, as they are
+// written.
+func Read(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		e, err := Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// Parse reads one line of the log, in either format.
+func Parse(line string) (Event, error) {
+	if strings.HasPrefix(line, "{") {
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return Event{}, err
+		}
+		// Text times are local, and so are these.
+		e.Time = e.Time.Local()
+		return e, nil
+	}
+	if len(line) < 2+len(TimeLayout) || line[1] != ' ' {
+		return Event{}, fmt.Errorf("invalid event %q", line)
+	}
+	t, err := time.ParseInLocation(TimeLayout, line[2:2+len(TimeLayout)], time.Local)
+	if err != nil {
+		return Event{}, err
+	}
+	e := Event{State: line[:1], Time: t}
+	rest := strings.TrimPrefix(line[2+len(TimeLayout):], " ")
+	if i := strings.Index(rest, "  "); i >= 0 {
+		e.Tag, e.Notes = rest[:i], rest[i+2:]
+	} else {
+		e.Tag = rest
+	}
+	return e, nil
+}
@@ -0,0 +1,82 @@
+package logbook
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAppendChainedConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	start := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				e := Event{State: Start, Time: start.Add(time.Duration(i*20+j) * time.Second), Tag: "tag"}
+				if err := AppendChained(path, e); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	events, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	covered, _, err := Verify(events)
+	if err != nil || covered != 160 {
+		t.Errorf("Verify = %d, %v, want 160 events covered", covered, err)
+	}
+}
+
+func TestAppendChainedAfterOtherWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	at := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	if err := AppendChained(path, Event{State: Start, Time: at, Tag: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	// Another process chains an event of its own, a long one that spans
+	// more than one read from the end.
+	events, _ := Read(path)
+	other := Event{State: End, Time: at.Add(time.Minute), Tag: "a", Notes: strings.Repeat("x", 10000)}
+	other.Hash = Chain(events[0].Hash, other)
+	if err := JSONL.Append(path, other); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendChained(path, Event{State: Start, Time: at.Add(2 * time.Minute), Tag: "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if covered, _, err := Verify(events); err != nil || covered != 3 {
+		t.Errorf("Verify = %d, %v, want 3 events covered", covered, err)
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	at := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	for i, state := range []string{Start, Pause, Resume, End} {
+		if err := AppendChained(path, Event{State: state, Time: at.Add(time.Duration(i) * time.Minute), Tag: "a"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events[1].Tag = "b"
+	if covered, _, err := Verify(events); err == nil || covered != 1 {
+		t.Errorf("Verify = %d, %v, want an error after 1 event", covered, err)
+	}
+}
@@ -0,0 +1,76 @@
+package logbook
+
+import "time"
+
+// Session is a start and its end in the log, with the notes given at the
+// start; the end's notes are the outcome.
+type Session struct {
+	Tag     string
+	Notes   string
+	Outcome string
+	Start   time.Time
+	End     time.Time
+	// Paused is the time spent paused between Start and End.
+	Paused time.Duration
+}
+
+// Active is how long the session ran, pauses excluded.
+func (s Session) Active() time.Duration {
+	return s.End.Sub(s.Start) - s.Paused
+}
+
+// Sessions pairs the events into sessions, oldest end first. A session
+// that has not ended yet is left out. Sessions that overlap, as with
+// countdown -parallel, are told apart by their tags; an event with a tag
+// no open session has (the tag was changed during the session) goes to the
+// latest one.
+func Sessions(events []Event) []Session {
+	type open struct {
+		Session
+		pausedAt time.Time
+	}
+	var sessions []Session
+	var opened []*open
+	find := func(tag string) int {
+		for i := len(opened) - 1; i >= 0; i-- {
+			if opened[i].Tag == tag {
+				return i
+			}
+		}
+		return len(opened) - 1
+	}
+	for _, e := range events {
+		if e.State == Start {
+			if i := find(e.Tag); i >= 0 && opened[i].Tag == e.Tag {
+				// The session before never ended.
+				opened = append(opened[:i], opened[i+1:]...)
+			}
+			opened = append(opened, &open{Session: Session{Tag: e.Tag, Notes: e.Notes, Start: e.Time}})
+			continue
+		}
+		i := find(e.Tag)
+		if i < 0 {
+			continue
+		}
+		current := opened[i]
+		switch e.State {
+		case Pause:
+			if current.pausedAt.IsZero() {
+				current.pausedAt = e.Time
+			}
+		case Resume:
+			if !current.pausedAt.IsZero() {
+				current.Paused += e.Time.Sub(current.pausedAt)
+				current.pausedAt = time.Time{}
+			}
+		case End:
+			if !current.pausedAt.IsZero() {
+				current.Paused += e.Time.Sub(current.pausedAt)
+			}
+			current.End, current.Outcome = e.Time, e.Notes
+			sessions = append(sessions, current.Session)
+			opened = append(opened[:i], opened[i+1:]...)
+		}
+	}
+	return sessions
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Macro is a named list of palette commands, run with ":macro <name>" or
+// its key:
+//
+//	[macros.rest]
+//	key = "b"
+//	commands = ["note done", "start 5m rest"]
+type Macro struct {
+	Key      string   `toml:"key,omitempty"`
+	Commands []string `toml:"commands"`
+}
+
+// reservedKeys are the presets of -classroom, so macros cannot take them,
+// nor the keys of the actions.
+const reservedKeys = "0123456789"
+
+var macroName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// macros are the config's macros, loaded at startup.
+var macros map[string]Macro
+
+// recording is the macro being recorded with ":record", kept across
+// sessions so a macro may start the next one.
+var recording *macroRecording
+
+type macroRecording struct {
+	name     string
+	commands []string
+}
+
+// plannedSession is a countdown started from the palette with ":start",
+// which main runs once the current one has ended.
+type plannedSession struct {
+	duration time.Duration
+	tag      string
+}
+
+var (
+	// canPlan is set for a plain countdown, the only one that can run
+	// another afterwards.
+	canPlan     bool
+	nextSession *plannedSession
+)
+
+func validateMacros(m map[string]Macro) error {
+	keys := make(map[string]string)
+	for name, macro := range m {
+		if !macroName.MatchString(name) {
+			return fmt.Errorf("macro %q: names are letters, digits, _ and -", name)
+		}
+		if err := validMacroKey(macro.Key); err != nil {
+			return fmt.Errorf("macro %s: %v", name, err)
+		}
+		if other, ok := keys[macro.Key]; ok && macro.Key != "" {
+			return fmt.Errorf("macros %s and %s share the key %q", other, name, macro.Key)
+		}
+		keys[macro.Key] = name
+	}
+	return nil
+}
+
+func validMacroKey(key string) error {
+	if key == "" {
+		return nil
+	}
+	if len([]rune(key)) != 1 {
+		return fmt.Errorf("key %q is not a single character", key)
+	}
+	if strings.Contains(reservedKeys, key) {
+		return fmt.Errorf("key %q is taken by countdown", key)
+	}
+	if action, ok := keyAction([]rune(key)[0]); ok {
+		return fmt.Errorf("key %q is taken by %s, see [keys]", key, action)
+	}
+	return nil
+}
+
+// macroForKey finds the macro bound to r.
+func macroForKey(r rune) (string, bool) {
+	for name, m := range macros {
+		if m.Key == string(r) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// saveMacro appends the macro to the config file rather than rewriting
+// it, so the comments in it stay. A macro that exists is left alone.
+func saveMacro(path, name string, m Macro) error {
+	if err := validMacroKey(m.Key); err != nil {
+		return err
+	}
+	if _, ok := macros[name]; ok {
+		return fmt.Errorf("macro %s exists, edit the config to change it", name)
+	}
+	if m.Key != "" {
+		if other, ok := macroForKey([]rune(m.Key)[0]); ok {
+			return fmt.Errorf("key %q is taken by macro %s", m.Key, other)
+		}
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "\n[macros.%s]\n", name)
+	if err := toml.NewEncoder(&b).Encode(m); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(b.Bytes()); err != nil {
+		return err
+	}
+	if macros == nil {
+		macros = make(map[string]Macro)
+	}
+	macros[name] = m
+	return nil
+}
@@ -1,99 +1,1075 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"runtime"
 	"strings"
+	"syscall"
+	"text/template"
 	"time"
+	"unicode/utf8"
 
-	"github.com/nsf/termbox-go"
+	"github.com/antonmedv/countdown/logbook"
+	"github.com/antonmedv/countdown/parse"
+	"github.com/antonmedv/countdown/render"
+	"github.com/antonmedv/countdown/timer"
+	"github.com/gdamore/tcell/v2"
 )
 
 const (
 	usage = `
  countdown [-up] [-t] [-n] <duration>
+ countdown -up [-t] [-n]
+ countdown preset <name> | @<name>
+ countdown routine <name>
+ countdown cook <bundle>
+ countdown rounds <name>
+ countdown stopwatch
+ countdown run [-timeout <duration>] -- <command>
+ countdown again [query]
+ countdown replay [<id> | last]
+ countdown timeline [<id>...]
+ countdown report [today | week | month]...
+ countdown export -csv | -ics [-from <date>] [-to <date>]
+ countdown verify
+ countdown daemon [-share <address>] | start <duration> | pause | resume | status | stop | share
+ countdown at <time> [-t] [-n] <duration>
+ countdown jobs [cancel <id>]
+ countdown tag set <tag> key=value... | get <tag> [key] | list
+ countdown xbar [-install <plugin folder>]
+ countdown version | self-update
+ countdown auth add | get | remove <service>
+ countdown sync
+ countdown server [-addr <address>] [-data <dir>]
+ countdown <plugin> [args]
 
  Usage
   countdown 25s
   countdown 14:15
   countdown 02:15PM
+  countdown 1:30:00
+  countdown -d 12:30
+  countdown -until-next 30m
+  countdown -remind 1h laundry
+  countdown -pomodoro -t Work
+  countdown -rate 4.50/h
+  countdown -exam -announce 30m,15m,5m 2h
+  countdown -meditate 20m
+  countdown -cube
+  countdown -rotate alice,bob,carol 7m
+  countdown -classroom
+  countdown -kiosk 1h
+  countdown -parallel tea=3m eggs=7m
+  countdown -chain -wait 10m 2m 10m
+  countdown -interval work=40s rest=20s rounds=8
+  countdown -chess -fischer 2s 5m
+  countdown in 90 minutes
+  countdown until friday 9am
   countdown -t Tag -n "Notes for the activity" 10m
 
+ Every flag can also be set with a COUNTDOWN_<FLAG> environment variable,
+ e.g. COUNTDOWN_UP=true, COUNTDOWN_TAG for -t and COUNTDOWN_NOTES for -n.
+
  Flags
 `
-	tick         = time.Second
 	inputDelayMS = 500 * time.Millisecond
+	// confirmQuitWindow is how soon the second Esc of -confirm-quit must
+	// follow the first.
+	confirmQuitWindow = 2 * time.Second
+	confirmQuitPrompt = "press Esc again within 2s to quit"
+	lowPowerTick      = 10 * time.Second
+	resizeDelay       = 50 * time.Millisecond
 )
 
 var (
-	timer          *time.Timer
-	ticker         *time.Ticker
-	queues         chan termbox.Event
+	tick           = time.Second
+	screen         tcell.Screen
+	queues         <-chan tcell.Event
+	stopEvents     func()
 	w, h           int
 	inputStartTime time.Time
-	isPaused       bool
 	tag            string
 	notes          string
-	logPath       string
+	logPath        string
+	light          Light
+	gradient       bool
+	banner         render.Symbol
+	confetti       bool
+	fill           bool
+	isBreak        bool
+	dimBreaks      bool
+	renderer       Renderer = cellRenderer{}
+	caption        string
+	stepControls   bool
+	ring           bool
+	bellOnly       bool
+	bells          int
+	digitsColor    = tcell.ColorDefault
+	// openEnded is -up without a duration: it counts up until stopped.
+	openEnded bool
+	// confirmQuit makes Esc and Ctrl-C abort a session only when pressed
+	// twice within confirmQuitWindow.
+	confirmQuit bool
 )
 
+// commands are the subcommands, run as "countdown <command> [args]".
+var commands = map[string]func(args []string){
+	"version":     versionCommand,
+	"self-update": selfUpdateCommand,
+	"tag":         tagCommand,
+	"auth":        authCommand,
+	"server":      serverCommand,
+	"jobs":        jobsCommand,
+	"xbar":        xbarCommand,
+}
+
+// builtinWords start command lines handled in main, which plugins cannot
+// take over.
+var builtinWords = map[string]bool{
+	"preset": true, "at": true, "routine": true, "cook": true, "rounds": true, "in": true, "until": true,
+}
+
+// flagCommands are the subcommands that share all the flags.
+var flagCommands = map[string]bool{
+	"run": true, "daemon": true, "again": true, "replay": true, "timeline": true, "report": true, "export": true, "verify": true,
+	"start": true, "pause": true, "resume": true, "status": true, "stop": true, "share": true, "stopwatch": true, "sync": true,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+		if !flagCommands[os.Args[1]] && !builtinWords[os.Args[1]] && pluginCommand(os.Args[1], os.Args[2:]) {
+			return
+		}
+	}
+
+	// SIGTERM, or SIGINT outside of the TUI (which reads Ctrl-C as a key),
+	// stops whatever runs cleanly.
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	// "countdown routine|cook|rounds <name> [flags]" and the flagCommands
+	// share all the flags, so they are handled here rather than as separate
+	// commands.
+	var routineName, bundleName, roundsName, presetName, atTime, subcommand string
+	if len(os.Args) > 1 && flagCommands[os.Args[1]] {
+		subcommand = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	} else if len(os.Args) > 2 && os.Args[1] == "preset" {
+		presetName = os.Args[2]
+		os.Args = append(os.Args[:1], os.Args[3:]...)
+	} else if len(os.Args) > 1 && len(os.Args[1]) > 1 && os.Args[1][0] == '@' {
+		presetName = os.Args[1][1:]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	} else if len(os.Args) > 2 && os.Args[1] == "at" {
+		// "countdown at 18:00 -t gym 1h": the time comes before the flags.
+		atTime, subcommand = os.Args[2], "at"
+		os.Args = append(os.Args[:1], os.Args[3:]...)
+	} else if len(os.Args) > 2 && os.Args[1] == "routine" {
+		routineName = os.Args[2]
+		os.Args = append(os.Args[:1], os.Args[3:]...)
+	} else if len(os.Args) > 2 && os.Args[1] == "cook" {
+		bundleName = os.Args[2]
+		os.Args = append(os.Args[:1], os.Args[3:]...)
+	} else if len(os.Args) > 2 && os.Args[1] == "rounds" {
+		roundsName = os.Args[2]
+		os.Args = append(os.Args[:1], os.Args[3:]...)
+	}
 
 	countUp := flag.Bool("up", false, "count up from zero")
 	tag := flag.String("t", "Unset", "The tag for this activity")
 	notes := flag.String("n", "", "Notes for this activity")
 	logPath := flag.String("f", os.Getenv("COUNTDOWN_LOG_PATH"), "The log path")
+	fontName := flag.String("font", "standard", "digit font: standard, slim, block or a FIGlet .flf file")
+	themeName := flag.String("theme", "", "color theme: default, mono, solarized, dracula, nord, gruvbox or one of [themes] in the config")
+	flag.DurationVar(&warnAt, "warn", 0, "turn the digits to the theme's warn color (yellow) with this much left, e.g. 2m")
+	flag.DurationVar(&criticalAt, "critical", 0, "turn the digits to the theme's critical color (red) with this much left, e.g. 30s")
+	flag.BoolVar(&gradient, "gradient", false, "fade the digits from green to red (requires truecolor)")
+	bannerArg := flag.String("banner", "", "ASCII-art file or text to show when the countdown completes")
+	bannerStart := flag.Bool("banner-start", false, "also show the banner before the countdown starts")
+	flag.BoolVar(&confetti, "confetti", false, "celebrate with confetti when the countdown completes")
+	flag.StringVar(&soundPath, "sound", "", "play this sound file when the countdown completes")
+	flag.IntVar(&volume, "volume", 100, "with -sound, the volume from 0 to 100")
+	flag.BoolVar(&repeatAlarm, "repeat", false, "repeat the -sound (or the bell) until a key is pressed")
+	alertName := flag.String("alert", "", "signal completion on every channel of this [alerts] profile; all uses flash, bell, notification and speech")
+	var hooks Hooks
+	flag.StringVar(&hooks.Start, "on-start", "", "shell command to run when a session starts, with {{.Tag}} and the like, see the README")
+	flag.StringVar(&hooks.Pause, "on-pause", "", "shell command to run when a session is paused")
+	flag.StringVar(&hooks.Resume, "on-resume", "", "shell command to run when a session is resumed")
+	flag.StringVar(&hooks.Finish, "on-finish", "", "shell command to run when a session completes")
+	flag.StringVar(&hooks.Finish, "on-done", "", "same as -on-finish")
+	flag.StringVar(&hooks.Abort, "on-abort", "", "shell command to run when a session is aborted")
+	logFormat := flag.String("log-format", "text", "how the log is written: text or jsonl")
+	audit := flag.Bool("audit", false, "chain the logged events by hash, as JSON Lines, for countdown verify")
+	logURL := flag.String("log", "", "also log the sessions to a database, e.g. sqlite:///path/to.db")
+	recordTimeline := flag.Bool("timeline", false, "record the session timeline for countdown replay")
+	notify := flag.Bool("notify", false, "send a desktop notification with the tag and notes when the countdown completes")
+	flag.BoolVar(&confirmQuit, "confirm-quit", false, "abort a session only when Esc or Ctrl-C is pressed twice within 2s")
+	flag.BoolVar(&fill, "fill", false, "fill the terminal background column by column as time elapses")
+	flag.BoolVar(&dimBreaks, "break", false, "dim the breaks of -pomodoro, rounds and workflows to a screen with just the time")
+	rendererName := flag.String("renderer", "auto", "digits renderer: auto, cells, kitty or sixel")
+	flag.BoolVar(&showDetails, "details", false, "show the tag, notes and end time under the digits, toggled with i")
+	flag.BoolVar(&progressBar, "progress", false, "show a progress bar under the digits, toggled with p")
+	flag.BoolVar(&ring, "ring", false, "draw a braille progress ring around the digits")
+	flag.BoolVar(&bellOnly, "bell-only", false, "skip all completion visuals and only ring the terminal bell")
+	flag.IntVar(&bells, "bells", 3, "how many times -bell-only rings the bell")
+	formatArg := flag.String("format", "", "print a templated line per tick instead of the TUI, e.g. '{{.Time}}'")
+	dryRun := flag.Bool("dry-run", false, "print the resolved end time and settings as JSON and exit")
+	var durationLiteral bool
+	flag.BoolVar(&durationLiteral, "d", false, "read H:MM[:SS] as a duration rather than a time of day")
+	flag.BoolVar(&durationLiteral, "duration-literal", false, "same as -d")
+	flag.BoolVar(&durationLiteral, "as-duration", false, "same as -d")
+	asTime := flag.Bool("as-time", false, "read H:MM as a time of day without asking")
+	untilNext := flag.Duration("until-next", 0, "count down to the next clock boundary of this interval, e.g. 30m")
+	flag.DurationVar(&tick, "tick", time.Second, "how often the display refreshes, e.g. 250ms or 10s")
+	lowPower := flag.Bool("low-power", false, "redraw rarely, draw plain cells and skip animations")
+	cpuProfile := flag.String("cpuprofile", "", "write a CPU profile to this file")
+	flag.StringVar(&profile, "profile", os.Getenv("COUNTDOWN_PROFILE"), "use a separate config and log, e.g. work or personal")
+	workflowPath := flag.String("workflow", "", "run the timers of a YAML workflow file")
+	remindAfter := flag.Duration("remind", 0, "run detached and only send a desktop notification after this duration")
+	nag := flag.Duration("nag", 0, "with -remind, repeat the notification at this interval")
+	examMode := flag.Bool("exam", false, "exam mode: no pausing, announcements, big end time and a record of the exam")
+	announceArg := flag.String("announce", "30m,15m,5m", "with -exam, when to announce the remaining time")
+	tts := flag.Bool("tts", false, "with -exam, also read announcements aloud")
+	meditation := flag.Bool("meditate", false, "meditation mode: gongs at start, halfway and end, minimal display, hold the quit key to exit")
+	cubing := flag.Bool("cube", false, "speedcubing timer: hold space to arm, release to start, any key to stop")
+	rotation := flag.String("rotate", "", "pair or mob rotation: comma-separated names, one turn per duration")
+	classroomMode := flag.Bool("classroom", false, "classroom mode: extra-large digits, 1-9 jump to -presets, + adds 2 minutes")
+	presetsArg := flag.String("presets", "5m,10m,15m", "with -classroom, the durations bound to keys 1 to 9")
+	noHints := flag.Bool("no-hints", false, "with -classroom, hide the key hints")
+	kioskMode := flag.Bool("kiosk", false, "always-on display: no exit keys, restarts on the [kiosk] schedule or forever")
+	timeout := flag.Duration("timeout", 0, "with run, stop the command after this duration")
+	ciName := flag.String("ci", "auto", "with run, CI log annotations: auto, github, teamcity or none")
+	exportCSV := flag.Bool("csv", false, "with export, write the sessions as CSV")
+	exportICS := flag.Bool("ics", false, "with export, write the sessions as an iCalendar file")
+	exportFrom := flag.String("from", "", "with export, the first day, e.g. 2024-01-01")
+	exportTo := flag.String("to", "", "with export, the last day, e.g. 2024-01-31")
+	pomodoroMode := flag.Bool("pomodoro", false, "alternate work and breaks, see [pomodoro] in the config")
+	chainMode := flag.Bool("chain", false, "run the durations one after another, e.g. -chain 10m 2m 10m")
+	chainWait := flag.Bool("wait", false, "with -chain, wait for a key before each segment")
+	chessMode := flag.Bool("chess", false, "a chess clock, any key ends a move, e.g. -chess 5m or -chess 5m 3m")
+	fischer := flag.Duration("fischer", 0, "with -chess, the time added after every move")
+	bronstein := flag.Duration("bronstein", 0, "with -chess, give back the time a move took, up to this much")
+	intervalMode := flag.Bool("interval", false, "interval training, e.g. -interval work=40s rest=20s rounds=8")
+	pluginList := flag.String("plugins", "", "comma-separated plugins, countdown-<name> executables that read the events as JSON")
+	parallelMode := flag.Bool("parallel", false, "run the durations as timers side by side, e.g. -parallel tea=3m eggs=7m")
+	shareAddr := flag.String("share", "", "with daemon, serve a read-only share link of the timer on this address, e.g. :8421")
+	sprintCommand := flag.String("sprint", "", "writing sprint: sample the word count with this command, e.g. 'wc -w draft.md'")
+	sprintEvery := flag.Duration("sprint-every", 30*time.Second, "with -sprint, how often to sample")
+	targetWPM := flag.Float64("wpm", 0, "with -sprint, the target pace in words per minute")
+	batteryLow := flag.Int("battery-low", 0, "warn when the countdown outlasts the battery and notify when it drops below this percent")
+	rateArg := flag.String("rate", "", "count up and show the accumulated cost, e.g. 4.50/h or $12/30m")
 	flag.Parse()
 
-	if *logPath == "" {
-		fmt.Println("No file argument given, set COUNTDOWN_LOG_PATH env variable or provide a file as -f argument.")
+	if err := applyEnv(); err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(2)
+	}
+	if *remindAfter > 0 {
+		remind(*remindAfter, *nag, strings.Join(flag.Args(), " "))
+		return
+	}
+	if profile != "" {
+		if !validProfile(profile) {
+			stderr("error: invalid profile %q\n", profile)
+			os.Exit(2)
+		}
+		// The environment log path is shared by all profiles.
+		if !isFlagSet("f") {
+			*logPath = ""
+		}
+	}
+
+	if presetName != "" {
+		presetConfig, err := loadConfig(configPath())
+		if err != nil {
+			stderr("error: invalid config: %v\n", err)
+			os.Exit(2)
+		}
+		if err := applyPreset(presetName, presetConfig.Presets, os.Args[1:]); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if *rateArg != "" {
+		var err error
+		if rate, err = parseRate(*rateArg); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+		*countUp = true
+	}
+
+	if tick <= 0 {
+		stderr("error: -tick must be positive\n")
+		os.Exit(2)
+	}
+
+	config, err := loadConfig(configPath())
+	if err != nil {
+		stderr("error: invalid config: %v\n", err)
+		os.Exit(2)
+	}
+	if keys, err = parseKeys(config.Keys, config.Macros); err != nil {
+		stderr("error: invalid config: %v\n", err)
+		os.Exit(2)
+	}
+	if err := validateMacros(config.Macros); err != nil {
+		stderr("error: invalid config: %v\n", err)
+		os.Exit(2)
+	}
+	macros = config.Macros
+	if err := parseScripts(config.Script); err != nil {
+		stderr("error: invalid config: %v\n", err)
+		os.Exit(2)
+	}
+	if tags, err = loadTags(tagsPath()); err != nil {
+		stderr("error: invalid tags file: %v\n", err)
+		os.Exit(2)
+	}
+	if *logPath == "" && !*dryRun && config.LogPath == "" && !configExists() && isInteractive() {
+		config = setupWizard(configPath())
+	}
+	// The command line beats the config file, which beats the environment.
+	if !isFlagSet("f") && config.LogPath != "" {
+		*logPath = config.LogPath
+	}
+	if !fromCommandLine("t") && config.Tag != "" {
+		*tag = config.Tag
+	}
+	if !isFlagSet("t") && config.Suggest.Enabled && (subcommand == "" || subcommand == "start") && !*dryRun && isInteractive() {
+		if suggested, ok := suggestTag(config.Suggest); ok {
+			*tag = suggested
+		}
+	}
+	if !fromCommandLine("up") && config.Up {
+		*countUp = true
+	}
+	if !fromCommandLine("gradient") && config.Gradient {
+		gradient = true
+	}
+	if !fromCommandLine("theme") && config.Theme != "" {
+		*themeName = config.Theme
+	}
+	if *themeName != "" {
+		if theme, err = lookupTheme(*themeName, config.Themes); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if !fromCommandLine("font") && config.Font != "" {
+		*fontName = config.Font
+	}
+	font, err := loadFont(*fontName)
+	if err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(2)
+	}
+	render.UseFont(font)
+	for _, t := range []struct {
+		name  string
+		value string
+		d     *time.Duration
+	}{{"warn", config.Warn, &warnAt}, {"critical", config.Critical, &criticalAt}} {
+		if !fromCommandLine(t.name) && t.value != "" {
+			if *t.d, err = parse.Literal(t.value); err != nil {
+				stderr("error: invalid config: %s: %v\n", t.name, err)
+				os.Exit(2)
+			}
+		}
+		if *t.d < 0 {
+			stderr("error: -%s must not be negative\n", t.name)
+			os.Exit(2)
+		}
+	}
+	if !fromCommandLine("bell-only") && config.BellOnly {
+		bellOnly = true
+	}
+	if !fromCommandLine("details") && config.Details {
+		showDetails = true
+	}
+	if !fromCommandLine("progress") && config.Progress {
+		progressBar = true
+	}
+	if !fromCommandLine("confetti") && config.Confetti {
+		confetti = true
+	}
+	if !fromCommandLine("sound") && config.Sound != "" {
+		soundPath = config.Sound
+	}
+	if !fromCommandLine("volume") && config.Volume != 0 {
+		volume = config.Volume
+	}
+	if soundPath != "" {
+		if _, err := os.Stat(soundPath); err != nil {
+			stderr("error: -sound: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if volume < 0 || volume > 100 {
+		stderr("error: -volume must be from 0 to 100\n")
+		os.Exit(2)
+	}
+	if !fromCommandLine("log-format") && config.LogFormat != "" {
+		*logFormat = config.LogFormat
+	}
+	format, err := logbook.ParseFormat(*logFormat)
+	if err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(2)
+	}
+	if !fromCommandLine("audit") && config.Audit {
+		*audit = true
+	}
+	if !fromCommandLine("log") && config.Log != "" {
+		*logURL = config.Log
+	}
+	var dbPath string
+	if *logURL != "" {
+		if dbPath, err = sqlitePath(*logURL); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if !fromCommandLine("timeline") && config.Timeline {
+		*recordTimeline = true
+	}
+	if !fromCommandLine("notify") && config.Notify {
+		*notify = true
+	}
+	if !fromCommandLine("battery-low") && config.BatteryLow > 0 {
+		*batteryLow = config.BatteryLow
+	}
+	if *batteryLow < 0 || *batteryLow > 100 {
+		stderr("error: -battery-low must be a percent from 0 to 100\n")
+		os.Exit(2)
+	}
+	if !fromCommandLine("break") && config.DimBreaks {
+		dimBreaks = true
+	}
+	if !fromCommandLine("confirm-quit") && config.ConfirmQuit {
+		confirmQuit = true
+	}
+	plugins := parsePlugins(*pluginList)
+	if !fromCommandLine("plugins") && len(config.Plugins) > 0 {
+		plugins = config.Plugins
+	}
+	if err := findPlugins(plugins); err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(2)
+	}
+	if !fromCommandLine("alert") && config.Alert != "" {
+		*alertName = config.Alert
+	}
+	if *alertName != "" {
+		if alertProfile, err = lookupAlert(*alertName, config.Alerts); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *lowPower {
+		if !isFlagSet("tick") {
+			tick = lowPowerTick
+		}
+		*rendererName = "cells"
+		confetti = false
+	}
+
+	switch subcommand {
+	case "start", "pause", "resume", "status", "stop", "share", "at":
+		req := daemonRequest{Command: subcommand, Tag: *tag, Notes: *notes}
+		if subcommand == "start" || subcommand == "at" {
+			if req.Duration, err = parse.Duration(flag.Args(), durationLiteral, config.Unit); err != nil {
+				fail(err)
+			}
+		}
+		if subcommand == "at" {
+			d, err := parse.TimeOfDay(atTime)
+			if err != nil {
+				stderr("error: invalid time %q, expected e.g. 18:00 or 6:00PM\n", atTime)
+				os.Exit(2)
+			}
+			req.Command, req.At = "schedule", time.Now().Add(d).Truncate(time.Minute)
+		}
+		var tmpl *template.Template
+		if *formatArg != "" {
+			if tmpl, err = template.New("format").Parse(*formatArg); err != nil {
+				stderr("error: invalid format: %v\n", err)
+				os.Exit(2)
+			}
+		}
+		var daemonArgs []string
+		if isFlagSet("f") {
+			daemonArgs = append(daemonArgs, "-f", *logPath)
+		}
+		if profile != "" {
+			daemonArgs = append(daemonArgs, "-profile", profile)
+		}
+		if *shareAddr != "" {
+			daemonArgs = append(daemonArgs, "-share", *shareAddr)
+		}
+		control(req, tmpl, daemonArgs)
+		return
+	}
+
+	if *logPath == "" && !*dryRun {
+		fmt.Println("No file argument given, set COUNTDOWN_LOG_PATH env variable, log_path in " + configPath() + " or provide a file as -f argument.")
 		os.Exit(2)
 	}
-	_, err := os.OpenFile(*logPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if !*dryRun {
+		if err := logbook.Create(*logPath); err != nil {
+			fail(err)
+		}
+	}
+
+	light, err = newLight(config.Light)
 	if err != nil {
-		fmt.Println("There was a problem accessing " + *logPath)
+		stderr("error: %v\n", err)
 		os.Exit(2)
 	}
+	bus.Subscribe(logEvents(format, *audit))
+	if dbPath != "" && !*dryRun {
+		if err := openSQLite(dbPath); err != nil {
+			fail(fmt.Errorf("%w: %v", logbook.ErrUnavailable, err))
+		}
+		bus.Subscribe(sqliteEvents(dbPath))
+	}
+	bus.Subscribe(lightEvents)
+	bus.Subscribe(statusEvents())
+	// The daemon notifies on its own.
+	if *notify && !alertProfile.Notify && subcommand != "daemon" {
+		bus.Subscribe(notifyEvents())
+		if runtime.GOOS == "windows" {
+			bus.Subscribe(toastProgressEvents())
+		}
+	}
+	if alertProfile != (AlertProfile{}) {
+		bus.Subscribe(alertEvents(alertProfile))
+	}
+	// A timeline is one session at a time.
+	if *recordTimeline && !*parallelMode {
+		bus.Subscribe(timelineEvents())
+	}
+	if !hooks.empty() {
+		templates, err := hooks.parse()
+		if err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+		bus.Subscribe(hookEvents(templates))
+	}
+	if scripts.next != nil {
+		bus.Subscribe(scriptEvents())
+	}
+	if len(plugins) > 0 && !*dryRun {
+		bus.Subscribe(pluginEvents(plugins))
+	}
+
+	renderer, err = newRenderer(*rendererName)
+	if err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(2)
+	}
+
+	if *bannerArg != "" {
+		banner = loadBanner(*bannerArg)
+	}
+
+	if subcommand == "daemon" {
+		serveDaemon(ctx, *logPath, *shareAddr)
+		return
+	}
+	if subcommand == "replay" {
+		replayCommand(ctx, *logPath, flag.Args())
+		return
+	}
+	if subcommand == "timeline" {
+		timelineCSVCommand(*logPath, flag.Args())
+		return
+	}
+	if subcommand == "report" {
+		reportCommand(*logPath, flag.Args())
+		return
+	}
+	if subcommand == "export" {
+		if *exportCSV == *exportICS {
+			stderr("error: export needs one format, e.g. countdown export -csv or -ics\n")
+			os.Exit(2)
+		}
+		exportCommand(*logPath, *exportFrom, *exportTo, *exportICS)
+		return
+	}
+	if subcommand == "verify" {
+		verifyCommand(*logPath)
+		return
+	}
+	if subcommand == "sync" {
+		syncCommand(ctx, config.Sync, *logPath)
+		return
+	}
+	if subcommand == "run" {
+		if flag.NArg() == 0 {
+			stderr("error: run needs a command, e.g. countdown run -- make test\n")
+			os.Exit(2)
+		}
+		ci, err := newAnnotator(*ciName)
+		if err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+		os.Exit(run(ctx, flag.Args(), *timeout, ci, *tag, *logPath))
+	}
+
+	if *batteryLow > 0 {
+		batteryStatuses = make(chan batteryStatus, 1)
+		go watchBattery(ctx, *batteryLow)
+	}
+
+	var wf *Workflow
+	if *workflowPath != "" {
+		wf, err = loadWorkflow(*workflowPath)
+		if err != nil {
+			stderr("error: workflow: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	var routine []time.Duration
+	if routineName != "" {
+		routine, err = parseRoutine(routineName, config.Routines[routineName])
+		if err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	var bundle []cookItem
+	var bundleTotal time.Duration
+	if bundleName != "" {
+		bundle, bundleTotal, err = scheduleBundle(bundleName, config.Cooking[bundleName])
+		if err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	var rounds *roundPlan
+	if roundsName != "" {
+		rounds, err = parseRounds(roundsName, config.Rounds[roundsName])
+		if err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	var pomo *pomodoroPlan
+	if *pomodoroMode {
+		pomo, err = parsePomodoro(config.Pomodoro)
+		if err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	named := routine != nil || bundle != nil || rounds != nil || pomo != nil
 
 	args := flag.Args()
-	if len(args) != 1 {
+	stopwatchMode := subcommand == "stopwatch"
+	openEnded = isFlagSet("up") && len(args) == 0 && subcommand == "" && wf == nil && !named && rate == nil && *untilNext <= 0 &&
+		!*cubing && !*meditation && !*examMode && !*intervalMode && !*chessMode && !*parallelMode && !*chainMode && !*classroomMode && !*kioskMode
+	if subcommand == "again" {
+		s, err := pickSession(*logPath, strings.Join(args, " "))
+		if err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(1)
+		}
+		*tag, *notes = s.tag, s.notes
+		args = []string{s.duration.String()}
+	}
+	var slots []kioskSlot
+	if *kioskMode {
+		if wf != nil || named || rate != nil || *cubing || *classroomMode || *examMode || *meditation || *rotation != "" {
+			stderr("error: -kiosk only supports a plain countdown\n")
+			os.Exit(2)
+		}
+		if len(config.Kiosk.Schedule) > 0 {
+			if len(args) != 0 {
+				stderr("error: -kiosk with a schedule takes no duration argument\n")
+				os.Exit(2)
+			}
+			if slots, err = parseKiosk(config.Kiosk); err != nil {
+				stderr("error: %v\n", err)
+				os.Exit(2)
+			}
+			slot, _ := nextSlot(slots, time.Now())
+			args = []string{slot.duration.String()}
+		}
+	}
+	if *classroomMode {
+		if wf != nil || named || rate != nil || *cubing {
+			stderr("error: -classroom only supports a plain countdown\n")
+			os.Exit(2)
+		}
+		presets, err := parsePresets(*presetsArg)
+		if err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+		classroom = &Classroom{presets: presets, hints: !*noHints}
+		digitScale = classroomScale
+		if len(args) == 0 && *untilNext <= 0 {
+			args = []string{presets[0].String()}
+		}
+	}
+	var chessTimes [2]time.Duration
+	if *chessMode {
+		if *intervalMode || *chainMode || *parallelMode || wf != nil || named || rate != nil || *cubing || *classroomMode || *examMode || *meditation || *rotation != "" || *kioskMode || *formatArg != "" || *untilNext > 0 {
+			stderr("error: -chess only supports plain countdowns\n")
+			os.Exit(2)
+		}
+		if *fischer < 0 || *bronstein < 0 || *fischer > 0 && *bronstein > 0 {
+			stderr("error: -chess takes either -fischer or -bronstein\n")
+			os.Exit(2)
+		}
+		if chessTimes, err = parseChess(args, durationLiteral, config.Unit); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+		args = nil
+	}
+	var interval *intervalPlan
+	if *intervalMode {
+		if *chainMode || *parallelMode || wf != nil || named || rate != nil || *cubing || *classroomMode || *examMode || *meditation || *rotation != "" || *kioskMode || *formatArg != "" || *untilNext > 0 {
+			stderr("error: -interval only supports plain countdowns\n")
+			os.Exit(2)
+		}
+		if interval, err = parseInterval(args, config.Unit); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+		args = nil
+	}
+	if len(args) == 0 && *untilNext <= 0 && wf == nil && !named && interval == nil && !*chessMode && rate == nil && !openEnded && !*cubing && !stopwatchMode && tags[*tag].Duration != "" {
+		args = []string{tags[*tag].Duration}
+	}
+	if len(args) == 0 && *untilNext <= 0 && wf == nil && !named && interval == nil && !*chessMode && rate == nil && !openEnded && !*cubing && !stopwatchMode && scripts.duration != nil {
+		d, err := scriptDuration(*logPath, *tag, *notes, config.Unit)
+		if err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+		if d != "" {
+			args = []string{d}
+		}
+	}
+	if len(args) == 0 && *untilNext <= 0 && wf == nil && !named && interval == nil && !*chessMode && rate == nil && !openEnded && !*cubing && !stopwatchMode && config.Duration != "" {
+		args = []string{config.Duration}
+	}
+	if len(args) == 0 && *untilNext <= 0 && wf == nil && !named && interval == nil && !*chessMode && rate == nil && !openEnded && !*cubing && !stopwatchMode {
 		stderr(usage)
 		flag.PrintDefaults()
 		os.Exit(2)
 	}
-	timeLeft, err := parseTime(args[0])
 
-	if err != nil {
-		timeLeft, err = time.ParseDuration(args[0])
+	var parallelSessions []plannedSession
+	if *parallelMode {
+		if wf != nil || named || rate != nil || *cubing || *classroomMode || *examMode || *meditation || *rotation != "" || *kioskMode || *formatArg != "" || *untilNext > 0 {
+			stderr("error: -parallel only supports plain countdowns\n")
+			os.Exit(2)
+		}
+		if parallelSessions, err = parseParallel(args, *tag, config.Unit); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	var chain []time.Duration
+	if *chainMode {
+		if *parallelMode || wf != nil || named || rate != nil || *cubing || *classroomMode || *examMode || *meditation || *rotation != "" || *kioskMode || *formatArg != "" || *untilNext > 0 {
+			stderr("error: -chain only supports plain countdowns\n")
+			os.Exit(2)
+		}
+		if chain, err = parseChain(args, durationLiteral, config.Unit); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	var timeLeft time.Duration
+	if stopwatchMode {
+		if len(args) != 0 || *untilNext > 0 || wf != nil || named || rate != nil || *cubing || *chessMode || interval != nil || chain != nil || parallelSessions != nil {
+			stderr("error: stopwatch takes no duration argument\n")
+			os.Exit(2)
+		}
+	} else if *cubing {
+		if len(args) != 0 || *untilNext > 0 || wf != nil || named || rate != nil {
+			stderr("error: -cube takes no duration argument\n")
+			os.Exit(2)
+		}
+	} else if named {
+		if len(args) != 0 || *untilNext > 0 || wf != nil {
+			stderr("error: a routine, cooking bundle, rounds or -pomodoro takes no duration argument\n")
+			os.Exit(2)
+		}
+		timeLeft = bundleTotal
+		if routine != nil {
+			timeLeft = routine[0]
+		}
+		if rounds != nil {
+			timeLeft = rounds.round
+		}
+		if pomo != nil {
+			timeLeft = pomo.work
+		}
+	} else if wf != nil {
+		if len(args) != 0 || *untilNext > 0 {
+			stderr("error: -workflow takes no duration argument\n")
+			os.Exit(2)
+		}
+		timeLeft = wf.Steps[wf.Start].duration
+	} else if chain != nil {
+		timeLeft = chain[0]
+	} else if interval != nil {
+		timeLeft = interval.work
+	} else if *chessMode {
+		timeLeft = chessTimes[0]
+	} else if parallelSessions != nil {
+		for _, s := range parallelSessions {
+			if s.duration > timeLeft {
+				timeLeft = s.duration
+			}
+		}
+	} else if *untilNext > 0 {
+		if len(args) != 0 {
+			stderr("error: -until-next takes no duration argument\n")
+			os.Exit(2)
+		}
+		timeLeft = untilBoundary(time.Now(), *untilNext)
+	} else if len(args) == 0 {
+		// A meter, or -up without a duration, runs until it is stopped.
+		timeLeft = meterLimit
+	} else {
+		if len(args) == 1 && !durationLiteral && !*asTime && ambiguousArg.MatchString(args[0]) && isInteractive() {
+			durationLiteral = askDuration(args[0])
+		}
+		timeLeft, err = parse.Duration(args, durationLiteral, config.Unit)
 		if err != nil {
-			stderr("error: invalid duration or time: %v\n", args[0])
+			fail(err)
+		}
+	}
+
+	if digitsColor = tagColor(*tag); digitsColor == tcell.ColorDefault && config.Color != "" {
+		digitsColor = tcell.GetColor(config.Color)
+	}
+	if digitsColor == tcell.ColorDefault {
+		digitsColor = theme.digits
+	}
+	// A tag's rate shows the cost without turning the countdown into a
+	// meter.
+	if rate == nil && tags[*tag].Rate != "" {
+		if rate, err = parseRate(tags[*tag].Rate); err != nil {
+			stderr("error: tag %s: %v\n", *tag, err)
 			os.Exit(2)
 		}
 	}
 
-	err = termbox.Init()
-	if err != nil {
-		panic(err)
+	var tmpl *template.Template
+	if *formatArg != "" {
+		tmpl, err = template.New("format").Parse(*formatArg)
+		if err != nil {
+			stderr("error: invalid format: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if *dryRun {
+		printPlan(Plan{
+			Duration: timeLeft.String(),
+			Seconds:  timeLeft.Seconds(),
+			End:      time.Now().Add(timeLeft).Round(time.Second),
+			Up:       *countUp,
+			Break:    dimBreaks,
+			Tag:      *tag,
+			Notes:    *notes,
+			LogPath:  *logPath,
+			Profile:  profile,
+			Config:   configPath(),
+			Light:    config.Light.Provider,
+			Renderer: *rendererName,
+			Format:   *formatArg,
+		})
+		return
+	}
+
+	if *cpuProfile != "" {
+		startProfile(*cpuProfile)
 	}
 
-	queues = make(chan termbox.Event)
-	go func() {
-		for {
-			queues <- termbox.PollEvent()
+	if *sprintCommand != "" {
+		if wf != nil || named || openEnded || rate != nil || *examMode || *meditation || *rotation != "" || *cubing || stopwatchMode || *chainMode || *chessMode || interval != nil || parallelSessions != nil || *kioskMode || *classroomMode || tmpl != nil {
+			stderr("error: -sprint only supports a plain countdown\n")
+			os.Exit(2)
 		}
-	}()
-	countdown(timeLeft, *countUp, *tag, *notes, *logPath)
-}
+		if *sprintEvery <= 0 || *targetWPM < 0 {
+			stderr("error: -sprint-every must be positive and -wpm not negative\n")
+			os.Exit(2)
+		}
+		sprint = &Sprint{command: *sprintCommand, every: *sprintEvery, target: *targetWPM}
+	}
+
+	if *meditation && (wf != nil || named || *countUp || *examMode || tmpl != nil) {
+		stderr("error: -meditate only supports a plain countdown\n")
+		os.Exit(2)
+	}
+
+	var names []string
+	if *rotation != "" {
+		if wf != nil || named || *countUp || *examMode || *meditation || tmpl != nil {
+			stderr("error: -rotate only supports a plain countdown\n")
+			os.Exit(2)
+		}
+		if names, err = parseRotation(*rotation); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	var announce []time.Duration
+	if *examMode {
+		if wf != nil || named || *countUp || tmpl != nil {
+			stderr("error: -exam only supports a plain countdown\n")
+			os.Exit(2)
+		}
+		if announce, err = parseAnnouncements(*announceArg); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if tmpl != nil {
+		if wf != nil || named {
+			stderr("error: -format does not support workflows, routines, cooking bundles, rounds or -pomodoro\n")
+			os.Exit(2)
+		}
+		stream(ctx, tmpl, timeLeft, *countUp, *tag, *notes, *logPath)
+		return
+	}
+
+	if err = openScreen(); err != nil {
+		fail(err)
+	}
+	defer restoreOnPanic()
 
-func start(d time.Duration) {
-	timer = time.NewTimer(d)
-	ticker = time.NewTicker(tick)
+	if *examMode {
+		if exam, err = startExam(announce, *tts, timeLeft, *tag, *logPath); err != nil {
+			closeScreen()
+			stderr("error: exam record: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	queues, stopEvents = pollEvents()
+	if banner != nil && *bannerStart {
+		showBanner(banner, bannerStartDelay)
+	}
+	if bundle != nil {
+		finish(cook(ctx, bundle, bundleTotal, *tag, "cook "+bundleName, *logPath))
+		return
+	}
+	if pomo != nil {
+		finish(pomodoro(ctx, pomo, *tag, *logPath))
+		return
+	}
+	if rounds != nil {
+		finish(runRounds(ctx, rounds, *tag, *logPath))
+		return
+	}
+	if routine != nil {
+		finish(runRoutine(ctx, config.Routines[routineName], routine, *countUp, *tag, *logPath))
+		return
+	}
+	if wf != nil {
+		finish(runWorkflow(ctx, wf, *countUp, *tag, *logPath))
+		return
+	}
+	if *kioskMode {
+		finish(kiosk(ctx, slots, config.Kiosk.Passphrase, timeLeft, *tag, *logPath))
+		return
+	}
+	if *cubing {
+		finish(cube(ctx, *tag, *logPath))
+		return
+	}
+	if stopwatchMode {
+		noFanfare()
+		finish(stopwatch(ctx, *tag, *notes, *logPath))
+		return
+	}
+	if names != nil {
+		finish(rotate(ctx, names, timeLeft, *tag, *logPath))
+		return
+	}
+	if chain != nil {
+		finish(runChain(ctx, chain, *chainWait, *countUp, *tag, *notes, *logPath))
+		return
+	}
+	if *chessMode {
+		finish(chess(ctx, chessTimes, *fischer, *bronstein, *tag, *logPath))
+		return
+	}
+	if interval != nil {
+		finish(runInterval(ctx, interval, config.Interval, *tag, *logPath))
+		return
+	}
+	if parallelSessions != nil {
+		finish(parallel(ctx, parallelSessions, *tag, *logPath))
+		return
+	}
+	if *meditation {
+		completed := meditate(ctx, timeLeft, *tag, *notes, *logPath)
+		// The gongs were the end.
+		noFanfare()
+		finish(completed)
+		return
+	}
+	if *sprintCommand != "" {
+		if err := sprint.begin(ctx); err != nil {
+			closeScreen()
+			stderr("error: %v\n", err)
+			os.Exit(2)
+		}
+		defer sprint.stop()
+		bus.Subscribe(sprintEvents)
+	}
+	canPlan = exam == nil && classroom == nil && rate == nil
+	if openEnded {
+		noFanfare()
+	}
+	completed := countdown(ctx, timeLeft, *countUp, *tag, *notes, *logPath)
+	for nextSession != nil {
+		next := *nextSession
+		nextSession = nil
+		completed = countdown(ctx, next.duration, false, next.tag, "", *logPath)
+	}
+	if exam != nil {
+		exam.finish(completed)
+	}
+	finish(completed)
 }
 
-func stop() {
-	timer.Stop()
-	ticker.Stop()
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
 
 func durationToDraw(timeLeft, totalDuration time.Duration, countUp bool) time.Duration {
@@ -103,137 +1079,432 @@ func durationToDraw(timeLeft, totalDuration time.Duration, countUp bool) time.Du
 	return timeLeft
 }
 
-func countdown(totalDuration time.Duration, countUp bool, tag string, notes string, logPath string) {
-	timeLeft := totalDuration
-	var exitCode int
-	isPaused = false
-	w, h = termbox.Size()
-	start(timeLeft)
-	appendToLog("i", tag, notes, logPath)
+func elapsedFraction(timeLeft, totalDuration time.Duration) float64 {
+	if totalDuration <= 0 {
+		return 0
+	}
+	return 1 - float64(timeLeft)/float64(totalDuration)
+}
 
-	draw(durationToDraw(timeLeft, totalDuration, countUp), w, h)
+// countdown runs one timer and reports whether it completed rather than
+// being aborted.
+func countdown(ctx context.Context, totalDuration time.Duration, countUp bool, tag string, notes string, logPath string) bool {
+	t := timer.New(totalDuration, tick)
+	events := t.Subscribe()
+	defer t.Stop()
+	warned := false
+	var resized <-chan time.Time
+	w, h = screen.Size()
+	t.Start()
+	bus.Publish(Event{Kind: SessionStarted, Tag: tag, Notes: notes, LogPath: logPath, Left: totalDuration, Total: totalDuration})
+
+	var pal palette
+	// help is the overlay of the keys, open until the next key.
+	var help bool
+	var added []string
+	// adjusted is the time added with the adjustActions, or taken off.
+	var adjusted time.Duration
+	// quitAsked is when Esc was first pressed, with -confirm-quit.
+	var quitAsked time.Time
+	redraw := func() {
+		draw(t.Left(), t.Total(), countUp, w, h)
+		if t.Paused() {
+			drawPause(w, h)
+		}
+		pal.draw(w, h)
+		if help {
+			drawHelp(activeKeys(t.Paused()), w, h)
+		}
+	}
+	elapsed := func() time.Duration {
+		return t.Total() - t.Left()
+	}
+	// endNotes are the notes added from the palette and the cost, logged
+	// with the end of the session.
+	endNotes := func(elapsed time.Duration) string {
+		notes := added
+		if adjusted != 0 {
+			notes = append(notes[:len(notes):len(notes)], "adjusted "+signedDuration(adjusted))
+		}
+		if cost := rateNote(elapsed); cost != "" {
+			notes = append(notes[:len(notes):len(notes)], cost)
+		}
+		if words := sprintNote(elapsed); words != "" {
+			notes = append(notes[:len(notes):len(notes)], words)
+		}
+		if openEnded {
+			notes = append(notes[:len(notes):len(notes)], "elapsed "+render.Format(elapsed))
+		}
+		return strings.Join(notes, "; ")
+	}
+	setPaused := func(paused bool) {
+		if paused == t.Paused() {
+			return
+		}
+		if paused {
+			t.Pause()
+			bus.Publish(Event{Kind: SessionPaused, Tag: tag, LogPath: logPath})
+		} else {
+			t.Resume()
+			bus.Publish(Event{Kind: SessionResumed, Tag: tag, LogPath: logPath})
+		}
+	}
+	session := &paletteSession{timer: t, tag: &tag, notes: &added, setPaused: setPaused}
+	details.tag, details.notes = &tag, notes
+	defer func() { details.tag = nil }()
+	redraw()
 
-loop:
 	for {
 		select {
+		case <-ctx.Done():
+			bus.Publish(Event{Kind: SessionEnded, Tag: tag, Notes: endNotes(elapsed()), LogPath: logPath})
+			return false
 		case ev := <-queues:
-			if ev.Key == termbox.KeyEsc || ev.Key == termbox.KeyCtrlC {
-				exitCode = 1
-				appendToLog("o", tag, "", logPath)
-				break loop
-			}
-
-			if pressTime := time.Now(); ev.Key == termbox.KeySpace && pressTime.Sub(inputStartTime) > inputDelayMS {
-				if isPaused {
-					start(timeLeft)
-					appendToLog("u", tag, "", logPath)
-					draw(durationToDraw(timeLeft, totalDuration, countUp), w, h)
-				} else {
-					stop()
-					appendToLog("p", tag, "", logPath)
-					drawPause(w, h)
+			if _, ok := ev.(*tcell.EventResize); ok {
+				// Redraw once the resizing settles rather than on every step.
+				resized = time.After(resizeDelay)
+				break
+			}
+			key, ok := ev.(*tcell.EventKey)
+			if !ok {
+				break
+			}
+			bus.Publish(Event{Kind: Input, Tag: tag, Key: key})
+			if pal.open {
+				if line, ok := pal.key(key); ok {
+					pal.run(line, session)
+					if session.quit {
+						bus.Publish(Event{Kind: SessionEnded, Tag: tag, Notes: endNotes(elapsed()), LogPath: logPath})
+						return false
+					}
+				}
+				redraw()
+				break
+			}
+			if help || pressed("help", key) {
+				help = !help
+				redraw()
+				break
+			}
+			if name, ok := macroForKey(key.Rune()); ok && key.Key() == tcell.KeyRune && exam == nil {
+				pal.run("macro "+name, session)
+				if session.quit {
+					bus.Publish(Event{Kind: SessionEnded, Tag: tag, Notes: endNotes(elapsed()), LogPath: logPath})
+					return false
+				}
+				redraw()
+				break
+			}
+			if pal.message != "" {
+				pal.message = ""
+				redraw()
+			}
+			if pressed("quit", key) {
+				if openEnded {
+					// Stopping is how an open-ended count-up is done.
+					bus.Publish(Event{Kind: SessionEnded, Tag: tag, Notes: endNotes(elapsed()), LogPath: logPath, Completed: true, Quiet: true, Total: elapsed()})
+					return true
 				}
+				if confirmQuit && time.Since(quitAsked) > confirmQuitWindow {
+					quitAsked = time.Now()
+					pal.message, pal.failed = confirmQuitPrompt, false
+					redraw()
+					break
+				}
+				bus.Publish(Event{Kind: SessionEnded, Tag: tag, Notes: endNotes(elapsed()), LogPath: logPath})
+				return false
+			}
+
+			// Exam mode has no pausing, so no palette either.
+			if pressed("palette", key) && exam == nil {
+				pal.start()
+				redraw()
+				break
+			}
 
-				isPaused = !isPaused
+			if pressTime := time.Now(); pressed("pause", key) && exam == nil && pressTime.Sub(inputStartTime) > inputDelayMS {
+				setPaused(!t.Paused())
+				redraw()
 				inputStartTime = time.Now()
 			}
 
-			if ev.Type == termbox.EventResize {
-				w, h = termbox.Size()
-				draw(durationToDraw(timeLeft, totalDuration, countUp), w, h)
+			if stepControls && pressed("next", key) {
+				// Skipped steps count as done, without the fanfare.
+				bus.Publish(Event{Kind: SessionEnded, Tag: tag, Notes: endNotes(elapsed()), LogPath: logPath, Completed: true, Quiet: true})
+				return true
+			}
+
+			if pressed("progress", key) {
+				progressBar = !progressBar
+				redraw()
+				break
+			}
 
-				if isPaused {
-					drawPause(w, h)
+			if pressed("details", key) {
+				showDetails = !showDetails
+				redraw()
+				break
+			}
+
+			// restart starts the session over: the false start is logged as
+			// aborted and a new session starts with the whole duration.
+			if pressed("restart", key) && exam == nil {
+				bus.Publish(Event{Kind: SessionEnded, Tag: tag, Notes: endNotes(elapsed()), LogPath: logPath})
+				added, adjusted, warned = nil, 0, false
+				t.Reset(totalDuration)
+				t.Resume()
+				bus.Publish(Event{Kind: SessionStarted, Tag: tag, Notes: notes, LogPath: logPath, Left: totalDuration, Total: totalDuration})
+				redraw()
+				break
+			}
+
+			// Classroom mode has a + of its own, and an exam or a meter runs
+			// for as long as it runs.
+			for action, step := range adjustActions {
+				if !pressed(action, key) || classroom != nil || exam != nil || openEnded {
+					continue
+				}
+				// Taking off more than is left would end the session as
+				// completed, which is not what a stray key should do.
+				if step > 0 || t.Left() > -step {
+					t.Extend(step)
+					adjusted += step
+					redraw()
 				}
 			}
-		case <-ticker.C:
-			timeLeft -= tick
-			draw(durationToDraw(timeLeft, totalDuration, countUp), w, h)
-		case <-timer.C:
-			appendToLog("o", tag, "", logPath)
-			break loop
+
+			if classroom != nil {
+				if d, ok := classroom.preset(key.Rune()); ok {
+					t.Reset(d)
+					redraw()
+				} else if pressed("classroom_add", key) {
+					t.Extend(classroomExtension)
+					redraw()
+				}
+			}
+		case <-resized:
+			resized = nil
+			w, h = screen.Size()
+			redraw()
+		case s := <-batteryStatuses:
+			// Shown like the outcome of a command, until a key is pressed.
+			if warning := s.warning(t.Left()); warning != "" && !openEnded {
+				pal.message, pal.failed = warning, true
+				redraw()
+			}
+		case ev := <-events:
+			if ev.Kind == timer.Changed {
+				bus.Publish(Event{Kind: SessionChanged, Tag: tag, Left: ev.Left, Total: ev.Total})
+			}
+			if ev.Kind != timer.Tick {
+				break
+			}
+			if warnBefore > 0 && !warned && ev.Left <= warnBefore {
+				warned = true
+				// Ringing waits between the bells, which would hold up the keys.
+				go ringBell(warnBells)
+			}
+			if exam != nil {
+				exam.check(ev.Left)
+			}
+			if !quitAsked.IsZero() && time.Since(quitAsked) > confirmQuitWindow {
+				quitAsked = time.Time{}
+				if pal.message == confirmQuitPrompt {
+					pal.message = ""
+				}
+			}
+			bus.Publish(Event{Kind: Tick, Tag: tag, Left: ev.Left, Total: ev.Total})
+			draw(ev.Left, ev.Total, countUp, w, h)
+			pal.draw(w, h)
+			if help {
+				drawHelp(activeKeys(t.Paused()), w, h)
+			}
+		case <-t.Done():
+			bus.Publish(Event{Kind: SessionEnded, Tag: tag, Notes: endNotes(t.Total()), LogPath: logPath, Completed: true, Quiet: bellOnly, Total: t.Total()})
+			return true
 		}
 	}
+}
+
+// signedDuration writes d with its sign, e.g. +1m0s or -10s.
+func signedDuration(d time.Duration) string {
+	if d > 0 {
+		return "+" + d.String()
+	}
+	return d.String()
+}
+
+// noFanfare is for timers that end when they are stopped, which is no
+// achievement to celebrate.
+func noFanfare() {
+	confetti, soundPath, repeatAlarm, alertProfile.Flash = false, "", false, false
+}
 
-	termbox.Close()
-	if exitCode != 0 {
-		os.Exit(exitCode)
+// finish tears down the TUI after the last timer and signals completion,
+// or exits with status 1 when it was aborted.
+func finish(completed bool) {
+	if completed && (soundPath != "" || repeatAlarm) {
+		alarm()
+	}
+	if completed && alertProfile.Flash && !bellOnly {
+		flashScreen()
+	}
+	if completed && confetti && !bellOnly {
+		celebrate()
+	}
+
+	stopEvents()
+	closeScreen()
+	stopProfile()
+	if !completed {
+		os.Exit(exitAborted)
+	}
+	if bellOnly {
+		ringBell(bells)
+		return
+	}
+	if banner != nil {
+		printBanner(banner)
 	}
 }
 
-func draw(d time.Duration, w int, h int) {
+func draw(timeLeft, totalDuration time.Duration, countUp bool, w int, h int) {
 	clear()
 
-	str := format(d)
-	text := toText(str)
+	if isBreak {
+		drawBreak(durationToDraw(timeLeft, totalDuration, countUp), w, h)
+		return
+	}
 
-	startX, startY := w/2-text.width()/2, h/2-text.height()/2
+	elapsed := elapsedFraction(timeLeft, totalDuration)
+	style := tcell.StyleDefault.Foreground(digitsColor)
+	if gradient {
+		style = style.Foreground(gradientColor(elapsed))
+	}
+	if fg, _, _ := style.Decompose(); !openEnded {
+		style = style.Foreground(thresholdColor(timeLeft, fg))
+	}
 
-	x, y := startX, startY
-	for _, s := range text {
-		echo(s, x, y)
-		x += s.width()
+	renderer.drawTime(render.Format(durationToDraw(timeLeft, totalDuration, countUp)), style, w, h)
+
+	if ring {
+		drawRing(elapsed, style, w, h)
 	}
 
-	flush()
-}
+	line := caption
+	if rate != nil {
+		line = strings.TrimSpace(caption + "  " + rate.cost(totalDuration-timeLeft))
+	}
+	if exam != nil {
+		line = exam.message
+		exam.drawEnd(w, h)
+	}
+	if heading != "" {
+		drawHeading(heading, w, h)
+	}
+	if classroom != nil {
+		classroom.drawHints(w, h)
+	}
+	below := h/2 + render.Digits("0").Height()/2 + 1
+	if progressBar && !openEnded {
+		drawProgress(elapsed, style, w, below)
+		below += 2
+	}
+	if showDetails && details.tag != nil {
+		drawDetails(timeLeft, w, below)
+		below += 2
+	}
+	if line != "" {
+		echoString(line, w/2-utf8.RuneCountInString(line)/2, below, tcell.StyleDefault.Foreground(theme.text))
+	}
 
-func drawPause(w int, h int) {
-	startX := w/2 - pausedText.width()/2
-	startY := h * 3 / 4
+	if fill {
+		bg := theme.fill
+		if bg == tcell.ColorDefault {
+			bg = tcell.ColorBlue
+		}
+		if gradient {
+			bg = gradientColor(elapsed)
+		}
+		fillColumns(int(elapsed*float64(w)), h, bg)
+	}
 
-	echo(pausedText, startX, startY)
 	flush()
+	renderer.present()
 }
 
-func format(d time.Duration) string {
-	d = d.Round(time.Second)
-	h := d / time.Hour
-	d -= h * time.Hour
-	m := d / time.Minute
-	d -= m * time.Minute
-	s := d / time.Second
-
-	if h < 1 {
-		return fmt.Sprintf("%02d:%02d", m, s)
+// drawHeading draws str in big letters above the digits, when there is
+// room for it.
+func drawHeading(str string, w, h int) {
+	s := render.Big(str)
+	y := h/2 - render.Digits("0").Height()/2 - s.Height() - 1
+	if y < 0 {
+		return
 	}
-	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
+	echo(s, w/2-s.Width()/2, y, tcell.StyleDefault)
 }
 
-func appendToLog(state string, tag string, notes string, logPath string){
-	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
-	if err != nil {
-		stderr("There was a problem accessing " + logPath)
-		os.Exit(2)
-	}
-	defer f.Close()
+// drawBreak renders a deliberately dull screen to discourage working
+// through a break.
+func drawBreak(d time.Duration, w int, h int) {
+	str := "Break – " + render.Format(d)
+	echoString(str, w/2-utf8.RuneCountInString(str)/2, h/2, tcell.StyleDefault.Dim(true))
+	flush()
+}
 
-	var log string = state + " " + time.Now().Format("2006-01-02 15:04:05") + " " +tag + "  " + notes + "\n"
+func drawPause(w int, h int) {
+	startX := w/2 - render.PausedText.Width()/2
+	startY := h * 3 / 4
 
-	if _, err = f.WriteString(log); err != nil {
-		stderr("There was a problem writing to " + logPath)
-		os.Exit(2)
-	}
+	echo(render.PausedText, startX, startY, tcell.StyleDefault)
+	flush()
 }
 
-func parseTime(date string) (time.Duration, error) {
-	targetTime, err := time.Parse(time.Kitchen, strings.ToUpper(date))
-	if err != nil {
-		targetTime, err = time.Parse("15:04", date)
-		if err != nil {
-			return time.Duration(0), err
+// logEvents returns the subscriber that writes the session events to the
+// session's log in format, or chained by hash with audit. The events of a session share an id, the time
+// it started; sessions that overlap (-parallel) are told apart by tag.
+func logEvents(format logbook.Format, audit bool) func(Event) {
+	sessions := make(map[string]string)
+	var last string
+	return func(e Event) {
+		var state string
+		session, ok := sessions[e.Tag]
+		if !ok {
+			session = last
+		}
+		switch e.Kind {
+		case SessionStarted:
+			state, session = logbook.Start, e.Time.Format(sessionID)
+			sessions[e.Tag], last = session, session
+		case SessionPaused:
+			state = logbook.Pause
+		case SessionResumed:
+			state = logbook.Resume
+		case SessionEnded:
+			state = logbook.End
+		case SessionLap:
+			state = logbook.Lap
+		default:
+			return
+		}
+		if state == logbook.End {
+			delete(sessions, e.Tag)
+		}
+		le := logbook.Event{State: state, Time: e.Time, Tag: e.Tag, Notes: e.Notes, Session: session}
+		write := format.Append
+		if audit {
+			write = logbook.AppendChained
+		}
+		if err := write(e.LogPath, le); err != nil {
+			fail(err)
 		}
 	}
+}
 
-	now := time.Now()
-	originTime := time.Date(0, time.January, 1, now.Hour(), now.Minute(), now.Second(), 0, time.UTC)
-
-	// The time of day has already passed, so target tomorrow.
-	if targetTime.Before(originTime) {
-		targetTime = targetTime.AddDate(0, 0, 1)
-	}
-
-	duration := targetTime.Sub(originTime)
-
-	return duration, err
+// untilBoundary returns the time left until the next multiple of interval
+// counted from local midnight, e.g. the next :00 or :30 for 30m.
+func untilBoundary(now time.Time, interval time.Duration) time.Duration {
+	now = now.Truncate(time.Second)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return interval - now.Sub(midnight)%interval
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// simulateScreen draws to an off-screen terminal of w by h for the test.
+func simulateScreen(tb testing.TB, w, h int) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		tb.Fatal(err)
+	}
+	s.SetSize(w, h)
+	screen = s
+	tb.Cleanup(func() {
+		s.Fini()
+		screen = nil
+		back, front = frame{}, frame{}
+	})
+}
+
+// BenchmarkDrawTick is a tick of a running countdown: a second less to
+// draw, of which only the changed cells go to the terminal.
+func BenchmarkDrawTick(b *testing.B) {
+	simulateScreen(b, 120, 40)
+	total := time.Hour
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		draw(total-time.Duration(i%3600)*time.Second, total, false, 120, 40)
+	}
+}
+
+// BenchmarkDrawUnchanged redraws the same time, as a resize or a key
+// does; nothing reaches the terminal.
+func BenchmarkDrawUnchanged(b *testing.B) {
+	simulateScreen(b, 120, 40)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		draw(25*time.Minute, time.Hour, false, 120, 40)
+	}
+}
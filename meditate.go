@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"time"
+	"unicode/utf8"
+
+	"github.com/antonmedv/countdown/render"
+	"github.com/antonmedv/countdown/timer"
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	// longPress is how long Esc must be held to end a meditation.
+	longPress = 1500 * time.Millisecond
+	// keyRepeatGap is the longest gap between repeats of a held key.
+	keyRepeatGap = 700 * time.Millisecond
+	endGongs     = 3
+)
+
+// meditate runs a do-not-disturb countdown: a gong at the start, halfway
+// and the end, a minimal display, and every key ignored except a long
+// press of a quit key, esc by default. The gong is -sound, or the bell
+// without one.
+func meditate(ctx context.Context, totalDuration time.Duration, tag string, notes string, logPath string) bool {
+	t := timer.New(totalDuration, tick)
+	events := t.Subscribe()
+	defer t.Stop()
+	halfway := false
+	var escStart, escLast time.Time
+	var resized <-chan time.Time
+	w, h = screen.Size()
+	t.Start()
+	bus.Publish(Event{Kind: SessionStarted, Tag: tag, Notes: notes, LogPath: logPath, Left: totalDuration, Total: totalDuration})
+	go gong(1)
+	drawMeditation(totalDuration, w, h)
+
+	for {
+		select {
+		case <-ctx.Done():
+			bus.Publish(Event{Kind: SessionEnded, Tag: tag, LogPath: logPath})
+			return false
+		case ev := <-queues:
+			if _, ok := ev.(*tcell.EventResize); ok {
+				resized = time.After(resizeDelay)
+				continue
+			}
+			if key, ok := ev.(*tcell.EventKey); !ok || !pressed("quit", key) {
+				continue
+			}
+			// A held key arrives as a stream of repeats.
+			now := time.Now()
+			if now.Sub(escLast) > keyRepeatGap {
+				escStart = now
+			}
+			escLast = now
+			if now.Sub(escStart) >= longPress {
+				bus.Publish(Event{Kind: SessionEnded, Tag: tag, LogPath: logPath})
+				return false
+			}
+		case <-resized:
+			resized = nil
+			w, h = screen.Size()
+			drawMeditation(t.Left(), w, h)
+		case ev := <-events:
+			if !halfway && ev.Left <= totalDuration/2 {
+				halfway = true
+				go gong(1)
+			}
+			drawMeditation(ev.Left, w, h)
+		case <-t.Done():
+			bus.Publish(Event{Kind: SessionEnded, Tag: tag, LogPath: logPath, Completed: true, Quiet: true})
+			gong(endGongs)
+			return true
+		}
+	}
+}
+
+// gong plays -sound n times in a row, as alarm does, or rings the bell
+// for the times it can't, and returns once it is over.
+func gong(n int) {
+	for i := 0; i < n; i++ {
+		var cmd *exec.Cmd
+		if soundPath != "" {
+			cmd = soundCommand(soundPath, volume)
+		}
+		if cmd == nil || cmd.Run() != nil {
+			ringBell(n - i)
+			return
+		}
+	}
+}
+
+func drawMeditation(timeLeft time.Duration, w, h int) {
+	clear()
+	str := render.Format(timeLeft)
+	echoString(str, w/2-utf8.RuneCountInString(str)/2, h/2, tcell.StyleDefault.Dim(true))
+	if hint := meditationHint(); hint != "" {
+		echoString(hint, w/2-utf8.RuneCountInString(hint)/2, h-2, tcell.StyleDefault.Dim(true))
+	}
+	flush()
+}
+
+// meditationHint names the first quit key, the one to hold, or is empty
+// when quit has none.
+func meditationHint() string {
+	if len(keys["quit"]) == 0 {
+		return ""
+	}
+	return "hold " + keys["quit"][0].name + " to end"
+}
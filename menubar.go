@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/antonmedv/countdown/render"
+)
+
+// menuBarState is what the status file holds while a session runs: the
+// end while running, or the time left while paused, so it only changes
+// when the session does.
+type menuBarState struct {
+	State string        `json:"state"`
+	Tag   string        `json:"tag"`
+	Notes string        `json:"notes,omitempty"`
+	End   time.Time     `json:"end,omitempty"`
+	Left  time.Duration `json:"left,omitempty"`
+	Total time.Duration `json:"total"`
+}
+
+// statusFile is where the running session is announced for menu bar
+// plugins; each profile has its own.
+func statusFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	name := "status.json"
+	if profile != "" {
+		name = "status-" + profile + ".json"
+	}
+	return filepath.Join(dir, "countdown", name)
+}
+
+// statusEvents returns the subscriber that keeps the status file up to
+// date, and removes it when the session ends.
+func statusEvents() func(Event) {
+	var s menuBarState
+	return func(e Event) {
+		now := time.Now()
+		switch e.Kind {
+		case SessionStarted:
+			s = menuBarState{State: "running", Tag: e.Tag, Notes: e.Notes, End: now.Add(e.Left), Total: e.Total}
+		case SessionPaused:
+			s.State, s.Left, s.End = "paused", s.End.Sub(now), time.Time{}
+		case SessionResumed:
+			s.State, s.End, s.Left = "running", now.Add(s.Left), 0
+		case SessionChanged:
+			s.Total = e.Total
+			if s.State == "paused" {
+				s.Left = e.Left
+			} else {
+				s.End = now.Add(e.Left)
+			}
+		case SessionEnded:
+			_ = os.Remove(statusFile())
+			return
+		default:
+			return
+		}
+		writeStatusFile(s)
+	}
+}
+
+func writeStatusFile(s menuBarState) {
+	path := statusFile()
+	if os.MkdirAll(filepath.Dir(path), 0700) != nil {
+		return
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	// Replace the file at once so a plugin never reads half of it.
+	tmp := path + ".tmp"
+	if os.WriteFile(tmp, b, 0600) == nil {
+		_ = os.Rename(tmp, path)
+	}
+}
+
+// xbarPlugin is the plugin "countdown xbar -install" writes; the name
+// asks xbar and SwiftBar to refresh it every second.
+const xbarPlugin = "countdown.1s.sh"
+
+// xbarCommand prints the active timer in the xbar and SwiftBar plugin
+// format: the daemon's timer with pause, resume and stop in the menu, or
+// else the session in the status file.
+func xbarCommand(args []string) {
+	fs := flag.NewFlagSet("xbar", flag.ExitOnError)
+	fs.StringVar(&profile, "profile", os.Getenv("COUNTDOWN_PROFILE"), "show the timer of a profile")
+	install := fs.String("install", "", "write a plugin running countdown xbar into this plugin folder")
+	_ = fs.Parse(args)
+	if profile != "" && !validProfile(profile) {
+		stderr("error: invalid profile %q\n", profile)
+		os.Exit(2)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "countdown"
+	}
+	if *install != "" {
+		command := shellQuote(exe) + " xbar"
+		if profile != "" {
+			command += " -profile " + shellQuote(profile)
+		}
+		script := "#!/bin/sh\nexec " + command + "\n"
+		if err := os.WriteFile(filepath.Join(*install, xbarPlugin), []byte(script), 0755); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if resp, err := sendDaemon(daemonRequest{Command: "status"}); err == nil && resp.Status != nil && resp.Status.State != "idle" {
+		s := resp.Status
+		fmt.Printf("⏱ %s\n---\n", s.Remaining)
+		printXbarSession(s.State, s.Tag, s.Notes, s.Remaining, s.Total)
+		fmt.Println("---")
+		if s.State == "paused" {
+			printXbarAction("Resume", exe, "resume")
+		} else {
+			printXbarAction("Pause", exe, "pause")
+		}
+		printXbarAction("Stop", exe, "stop")
+		return
+	}
+
+	var s menuBarState
+	b, err := os.ReadFile(statusFile())
+	if errors.Is(err, os.ErrNotExist) || (err == nil && json.Unmarshal(b, &s) != nil) {
+		fmt.Println("⏱")
+		fmt.Println("---")
+		fmt.Println("No timer running")
+		return
+	}
+	if err != nil {
+		fmt.Println("⏱ ?")
+		fmt.Println("---")
+		fmt.Println(err)
+		return
+	}
+	left := s.Left
+	if s.State == "running" {
+		if left = time.Until(s.End); left < 0 {
+			left = 0
+		}
+	}
+	fmt.Printf("⏱ %s\n---\n", render.Format(left))
+	printXbarSession(s.State, s.Tag, s.Notes, render.Format(left), render.Format(s.Total))
+}
+
+func printXbarSession(state, tag, notes, remaining, total string) {
+	fmt.Printf("%s %s left of %s (%s)\n", xbarEscape(tag), remaining, total, state)
+	if notes != "" {
+		fmt.Println(xbarEscape(notes))
+	}
+}
+
+func printXbarAction(title, exe, command string) {
+	fmt.Printf("%s | shell=%s param1=%s terminal=false refresh=true\n", title, xbarQuote(exe), command)
+}
+
+// xbarEscape keeps text from being read as plugin parameters.
+func xbarEscape(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", "¦"), "\n", " ")
+}
+
+func xbarQuote(s string) string {
+	if strings.ContainsAny(s, ` "`) {
+		return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	}
+	return s
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// desktopNotify shows a native desktop notification: notify-send on Linux
+// and BSDs, osascript on macOS and a toast on Windows.
+func desktopNotify(title, body string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		if path, err := exec.LookPath("terminal-notifier"); err == nil {
+			cmd = exec.Command(path, "-title", title, "-message", body)
+		} else {
+			script := fmt.Sprintf("display notification %s with title %s", appleScriptString(body), appleScriptString(title))
+			cmd = exec.Command("osascript", "-e", script)
+		}
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", windowsToast(title, body))
+	default:
+		cmd = exec.Command("notify-send", "--app-name=countdown", title, body)
+	}
+	return cmd.Run()
+}
+
+// notifyEvents returns the -notify subscriber, which sends a desktop
+// notification when a session completes. The end of a session carries
+// its outcome as notes, so the notes are taken from its start. The notify
+// script, if any, writes the body.
+func notifyEvents() func(Event) {
+	var notes string
+	return func(e Event) {
+		switch e.Kind {
+		case SessionStarted:
+			notes = e.Notes
+		case SessionEnded:
+			if e.Completed && !e.Quiet {
+				_ = desktopNotify("countdown: "+e.Tag+" is done", scriptNotify(e, notes, notes))
+			}
+		}
+	}
+}
+
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func powershellString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func windowsToast(title, body string) string {
+	return `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null;` +
+		`$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02);` +
+		`$text = $xml.GetElementsByTagName('text');` +
+		`$text.Item(0).AppendChild($xml.CreateTextNode(` + powershellString(title) + `)) | Out-Null;` +
+		`$text.Item(1).AppendChild($xml.CreateTextNode(` + powershellString(body) + `)) | Out-Null;` +
+		`[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('countdown').Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Retries back off from retryBase, doubling after every attempt.
+const (
+	retryAttempts = 3
+	retryBase     = 500 * time.Millisecond
+	// outboxMax is how many undelivered requests are kept; beyond it the
+	// oldest are dropped.
+	outboxMax = 1000
+	// outboxLockStale is when a lock left by a crashed countdown is taken
+	// over.
+	outboxLockStale = time.Minute
+)
+
+// statusError is an HTTP response that is not a success.
+type statusError struct {
+	method, url, status string
+	code                int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.method, e.url, e.status)
+}
+
+// retryable reports whether a request that failed with err may work
+// later: the network was down, it timed out or the server was busy. Other
+// refusals will not change.
+func retryable(err error) bool {
+	var s *statusError
+	if errors.As(err, &s) {
+		return s.code == http.StatusRequestTimeout || s.code == http.StatusTooManyRequests || s.code >= 500
+	}
+	return err != nil
+}
+
+// retry calls send up to retryAttempts times while it fails with a
+// retryable error and ctx allows.
+func retry(ctx context.Context, send func(ctx context.Context) error) error {
+	wait := retryBase
+	for attempt := 1; ; attempt++ {
+		err := send(ctx)
+		if err == nil || !retryable(err) || attempt == retryAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// outboxEntry is a request that could not be delivered yet. ID goes out
+// as the Idempotency-Key header, so a receiver can drop a request that
+// arrives twice after a timeout.
+type outboxEntry struct {
+	ID     string          `json:"id"`
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body"`
+	Queued time.Time       `json:"queued"`
+}
+
+func outboxPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	name := "outbox.jsonl"
+	if profile != "" {
+		name = "outbox-" + profile + ".jsonl"
+	}
+	return filepath.Join(dir, "countdown", name)
+}
+
+// deliverJSON sends body like sendJSON, with retries, and keeps it in the
+// outbox when it still fails for a reason that may pass, such as flaky
+// Wi-Fi. The requests waiting in the outbox are sent first, so every
+// receiver gets them in order, and a receiver that is still down gets
+// this one queued behind them.
+func deliverJSON(ctx context.Context, method, url string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	entry := outboxEntry{
+		ID:     fmt.Sprintf("%d-%d", time.Now().UnixNano(), os.Getpid()),
+		Method: method, URL: url, Body: b, Queued: time.Now(),
+	}
+	path := outboxPath()
+	unlock, err := lockOutbox(path)
+	if err != nil {
+		// Without the outbox this is a plain request with retries.
+		return retry(ctx, entry.send)
+	}
+	defer unlock()
+
+	pending, _ := readOutbox(path)
+	pending = append(pending, entry)
+	var keep []outboxEntry
+	down := map[string]error{}
+	for i, e := range pending {
+		// Queued requests get one attempt each and the new one retries; a
+		// receiver that fails keeps the rest of its requests waiting.
+		var ok bool
+		if err, ok = down[e.URL]; !ok {
+			if i == len(pending)-1 {
+				err = retry(ctx, e.send)
+			} else {
+				err = e.send(ctx)
+			}
+		}
+		if retryable(err) {
+			down[e.URL] = err
+			keep = append(keep, e)
+		}
+	}
+	// err is the new request's.
+	if werr := writeOutbox(path, keep); err == nil {
+		err = werr
+	}
+	return err
+}
+
+func (e outboxEntry) send(ctx context.Context) error {
+	return sendBody(ctx, e.Method, e.URL, "", e.ID, e.Body)
+}
+
+// lockOutbox keeps two countdowns from sending the same requests.
+func lockOutbox(path string) (unlock func(), err error) {
+	lock := path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		info, statErr := os.Stat(lock)
+		if statErr != nil || time.Since(info.ModTime()) < outboxLockStale {
+			return nil, err
+		}
+		os.Remove(lock)
+	}
+	return nil, fmt.Errorf("outbox %s is locked", path)
+}
+
+// readOutbox reads the waiting requests; a missing outbox has none.
+func readOutbox(path string) ([]outboxEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []outboxEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var e outboxEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// writeOutbox replaces the outbox with entries, removing it when there
+// are none.
+func writeOutbox(path string, entries []outboxEntry) error {
+	if len(entries) == 0 {
+		err := os.Remove(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if len(entries) > outboxMax {
+		entries = entries[len(entries)-outboxMax:]
+	}
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
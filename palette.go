@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antonmedv/countdown/timer"
+	"github.com/gdamore/tcell/v2"
+)
+
+// palette is the command line opened with ':' during a countdown, like
+// vim's. Its commands are in paletteCommands, so new actions need no key
+// of their own.
+type palette struct {
+	open  bool
+	input []rune
+	// message is the outcome of the last command, shown until a key is
+	// pressed.
+	message string
+	failed  bool
+}
+
+// paletteSession is what the commands act on: the running countdown.
+type paletteSession struct {
+	timer *timer.Timer
+	tag   *string
+	// notes are added with note and logged when the session ends.
+	notes *[]string
+	// setPaused pauses or resumes the countdown and tells the bus.
+	setPaused func(paused bool)
+	quit      bool
+	// depth counts the macros running, so one cannot run itself forever.
+	depth int
+}
+
+// maxMacroDepth is how deeply macros may run other macros.
+const maxMacroDepth = 8
+
+type paletteCommand struct {
+	usage string
+	run   func(s *paletteSession, args []string) (string, error)
+}
+
+var paletteCommands map[string]paletteCommand
+
+func init() {
+	paletteCommands = map[string]paletteCommand{
+		"add": {"add <duration>", func(s *paletteSession, args []string) (string, error) {
+			if len(args) != 1 {
+				return "", fmt.Errorf("usage: add <duration>")
+			}
+			d, err := time.ParseDuration(args[0])
+			if err != nil || d <= 0 {
+				return "", fmt.Errorf("invalid duration %q", args[0])
+			}
+			s.timer.Extend(d)
+			return "added " + d.String(), nil
+		}},
+		"tag": {"tag <name>", func(s *paletteSession, args []string) (string, error) {
+			if len(args) == 0 {
+				return "tag " + *s.tag, nil
+			}
+			*s.tag = strings.Join(args, " ")
+			return "tagged " + *s.tag, nil
+		}},
+		"note": {"note <text>", func(s *paletteSession, args []string) (string, error) {
+			if len(args) == 0 {
+				return "", fmt.Errorf("usage: note <text>")
+			}
+			*s.notes = append(*s.notes, strings.Join(args, " "))
+			return "noted", nil
+		}},
+		"pause": {"pause", func(s *paletteSession, args []string) (string, error) {
+			s.setPaused(true)
+			return "", nil
+		}},
+		"resume": {"resume", func(s *paletteSession, args []string) (string, error) {
+			s.setPaused(false)
+			return "", nil
+		}},
+		// quit only leaves a paused countdown, as vim's :q keeps unsaved
+		// work; quit! aborts a running one.
+		"quit": {"quit", func(s *paletteSession, args []string) (string, error) {
+			if !s.timer.Paused() {
+				return "", fmt.Errorf("the countdown is running, quit! aborts it")
+			}
+			s.quit = true
+			return "", nil
+		}},
+		"quit!": {"quit!", func(s *paletteSession, args []string) (string, error) {
+			s.quit = true
+			return "", nil
+		}},
+		// start ends the session and starts another, as only a plain
+		// countdown can.
+		"start": {"start <duration> [tag]", func(s *paletteSession, args []string) (string, error) {
+			if len(args) == 0 {
+				return "", fmt.Errorf("usage: start <duration> [tag]")
+			}
+			if !canPlan {
+				return "", fmt.Errorf("start only works in a plain countdown")
+			}
+			d, err := time.ParseDuration(args[0])
+			if err != nil || d <= 0 {
+				return "", fmt.Errorf("invalid duration %q", args[0])
+			}
+			next := plannedSession{duration: d, tag: *s.tag}
+			if len(args) > 1 {
+				next.tag = strings.Join(args[1:], " ")
+			}
+			nextSession, s.quit = &next, true
+			return "", nil
+		}},
+		"record": {"record <macro>", func(s *paletteSession, args []string) (string, error) {
+			if len(args) != 1 || !macroName.MatchString(args[0]) {
+				return "", fmt.Errorf("usage: record <macro>, named with letters, digits, _ and -")
+			}
+			if recording != nil {
+				return "", fmt.Errorf("already recording %s", recording.name)
+			}
+			if _, ok := macros[args[0]]; ok {
+				return "", fmt.Errorf("macro %s exists", args[0])
+			}
+			recording = &macroRecording{name: args[0]}
+			return "recording " + args[0], nil
+		}},
+		"save": {"save [key]", func(s *paletteSession, args []string) (string, error) {
+			if recording == nil {
+				return "", fmt.Errorf("not recording, see record")
+			}
+			if len(recording.commands) == 0 {
+				return "", fmt.Errorf("nothing recorded yet")
+			}
+			m := Macro{Commands: recording.commands}
+			if len(args) > 0 {
+				m.Key = args[0]
+			}
+			if err := saveMacro(configPath(), recording.name, m); err != nil {
+				return "", err
+			}
+			name := recording.name
+			recording = nil
+			return "saved macro " + name, nil
+		}},
+		"macro": {"macro <name>", func(s *paletteSession, args []string) (string, error) {
+			if len(args) != 1 {
+				return "", fmt.Errorf("usage: macro <name>")
+			}
+			m, ok := macros[args[0]]
+			if !ok {
+				return "", fmt.Errorf("no macro %q", args[0])
+			}
+			if s.depth >= maxMacroDepth {
+				return "", fmt.Errorf("macro %s: macros nested too deeply", args[0])
+			}
+			s.depth++
+			defer func() { s.depth-- }()
+			var msg string
+			for _, line := range m.Commands {
+				var err error
+				if msg, err = runPaletteLine(line, s); err != nil {
+					return "", fmt.Errorf("macro %s: %s: %v", args[0], line, err)
+				}
+				if s.quit {
+					break
+				}
+			}
+			return msg, nil
+		}},
+		"help": {"help", func(s *paletteSession, args []string) (string, error) {
+			var usages []string
+			for _, c := range paletteCommands {
+				usages = append(usages, c.usage)
+			}
+			sort.Strings(usages)
+			return strings.Join(usages, "  "), nil
+		}},
+	}
+}
+
+// key handles a key while the palette is open, and returns the command
+// line once Enter is pressed.
+func (p *palette) key(ev *tcell.EventKey) (line string, entered bool) {
+	switch ev.Key() {
+	case tcell.KeyEscape, tcell.KeyCtrlC:
+		p.open = false
+	case tcell.KeyEnter:
+		p.open = false
+		return string(p.input), true
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(p.input) == 0 {
+			p.open = false
+		} else {
+			p.input = p.input[:len(p.input)-1]
+		}
+	case tcell.KeyRune:
+		p.input = append(p.input, ev.Rune())
+	}
+	return "", false
+}
+
+// start opens the palette with an empty command line.
+func (p *palette) start() {
+	p.open, p.input, p.message, p.failed = true, nil, "", false
+}
+
+// run runs a command line on the session and keeps its outcome to show.
+// While recording, the commands that worked are added to the macro.
+func (p *palette) run(line string, s *paletteSession) {
+	var err error
+	p.message, err = runPaletteLine(line, s)
+	p.failed = err != nil
+	if err != nil {
+		p.message = err.Error()
+		return
+	}
+	fields := strings.Fields(line)
+	if recording == nil || len(fields) == 0 {
+		return
+	}
+	switch fields[0] {
+	case "record", "save", "help":
+	default:
+		recording.commands = append(recording.commands, strings.TrimSpace(line))
+	}
+}
+
+func runPaletteLine(line string, s *paletteSession) (string, error) {
+	words, err := splitWords(line)
+	if err != nil {
+		return "", err
+	}
+	if len(words) == 0 {
+		return "", nil
+	}
+	c, ok := paletteCommands[words[0]]
+	if !ok {
+		return "", fmt.Errorf("unknown command %q, try help", words[0])
+	}
+	return c.run(s, words[1:])
+}
+
+// draw shows the command line, or the last outcome, on the bottom line.
+func (p *palette) draw(w, h int) {
+	style := tcell.StyleDefault
+	switch {
+	case p.open:
+		line := ":" + string(p.input)
+		echoString(line, 0, h-1, style)
+		setCell(len([]rune(line)), h-1, ' ', style.Reverse(true))
+	case p.message != "":
+		if p.failed {
+			style = style.Foreground(tcell.ColorRed)
+		}
+		echoString(p.message, 0, h-1, style.Dim(!p.failed))
+	case recording != nil:
+		echoString("recording "+recording.name, 0, h-1, style.Dim(true))
+	default:
+		return
+	}
+	flush()
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/antonmedv/countdown/parse"
+	"github.com/antonmedv/countdown/render"
+	"github.com/antonmedv/countdown/timer"
+	"github.com/gdamore/tcell/v2"
+)
+
+const parallelHints = "Tab: select  Space: pause  a: add  x: stop  Esc: quit"
+
+// pane is one timer of -parallel, logged as a session of its own.
+type pane struct {
+	tag       string
+	timer     *timer.Timer
+	ended     bool
+	completed bool
+}
+
+// parseParallel reads the -parallel arguments, a duration each, with an
+// optional tag in front: "25m", "tea=3m". Timers without a tag get tag.
+func parseParallel(args []string, tag, unit string) ([]plannedSession, error) {
+	var sessions []plannedSession
+	for _, arg := range args {
+		s := plannedSession{tag: tag}
+		if i := strings.Index(arg, "="); i >= 0 {
+			s.tag, arg = arg[:i], arg[i+1:]
+		}
+		d, err := parse.Duration([]string{arg}, true, unit)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("-parallel: invalid duration %q", arg)
+		}
+		s.duration = d
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+// uniqueTag numbers tag if another timer has it already, as the log
+// tells overlapping sessions apart by their tags.
+func uniqueTag(panes []*pane, tag string) string {
+	taken := func(t string) bool {
+		for _, p := range panes {
+			if p.tag == t && !p.ended {
+				return true
+			}
+		}
+		return false
+	}
+	unique := tag
+	for n := 2; taken(unique); n++ {
+		unique = fmt.Sprintf("%s %d", tag, n)
+	}
+	return unique
+}
+
+// parallel runs several timers at once, side by side when they fit and
+// stacked otherwise, each with its own tag and pause. Tab selects a
+// timer, Space pauses it and x stops it; a adds a timer. It reports
+// whether every timer completed.
+func parallel(ctx context.Context, sessions []plannedSession, tag string, logPath string) bool {
+	var panes []*pane
+	add := func(s plannedSession) {
+		p := &pane{tag: uniqueTag(panes, s.tag), timer: timer.New(s.duration, tick)}
+		panes = append(panes, p)
+		p.timer.Start()
+		bus.Publish(Event{Kind: SessionStarted, Tag: p.tag, LogPath: logPath, Left: s.duration, Total: s.duration})
+	}
+	end := func(p *pane, completed bool) {
+		p.ended, p.completed = true, completed
+		p.timer.Stop()
+		bus.Publish(Event{Kind: SessionEnded, Tag: p.tag, LogPath: logPath, Completed: completed, Total: p.timer.Total()})
+	}
+	for _, s := range sessions {
+		add(s)
+	}
+	defer func() {
+		for _, p := range panes {
+			if !p.ended {
+				end(p, false)
+			}
+		}
+	}()
+
+	var selected int
+	var prompt palette
+	var resized <-chan time.Time
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	w, h = screen.Size()
+	redraw := func() {
+		drawParallel(panes, selected, &prompt, w, h)
+	}
+	redraw()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case ev := <-queues:
+			if _, ok := ev.(*tcell.EventResize); ok {
+				resized = time.After(resizeDelay)
+				break
+			}
+			key, ok := ev.(*tcell.EventKey)
+			if !ok {
+				break
+			}
+			if prompt.open {
+				if line, ok := prompt.key(key); ok {
+					prompt.message, prompt.failed = "", false
+					added, err := parseParallel(strings.Fields(line), tag, "")
+					if err == nil && len(added) == 0 {
+						err = fmt.Errorf("usage: <duration> or <tag>=<duration>")
+					}
+					if err != nil {
+						prompt.message, prompt.failed = err.Error(), true
+					}
+					for _, s := range added {
+						add(s)
+						selected = len(panes) - 1
+					}
+				}
+				redraw()
+				break
+			}
+			prompt.message = ""
+			p := panes[selected]
+			switch {
+			case pressed("quit", key):
+				return false
+			case key.Key() == tcell.KeyTab || key.Key() == tcell.KeyRight || key.Key() == tcell.KeyDown:
+				selected = (selected + 1) % len(panes)
+			case key.Key() == tcell.KeyBacktab || key.Key() == tcell.KeyLeft || key.Key() == tcell.KeyUp:
+				selected = (selected + len(panes) - 1) % len(panes)
+			case pressed("pause", key) && !p.ended:
+				if p.timer.Paused() {
+					p.timer.Resume()
+					bus.Publish(Event{Kind: SessionResumed, Tag: p.tag, LogPath: logPath})
+				} else {
+					p.timer.Pause()
+					bus.Publish(Event{Kind: SessionPaused, Tag: p.tag, LogPath: logPath})
+				}
+			case pressed("parallel_stop", key) && !p.ended:
+				end(p, false)
+			case pressed("parallel_add", key):
+				prompt.start()
+			}
+			redraw()
+		case <-resized:
+			resized = nil
+			w, h = screen.Size()
+			redraw()
+		case <-ticker.C:
+			all, completed := true, true
+			for _, p := range panes {
+				if !p.ended {
+					select {
+					case <-p.timer.Done():
+						end(p, true)
+						ringBell(1)
+					default:
+					}
+				}
+				all = all && p.ended
+				completed = completed && p.completed
+			}
+			if all {
+				return completed
+			}
+			redraw()
+		}
+	}
+}
+
+// drawParallel splits the screen into a pane per timer: side by side when
+// the digits fit, else stacked, else a line each.
+func drawParallel(panes []*pane, selected int, prompt *palette, w, h int) {
+	clear()
+	digits := render.Digits("00:00:00")
+	n := len(panes)
+	rows := h - 2
+	switch {
+	case w/n >= digits.Width()+2 && rows >= digits.Height()+4:
+		for i, p := range panes {
+			drawPane(p, i == selected, i*w/n, 0, w/n, rows)
+		}
+	case rows/n >= digits.Height()+3:
+		for i, p := range panes {
+			drawPane(p, i == selected, 0, i*rows/n, w, rows/n)
+		}
+	default:
+		for i, p := range panes {
+			line := fmt.Sprintf("%s  %s  %s", p.tag, render.Format(p.timer.Left()), paneState(p))
+			style := tcell.StyleDefault
+			if i == selected {
+				style = style.Reverse(true)
+			}
+			echoString(line, 1, i, style)
+		}
+	}
+	prompt.draw(w, h)
+	if !prompt.open && prompt.message == "" {
+		echoString(parallelHints, w/2-len(parallelHints)/2, h-1, tcell.StyleDefault.Dim(true))
+	}
+	if prompt.open {
+		echoString("<duration> or <tag>=<duration>", w-len("<duration> or <tag>=<duration>")-1, h-1, tcell.StyleDefault.Dim(true))
+	}
+	flush()
+}
+
+func drawPane(p *pane, selected bool, x, y, w, h int) {
+	left := render.Format(p.timer.Left())
+	text := render.Digits(left)
+	style := tcell.StyleDefault
+	if p.ended || p.timer.Paused() {
+		style = style.Dim(true)
+	}
+	top := y + h/2 - text.Height()/2
+	cx := x + w/2 - text.Width()/2
+	for _, s := range text {
+		echo(s, cx, top, style)
+		cx += s.Width()
+	}
+	title := style
+	if selected {
+		title = title.Reverse(true)
+	}
+	echoString(" "+p.tag+" ", x+w/2-(utf8.RuneCountInString(p.tag)+2)/2, top-2, title)
+	if state := paneState(p); state != "" {
+		echoString(state, x+w/2-len(state)/2, top+text.Height()+1, tcell.StyleDefault.Dim(true))
+	}
+}
+
+func paneState(p *pane) string {
+	switch {
+	case p.completed:
+		return "done"
+	case p.ended:
+		return "stopped"
+	case p.timer.Paused():
+		return "paused"
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+// Package parse reads the durations and times countdown accepts on its
+// command line and in its config file.
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalid is wrapped by every error Duration returns.
+var ErrInvalid = errors.New("invalid duration or time")
+
+// Duration reads duration arguments: several durations to add up, a
+// phrase, or a single duration or time of day.
+func Duration(args []string, literal bool, unit string) (time.Duration, error) {
+	return durationAt(args, literal, unit, time.Now())
+}
+
+// durationAt is Duration with times of day and phrases counted from now.
+func durationAt(args []string, literal bool, unit string, now time.Time) (time.Duration, error) {
+	if len(args) == 0 {
+		return 0, fmt.Errorf("%w: missing duration", ErrInvalid)
+	}
+	if d, ok := Sum(args); ok && len(args) > 1 {
+		return d, nil
+	}
+	if len(args) > 1 || strings.Contains(args[0], " ") {
+		d, err := Phrase(strings.Join(args, " "), now)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		return d, nil
+	}
+	d, err := argAt(args[0], literal, unit, now)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalid, args[0])
+	}
+	return d, nil
+}
+
+// Arg reads a single duration argument: a Go duration, a time of day,
+// H:MM:SS, or a bare number of units. H:MM is a time of day unless literal
+// is set or the argument has a "d:" prefix.
+func Arg(arg string, literal bool, unit string) (time.Duration, error) {
+	return argAt(arg, literal, unit, time.Now())
+}
+
+func argAt(arg string, literal bool, unit string, now time.Time) (time.Duration, error) {
+	if _, err := strconv.ParseFloat(arg, 64); err == nil && unit != "" {
+		return time.ParseDuration(arg + unit)
+	}
+	if strings.HasPrefix(arg, "d:") {
+		arg, literal = arg[2:], true
+	}
+	if literal || strings.Count(arg, ":") == 2 {
+		return Literal(arg)
+	}
+	d, err := timeOfDayAt(arg, now)
+	if err != nil {
+		d, err = time.ParseDuration(arg)
+	}
+	return d, err
+}
+
+// Sum adds up arguments like "1h 20m 30s". It only succeeds when every
+// argument is a Go duration, so times of day are never mixed in.
+func Sum(args []string) (time.Duration, bool) {
+	var total time.Duration
+	for _, arg := range args {
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return 0, false
+		}
+		total += d
+	}
+	return total, true
+}
+
+// Literal reads a Go duration or H:MM:SS, never a time of day.
+func Literal(arg string) (time.Duration, error) {
+	parts := strings.Split(arg, ":")
+	if len(parts) == 1 {
+		return time.ParseDuration(arg)
+	}
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid duration %q", arg)
+	}
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (i > 0 && n > 59) {
+			return 0, fmt.Errorf("invalid duration %q", arg)
+		}
+		d += time.Duration(n) * units[i]
+	}
+	return d, nil
+}
+
+// TimeOfDay returns the time left until the next 15:04 or 3:04PM.
+func TimeOfDay(date string) (time.Duration, error) {
+	return timeOfDayAt(date, time.Now())
+}
+
+func timeOfDayAt(date string, now time.Time) (time.Duration, error) {
+	targetTime, err := time.Parse(time.Kitchen, strings.ToUpper(date))
+	if err != nil {
+		targetTime, err = time.Parse("15:04", date)
+		if err != nil {
+			return time.Duration(0), err
+		}
+	}
+
+	originTime := time.Date(0, time.January, 1, now.Hour(), now.Minute(), now.Second(), 0, time.UTC)
+
+	// The time of day has already passed, so target tomorrow.
+	if targetTime.Before(originTime) {
+		targetTime = targetTime.AddDate(0, 0, 1)
+	}
+
+	duration := targetTime.Sub(originTime)
+
+	return duration, err
+}
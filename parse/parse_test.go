@@ -0,0 +1,123 @@
+package parse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestArg(t *testing.T) {
+	tests := []struct {
+		arg     string
+		literal bool
+		unit    string
+		want    time.Duration
+	}{
+		{"25m", false, "", 25 * time.Minute},
+		{"1h30m", false, "", 90 * time.Minute},
+		{"25", false, "m", 25 * time.Minute},
+		{"1.5", false, "h", 90 * time.Minute},
+		{"1:30:00", false, "", 90 * time.Minute},
+		{"1:30", true, "", time.Hour + 30*time.Minute},
+		{"d:0:45", false, "", 45 * time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := Arg(tt.arg, tt.literal, tt.unit)
+		if err != nil || got != tt.want {
+			t.Errorf("Arg(%q, %v, %q) = %v, %v, want %v", tt.arg, tt.literal, tt.unit, got, err, tt.want)
+		}
+	}
+	for _, arg := range []string{"25", "soon", "1:30:99"} {
+		if _, err := Arg(arg, false, ""); err == nil {
+			t.Errorf("Arg(%q): want an error", arg)
+		}
+	}
+}
+
+func TestArgTimeOfDay(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want time.Duration
+	}{
+		{"11:00", 30 * time.Minute},
+		{"10:30", 0},
+		{"9:00", 22*time.Hour + 30*time.Minute},
+		{"9:00am", 22*time.Hour + 30*time.Minute},
+		{"9:00PM", 10*time.Hour + 30*time.Minute},
+		{"23:59", 13*time.Hour + 29*time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := argAt(tt.arg, false, "", now)
+		if err != nil || got != tt.want {
+			t.Errorf("Arg(%q) at 10:30 = %v, %v, want %v", tt.arg, got, err, tt.want)
+		}
+	}
+}
+
+// TestDurationPhrases reads the phrases as they come on the command line,
+// as words or quoted.
+func TestDurationPhrases(t *testing.T) {
+	tests := []struct {
+		args []string
+		want time.Duration
+	}{
+		{[]string{"until", "17:30"}, 7 * time.Hour},
+		{[]string{"until 17:30"}, 7 * time.Hour},
+		{[]string{"until", "friday", "9am"}, 46*time.Hour + 30*time.Minute},
+		{[]string{"in", "90", "minutes"}, 90 * time.Minute},
+		{[]string{"in 90 minutes"}, 90 * time.Minute},
+		{[]string{"1h", "30m"}, 90 * time.Minute},
+		{[]string{"17:30"}, 7 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := durationAt(tt.args, false, "", now)
+		if err != nil || got != tt.want {
+			t.Errorf("Duration(%q) = %v, %v, want %v", tt.args, got, err, tt.want)
+		}
+	}
+	for _, args := range [][]string{{"until", "yesterday"}, {"in", "a", "while"}, {"until 25:00"}} {
+		if _, err := durationAt(args, false, "", now); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Duration(%q) = %v, want ErrInvalid", args, err)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want time.Duration
+	}{
+		{"10s", 10 * time.Second},
+		{"1:30", time.Hour + 30*time.Minute},
+		{"0:05:30", 5*time.Minute + 30*time.Second},
+		{"100:00:00", 100 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := Literal(tt.arg)
+		if err != nil || got != tt.want {
+			t.Errorf("Literal(%q) = %v, %v, want %v", tt.arg, got, err, tt.want)
+		}
+	}
+	for _, arg := range []string{"1:60", "1:2:3:4", "-1:00", "a:b", "9am"} {
+		if _, err := Literal(arg); err == nil {
+			t.Errorf("Literal(%q): want an error", arg)
+		}
+	}
+}
+
+func TestSum(t *testing.T) {
+	if d, ok := Sum([]string{"1h", "20m", "30s"}); !ok || d != time.Hour+20*time.Minute+30*time.Second {
+		t.Errorf("Sum(1h 20m 30s) = %v, %v", d, ok)
+	}
+	if _, ok := Sum([]string{"1h", "9:00"}); ok {
+		t.Errorf("Sum(1h 9:00) succeeded, want a time of day never mixed in")
+	}
+}
+
+func TestDurationInvalid(t *testing.T) {
+	for _, args := range [][]string{nil, {"soon"}, {"in", "five", "minutes"}} {
+		if _, err := Duration(args, false, ""); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Duration(%q) = %v, want ErrInvalid", args, err)
+		}
+	}
+}
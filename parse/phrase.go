@@ -0,0 +1,163 @@
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Phrases understood by Phrase:
+//
+//	phrase := "in" amount | ("until" | "till") target
+//	amount := { number unit | "a" unit | "an" unit | go-duration } ["and"]
+//	target := [day] [clock] | clock day
+//	day    := "today" | "tomorrow" | weekday
+//	clock  := "15:04" | "3:04pm" | "3pm" | "3" "pm" | "noon" | "midnight"
+
+var phraseUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+}
+
+// Unit reads a unit of time like "s", "min" or "hours".
+func Unit(word string) (time.Duration, bool) {
+	d, ok := phraseUnits[word]
+	return d, ok
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// Phrase turns "in 90 minutes" or "until friday 9am" into the
+// duration from now.
+func Phrase(phrase string, now time.Time) (time.Duration, error) {
+	words := strings.Fields(strings.ToLower(strings.ReplaceAll(phrase, ",", " ")))
+	if len(words) < 2 {
+		return 0, fmt.Errorf("expected \"in <duration>\" or \"until <time>\"")
+	}
+	switch words[0] {
+	case "in":
+		return parseAmount(words[1:])
+	case "until", "till":
+		target, err := parseTarget(words[1:], now)
+		if err != nil {
+			return 0, err
+		}
+		if !target.After(now) {
+			return 0, fmt.Errorf("%v is in the past", target.Format("Mon 15:04"))
+		}
+		return target.Sub(now).Round(time.Second), nil
+	}
+	return 0, fmt.Errorf("unknown phrase %q", words[0])
+}
+
+func parseAmount(words []string) (time.Duration, error) {
+	var total time.Duration
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if word == "and" {
+			continue
+		}
+		if d, err := time.ParseDuration(word); err == nil {
+			total += d
+			continue
+		}
+
+		var n float64
+		if word == "a" || word == "an" {
+			n = 1
+		} else {
+			var err error
+			n, err = strconv.ParseFloat(word, 64)
+			if err != nil {
+				return 0, fmt.Errorf("expected a number, got %q", word)
+			}
+		}
+		if i+1 >= len(words) {
+			return 0, fmt.Errorf("missing unit after %q", word)
+		}
+		i++
+		unit, ok := phraseUnits[words[i]]
+		if !ok {
+			return 0, fmt.Errorf("unknown unit %q", words[i])
+		}
+		total += time.Duration(n * float64(unit))
+	}
+	if total <= 0 {
+		return 0, fmt.Errorf("duration must be positive")
+	}
+	return total, nil
+}
+
+func parseTarget(words []string, now time.Time) (time.Time, error) {
+	days, hasDay, weekday := 0, false, false
+	hour, minute, hasClock := 0, 0, false
+
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if word == "at" || word == "on" {
+			continue
+		}
+		switch word {
+		case "today":
+			hasDay = true
+			continue
+		case "tomorrow":
+			days, hasDay = 1, true
+			continue
+		}
+		if wd, ok := weekdays[word]; ok {
+			days, hasDay, weekday = (int(wd)-int(now.Weekday())+7)%7, true, true
+			continue
+		}
+		if i+1 < len(words) && (words[i+1] == "am" || words[i+1] == "pm") {
+			word += words[i+1]
+			i++
+		}
+		h, m, err := Clock(word)
+		if err != nil {
+			return time.Time{}, err
+		}
+		hour, minute, hasClock = h, m, true
+	}
+	if !hasDay && !hasClock {
+		return time.Time{}, fmt.Errorf("missing time or day")
+	}
+
+	target := time.Date(now.Year(), now.Month(), now.Day()+days, hour, minute, 0, 0, now.Location())
+	if !target.After(now) {
+		switch {
+		case weekday:
+			target = target.AddDate(0, 0, 7)
+		case !hasDay:
+			target = target.AddDate(0, 0, 1)
+		}
+	}
+	return target, nil
+}
+
+// Clock reads a time of day like "15:04", "3:04pm", "3pm", "noon" or
+// "midnight".
+func Clock(word string) (int, int, error) {
+	switch word {
+	case "noon":
+		return 12, 0, nil
+	case "midnight":
+		return 0, 0, nil
+	}
+	for _, layout := range []string{"15:04", "3:04pm", "3pm"} {
+		if t, err := time.Parse(layout, word); err == nil {
+			return t.Hour(), t.Minute(), nil
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid time %q", word)
+}
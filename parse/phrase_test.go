@@ -0,0 +1,140 @@
+package parse
+
+import (
+	"testing"
+	"time"
+)
+
+// now is a Wednesday morning.
+var now = time.Date(2024, time.January, 3, 10, 30, 0, 0, time.UTC)
+
+func TestPhrase(t *testing.T) {
+	tests := []struct {
+		phrase string
+		want   time.Duration
+	}{
+		{"in 90 minutes", 90 * time.Minute},
+		{"in an hour and 30 mins", 90 * time.Minute},
+		{"until noon", 90 * time.Minute},
+		{"till 11:00", 30 * time.Minute},
+		{"until 9am", 22*time.Hour + 30*time.Minute},
+		{"until tomorrow 9am", 22*time.Hour + 30*time.Minute},
+		{"until friday 9am", 46*time.Hour + 30*time.Minute},
+		{"until 9 am friday", 46*time.Hour + 30*time.Minute},
+		{"until monday", 4*24*time.Hour + 13*time.Hour + 30*time.Minute},
+		{"until wednesday 9am", 6*24*time.Hour + 22*time.Hour + 30*time.Minute},
+		{"until wednesday 11am", 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := Phrase(tt.phrase, now)
+		if err != nil {
+			t.Errorf("Phrase(%q): %v", tt.phrase, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Phrase(%q) = %v, want %v", tt.phrase, got, tt.want)
+		}
+	}
+}
+
+func TestPhraseErrors(t *testing.T) {
+	for _, phrase := range []string{
+		"in",
+		"soon please",
+		"in 5 parsecs",
+		"in 0 minutes",
+		"until today 9am",
+		"until today",
+		"until 25:00",
+	} {
+		if d, err := Phrase(phrase, now); err == nil {
+			t.Errorf("Phrase(%q) = %v, want an error", phrase, d)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  time.Duration
+	}{
+		{[]string{"5", "min"}, 5 * time.Minute},
+		{[]string{"1.5", "hours"}, 90 * time.Minute},
+		{[]string{"a", "minute", "and", "30", "seconds"}, 90 * time.Second},
+		{[]string{"1h", "and", "10m"}, 70 * time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.words)
+		if err != nil || got != tt.want {
+			t.Errorf("parseAmount(%q) = %v, %v, want %v", tt.words, got, err, tt.want)
+		}
+	}
+	for _, words := range [][]string{{"5"}, {"five", "min"}, {"5", "furlongs"}, {"and"}} {
+		if _, err := parseAmount(words); err == nil {
+			t.Errorf("parseAmount(%q): want an error", words)
+		}
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	day := func(d, h, m int) time.Time { return time.Date(2024, time.January, d, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		words []string
+		want  time.Time
+	}{
+		// Later today, or tomorrow once the time has passed.
+		{[]string{"noon"}, day(3, 12, 0)},
+		{[]string{"10:00"}, day(4, 10, 0)},
+		{[]string{"10:30"}, day(4, 10, 30)},
+		// Today is not moved, so the past is left for Phrase to reject.
+		{[]string{"today", "9am"}, day(3, 9, 0)},
+		{[]string{"tomorrow"}, day(4, 0, 0)},
+		{[]string{"on", "friday", "at", "3pm"}, day(5, 15, 0)},
+		// Weekdays wrap around to next week.
+		{[]string{"tuesday"}, day(9, 0, 0)},
+		{[]string{"wed", "9am"}, day(10, 9, 0)},
+		{[]string{"wed", "11am"}, day(3, 11, 0)},
+		{[]string{"sunday", "midnight"}, day(7, 0, 0)},
+	}
+	for _, tt := range tests {
+		got, err := parseTarget(tt.words, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseTarget(%q) = %v, %v, want %v", tt.words, got, err, tt.want)
+		}
+	}
+	for _, words := range [][]string{{"at"}, {"someday"}, {"friday", "13:61"}} {
+		if _, err := parseTarget(words, now); err == nil {
+			t.Errorf("parseTarget(%q): want an error", words)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	tests := []struct {
+		word         string
+		hour, minute int
+	}{
+		{"15:04", 15, 4},
+		{"00:00", 0, 0},
+		{"3:04pm", 15, 4},
+		{"3pm", 15, 0},
+		{"12am", 0, 0},
+		{"12:30am", 0, 30},
+		{"12pm", 12, 0},
+		{"12:59pm", 12, 59},
+		{"11:59pm", 23, 59},
+		{"noon", 12, 0},
+		{"midnight", 0, 0},
+	}
+	for _, tt := range tests {
+		h, m, err := Clock(tt.word)
+		if err != nil || h != tt.hour || m != tt.minute {
+			t.Errorf("Clock(%q) = %d, %d, %v, want %d, %d", tt.word, h, m, err, tt.hour, tt.minute)
+		}
+	}
+	for _, word := range []string{"24:00", "13pm", "3:60pm", "3", "noonish"} {
+		if _, _, err := Clock(word); err == nil {
+			t.Errorf("Clock(%q): want an error", word)
+		}
+	}
+}
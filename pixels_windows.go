@@ -0,0 +1,7 @@
+package main
+
+// terminalPixels reports the size of the terminal window in pixels, which
+// the Windows console never does.
+func terminalPixels() (int, int, bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// pluginPrefix names the executables countdown runs as plugins, like
+// git runs git-<name>.
+const pluginPrefix = "countdown-"
+
+// pluginBuffer is how many events may wait for a slow plugin before the
+// newest are dropped. countdown only waits for a plugin to take the end of
+// a session, for at most pluginTimeout, as it may exit right after.
+const (
+	pluginBuffer  = 64
+	pluginTimeout = time.Second
+)
+
+// plugin is a running plugin's stdin: events go in, and synced answers
+// once those before were written.
+type plugin struct {
+	events chan pluginEvent
+	synced chan struct{}
+}
+
+// sync waits until the plugin took the events sent so far.
+func (p plugin) sync() {
+	timeout := time.After(pluginTimeout)
+	select {
+	case p.events <- pluginEvent{}:
+	case <-timeout:
+		return
+	}
+	select {
+	case <-p.synced:
+	case <-timeout:
+	}
+}
+
+var pluginName = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// pluginEvent is the line a plugin reads on stdin for every event.
+type pluginEvent struct {
+	Event     string    `json:"event"`
+	Time      time.Time `json:"time"`
+	Tag       string    `json:"tag"`
+	Notes     string    `json:"notes,omitempty"`
+	Left      float64   `json:"left_seconds"`
+	Total     float64   `json:"total_seconds"`
+	Completed bool      `json:"completed,omitempty"`
+	// Outcome is the notes of the end, such as the cost with -rate.
+	Outcome string `json:"outcome,omitempty"`
+	Profile string `json:"profile,omitempty"`
+}
+
+var pluginEventNames = map[EventKind]string{
+	SessionStarted: "start",
+	SessionPaused:  "pause",
+	SessionResumed: "resume",
+	SessionChanged: "change",
+	SessionEnded:   "end",
+	Tick:           "tick",
+	SessionLap:     "lap",
+}
+
+// pluginCommand runs "countdown <name> [args]" as countdown-<name> when
+// such an executable is in the PATH, and reports whether it did.
+func pluginCommand(name string, args []string) bool {
+	if !pluginName.MatchString(name) {
+		return false
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return false
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			os.Exit(exit.ExitCode())
+		}
+		stderr("error: %v\n", err)
+		os.Exit(1)
+	}
+	return true
+}
+
+// startPlugin starts countdown-<name> for the events of this run.
+func startPlugin(name string) (plugin, error) {
+	if !pluginName.MatchString(name) {
+		return plugin{}, fmt.Errorf("invalid plugin name %q", name)
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return plugin{}, fmt.Errorf("plugin %s: %v", name, err)
+	}
+	cmd := exec.Command(path)
+	// The plugin shares the terminal with the TUI, so it gets no output.
+	cmd.Stdout, cmd.Stderr = nil, nil
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return plugin{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return plugin{}, fmt.Errorf("plugin %s: %v", name, err)
+	}
+	p := plugin{events: make(chan pluginEvent, pluginBuffer), synced: make(chan struct{}, 1)}
+	go func() {
+		enc := json.NewEncoder(stdin)
+		failed := false
+		for e := range p.events {
+			switch {
+			case e.Event == "":
+				select {
+				case p.synced <- struct{}{}:
+				default:
+				}
+			case !failed:
+				// A plugin that quit is skipped from then on.
+				failed = enc.Encode(e) != nil
+			}
+		}
+	}()
+	go cmd.Wait()
+	return p, nil
+}
+
+// pluginEvents returns the subscriber that hands every event to the
+// plugins as a JSON line. The plugins start with the first event and read
+// until their stdin closes, when countdown exits; one that does not start
+// is skipped.
+func pluginEvents(names []string) func(Event) {
+	var plugins []plugin
+	started := false
+	var notes string
+	return func(e Event) {
+		name, ok := pluginEventNames[e.Kind]
+		if !ok {
+			return
+		}
+		if !started {
+			started = true
+			for _, n := range names {
+				if p, err := startPlugin(n); err == nil {
+					plugins = append(plugins, p)
+				}
+			}
+		}
+		if e.Kind == SessionStarted {
+			notes = e.Notes
+		}
+		pe := pluginEvent{
+			Event: name, Time: e.Time, Tag: e.Tag, Notes: notes,
+			Left: e.Left.Seconds(), Total: e.Total.Seconds(), Completed: e.Completed, Profile: profile,
+		}
+		if e.Kind == SessionLap {
+			pe.Notes = e.Notes
+		}
+		if e.Kind != SessionEnded {
+			for _, p := range plugins {
+				select {
+				case p.events <- pe:
+				default:
+				}
+			}
+			return
+		}
+		pe.Outcome = e.Notes
+		for _, p := range plugins {
+			select {
+			case p.events <- pe:
+				p.sync()
+			case <-time.After(pluginTimeout):
+			}
+		}
+	}
+}
+
+// findPlugins checks that every plugin is installed.
+func findPlugins(names []string) error {
+	for _, name := range names {
+		if !pluginName.MatchString(name) {
+			return fmt.Errorf("invalid plugin name %q", name)
+		}
+		if _, err := exec.LookPath(pluginPrefix + name); err != nil {
+			return fmt.Errorf("plugin %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// parsePlugins splits the -plugins list.
+func parsePlugins(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/antonmedv/countdown/parse"
+)
+
+// PomodoroConfig is the [pomodoro] section of the config file; unset
+// fields keep the classic 25m/5m with a 15m break every 4 cycles.
+//
+//	[pomodoro]
+//	work = "50m"
+//	break = "10m"
+//	long_break = "30m"
+//	cycles = 3
+type PomodoroConfig struct {
+	Work      string `toml:"work,omitempty"`
+	Break     string `toml:"break,omitempty"`
+	LongBreak string `toml:"long_break,omitempty"`
+	Cycles    int    `toml:"cycles,omitempty"`
+}
+
+type pomodoroPlan struct {
+	work, short, long time.Duration
+	cycles            int
+}
+
+func parsePomodoro(c PomodoroConfig) (*pomodoroPlan, error) {
+	p := &pomodoroPlan{work: 25 * time.Minute, short: 5 * time.Minute, long: 15 * time.Minute, cycles: 4}
+	for _, f := range []struct {
+		name  string
+		value string
+		d     *time.Duration
+	}{
+		{"work", c.Work, &p.work},
+		{"break", c.Break, &p.short},
+		{"long_break", c.LongBreak, &p.long},
+	} {
+		if f.value == "" {
+			continue
+		}
+		d, err := parse.Literal(f.value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("pomodoro: invalid %s %q", f.name, f.value)
+		}
+		*f.d = d
+	}
+	if c.Cycles < 0 {
+		return nil, fmt.Errorf("pomodoro: cycles must be positive")
+	}
+	if c.Cycles > 0 {
+		p.cycles = c.Cycles
+	}
+	return p, nil
+}
+
+// pomodoro alternates work and breaks until it is stopped with Esc, with a
+// long break after every p.cycles work phases. Each phase is logged with
+// its name as notes. It never completes: stopping it aborts the phase that
+// runs, so it reports false.
+func pomodoro(ctx context.Context, p *pomodoroPlan, tag string, logPath string) bool {
+	defer func() {
+		caption = ""
+		isBreak = false
+	}()
+
+	for i := 1; ; i++ {
+		cycle := (i-1)%p.cycles + 1
+		caption = fmt.Sprintf("Work %d/%d", cycle, p.cycles)
+		isBreak = false
+		if !countdown(ctx, p.work, false, tag, fmt.Sprintf("work %d", i), logPath) {
+			return false
+		}
+		ringBell(1)
+
+		rest, name := p.short, "break"
+		if cycle == p.cycles {
+			rest, name = p.long, "long break"
+		}
+		isBreak = dimBreaks
+		if !countdown(ctx, rest, false, tag, name, logPath) {
+			return false
+		}
+		ringBell(1)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// applyPreset runs a preset from the config file, a command line run by
+// name with "countdown preset <name>" or "countdown @<name>":
+//
+//	[presets]
+//	pomodoro = "25m"
+//	standup = "15m -t Meetings -n 'Daily standup' -sound ~/ding.wav"
+//
+// The flags are parsed again with the preset in front of the command
+// line, so the command line overrides it. The preset's duration is used
+// when the command line has none.
+func applyPreset(name string, presets map[string]string, commandLine []string) error {
+	preset, ok := presets[name]
+	if !ok {
+		return fmt.Errorf("no preset %q in the config", name)
+	}
+	words, err := splitWords(preset)
+	if err != nil {
+		return fmt.Errorf("preset %s: %v", name, err)
+	}
+	flags, positional := splitFlags(words)
+	args := append(flags, commandLine...)
+	if flag.NArg() == 0 {
+		args = append(args, positional...)
+	}
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return fmt.Errorf("preset %s: %v", name, err)
+	}
+	return nil
+}
+
+// splitFlags separates the flags (with their values) from the other
+// arguments, as presets may put the duration first.
+func splitFlags(words []string) (flags, positional []string) {
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if len(w) < 2 || w[0] != '-' {
+			positional = append(positional, w)
+			continue
+		}
+		flags = append(flags, w)
+		name := strings.TrimLeft(w, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		f := flag.Lookup(name)
+		if f == nil {
+			continue
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			continue
+		}
+		if i+1 < len(words) {
+			i++
+			flags = append(flags, words[i])
+		}
+	}
+	return flags, positional
+}
+
+// splitWords splits s at spaces like a shell does, keeping quoted parts
+// together, and expands a leading ~/.
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, expandHome(word.String()))
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c", quote)
+	}
+	if inWord {
+		words = append(words, expandHome(word.String()))
+	}
+	return words, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"runtime/pprof"
+)
+
+var profileFile *os.File
+
+// startProfile writes a CPU profile to path until stopProfile, for
+// checking that an idle or paused countdown stays asleep.
+func startProfile(path string) {
+	f, err := os.Create(path)
+	if err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(2)
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(2)
+	}
+	profileFile = f
+}
+
+func stopProfile() {
+	if profileFile == nil {
+		return
+	}
+	pprof.StopCPUProfile()
+	profileFile.Close()
+	profileFile = nil
+}
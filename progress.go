@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// progressMargin is the room left either side of the progress bar.
+const progressMargin = 4
+
+// progressBar is -progress, toggled with its key: a bar under the digits
+// filling up as time elapses, with the percentage after it.
+var progressBar bool
+
+// drawProgress draws the bar on row y across the width of the terminal,
+// which is redrawn on resize.
+func drawProgress(fraction float64, style tcell.Style, w, y int) {
+	if fraction < 0 {
+		fraction = 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	percent := fmt.Sprintf(" %3d%%", int(100*fraction))
+	width := w - 2*progressMargin - len(percent)
+	if width < 1 {
+		return
+	}
+	filled := int(fraction * float64(width))
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+	echoString(bar, progressMargin, y, style)
+	echoString(percent, progressMargin+width, y, tcell.StyleDefault.Foreground(theme.text))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/antonmedv/countdown/parse"
+)
+
+var ambiguousArg = regexp.MustCompile(`^\d{1,2}:\d{2}$`)
+
+// stdin reads the answers to every question asked on standard input. A
+// reader of its own would buffer lines that later questions need, when
+// the answers are piped.
+var stdin = bufio.NewReader(os.Stdin)
+
+func isInteractive() bool {
+	return isTerminal(os.Stdin)
+}
+
+// askDuration asks whether an H:MM argument is meant as a duration rather
+// than a time of day.
+func askDuration(arg string) bool {
+	asTime, err := parse.TimeOfDay(arg)
+	if err != nil {
+		return true
+	}
+	asDuration, err := parse.Literal(arg)
+	if err != nil {
+		return false
+	}
+	end := time.Now().Add(asTime).Round(time.Minute)
+
+	stderr("%q could be the time of day %s (in %v) or a duration of %v.\n", arg, end.Format("Mon 15:04"), asTime.Round(time.Minute), asDuration)
+	stderr("Use it as a [t]ime or a [d]uration? [t] ")
+	answer, _ := stdin.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "d", "duration":
+		return true
+	case "", "t", "time":
+		return false
+	}
+	stderr("error: expected t or d\n")
+	os.Exit(2)
+	return false
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/antonmedv/countdown/parse"
+)
+
+// meterLimit caps an open-ended -rate session.
+const meterLimit = 24 * time.Hour
+
+// Rate is what -rate charges: amount per period, e.g. "$4.50/h".
+type Rate struct {
+	symbol string
+	amount float64
+	per    time.Duration
+}
+
+var rate *Rate
+
+// parseRate reads "[symbol]amount/[n]unit", e.g. "4.50/h", "$12/30m" or
+// "€80/hour".
+func parseRate(s string) (*Rate, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid rate %q, expected e.g. 4.50/h", s)
+	}
+	price := strings.TrimSpace(parts[0])
+	i := strings.IndexFunc(price, func(r rune) bool { return unicode.IsDigit(r) || r == '.' })
+	if i < 0 {
+		return nil, fmt.Errorf("invalid rate %q, missing amount", s)
+	}
+	amount, err := strconv.ParseFloat(price[i:], 64)
+	if err != nil || amount < 0 {
+		return nil, fmt.Errorf("invalid rate %q, bad amount", s)
+	}
+
+	period := strings.TrimSpace(parts[1])
+	j := strings.IndexFunc(period, func(r rune) bool { return !unicode.IsDigit(r) })
+	if j < 0 {
+		return nil, fmt.Errorf("invalid rate %q, missing unit", s)
+	}
+	n := 1
+	if j > 0 {
+		n, _ = strconv.Atoi(period[:j])
+	}
+	unit, ok := parse.Unit(period[j:])
+	if !ok || n <= 0 {
+		return nil, fmt.Errorf("invalid rate %q, unknown period %q", s, period)
+	}
+	return &Rate{symbol: price[:i], amount: amount, per: time.Duration(n) * unit}, nil
+}
+
+func (r *Rate) cost(elapsed time.Duration) string {
+	return fmt.Sprintf("%s%.2f", r.symbol, r.amount*float64(elapsed)/float64(r.per))
+}
+
+// rateNote is the log note for a metered session, empty without -rate.
+func rateNote(elapsed time.Duration) string {
+	if rate == nil {
+		return ""
+	}
+	return "cost " + rate.cost(elapsed)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	remindChildEnv = "COUNTDOWN_REMIND_CHILD"
+	maxNags        = 12
+)
+
+// remind starts a detached reminder: no TUI, just a desktop notification
+// when the time is up, repeated every nag interval (at most maxNags times)
+// when nag is set.
+func remind(d, nag time.Duration, message string) {
+	if message == "" {
+		message = "Time is up"
+	}
+	if os.Getenv(remindChildEnv) != "" {
+		runReminder(d, nag, message)
+		return
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(2)
+	}
+	cmd := exec.Command(exe, "-remind", d.String(), "-nag", nag.String(), "--", message)
+	cmd.Env = append(os.Environ(), remindChildEnv+"=1")
+	cmd.SysProcAttr = detachAttr()
+	if err := cmd.Start(); err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(2)
+	}
+	at := time.Now().Add(d).Format("15:04:05")
+	fmt.Printf("Reminder %q at %s (pid %d)\n", message, at, cmd.Process.Pid)
+}
+
+func runReminder(d, nag time.Duration, message string) {
+	time.Sleep(d)
+	title := "countdown: " + strings.SplitN(message, "\n", 2)[0]
+	body := fmt.Sprintf("%s (set %s ago)", message, d)
+	if desktopNotify(title, body) != nil || nag <= 0 {
+		return
+	}
+	for i := 0; i < maxNags; i++ {
+		time.Sleep(nag)
+		if desktopNotify(title, body) != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package render
+
+import (
+	"image"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Big renders any text with a small bitmap font, two pixels per cell
+// using half blocks, for labels the digit font can't draw.
+func Big(str string) Symbol {
+	face := basicfont.Face7x13
+	width := font.MeasureString(face, str).Ceil()
+	height := face.Height
+	img := image.NewAlpha(image.Rect(0, 0, width, height+height%2))
+	d := &font.Drawer{Dst: img, Src: image.Opaque, Face: face, Dot: fixed.P(0, face.Ascent)}
+	d.DrawString(str)
+
+	on := func(x, y int) bool { return img.AlphaAt(x, y).A > 0 }
+	lines := make(Symbol, 0, (height+1)/2)
+	for y := 0; y < img.Bounds().Dy(); y += 2 {
+		var line strings.Builder
+		for x := 0; x < width; x++ {
+			switch top, bottom := on(x, y), on(x, y+1); {
+			case top && bottom:
+				line.WriteRune('█')
+			case top:
+				line.WriteRune('▀')
+			case bottom:
+				line.WriteRune('▄')
+			default:
+				line.WriteRune(' ')
+			}
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
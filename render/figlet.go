@@ -0,0 +1,72 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// figletFirst is the first character of a FIGlet font; the characters up
+// to '~' follow in order.
+const figletFirst = ' '
+
+// ParseFiglet reads the digits of a FIGlet font (.flf), such as those
+// that come with figlet or toilet's plain fonts.
+func ParseFiglet(r io.Reader) (Font, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("figlet: empty font")
+	}
+	header := strings.Fields(scanner.Text())
+	if len(header) < 6 || !strings.HasPrefix(header[0], "flf2a") || len(header[0]) == len("flf2a") {
+		return nil, fmt.Errorf("figlet: not a FIGlet font")
+	}
+	hardblank, _ := utf8.DecodeRuneInString(header[0][len("flf2a"):])
+	height, err := strconv.Atoi(header[1])
+	if err != nil || height < 1 {
+		return nil, fmt.Errorf("figlet: invalid height %q", header[1])
+	}
+	comments, err := strconv.Atoi(header[5])
+	if err != nil || comments < 0 {
+		return nil, fmt.Errorf("figlet: invalid comment lines %q", header[5])
+	}
+	for i := 0; i < comments; i++ {
+		if !scanner.Scan() {
+			return nil, fmt.Errorf("figlet: font ends in its comments")
+		}
+	}
+
+	font := make(Font)
+	for c := figletFirst; c <= ':'; c++ {
+		glyph := make(Symbol, height)
+		width := 0
+		for i := range glyph {
+			if !scanner.Scan() {
+				return nil, fmt.Errorf("figlet: font ends at %q", c)
+			}
+			line := strings.TrimRight(scanner.Text(), " \r")
+			// Each line ends with an end mark, the last one with two.
+			if end, size := utf8.DecodeLastRuneInString(line); size > 0 {
+				line = strings.TrimRight(line, string(end))
+			}
+			glyph[i] = strings.ReplaceAll(line, string(hardblank), " ")
+			if n := utf8.RuneCountInString(glyph[i]); n > width {
+				width = n
+			}
+		}
+		if c != ':' && c != '.' && (c < '0' || c > '9') {
+			continue
+		}
+		for i, line := range glyph {
+			glyph[i] = line + strings.Repeat(" ", width-utf8.RuneCountInString(line))
+		}
+		font[c] = glyph
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return font, nil
+}
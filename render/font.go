@@ -0,0 +1,250 @@
+// Package render turns times and labels into the block characters
+// countdown draws on the terminal.
+package render
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Symbol is one glyph, or a label, as lines of equal width.
+type Symbol []string
+
+func (s Symbol) Width() int {
+	return utf8.RuneCountInString(s[0])
+}
+
+func (s Symbol) Height() int {
+	return len(s)
+}
+
+// Text is a row of symbols.
+type Text []Symbol
+
+func (t Text) Width() int {
+	w := 0
+	for _, s := range t {
+		w += s.Width()
+	}
+	return w
+}
+
+func (t Text) Height() int {
+	return len(t[0])
+}
+
+// Font is a glyph for each of the digits, ':' and '.', all of one height.
+type Font map[rune]Symbol
+
+// Fonts are the built-in digit fonts, by name.
+var Fonts = map[string]Font{
+	"standard": defaultFont,
+	"slim":     slimFont,
+	"block":    blockFont,
+}
+
+// digitFont is the font Digits draws with.
+var digitFont = defaultFont
+
+// UseFont makes Digits draw with f.
+func UseFont(f Font) {
+	digitFont = f
+}
+
+// Digits renders str with the digit font, skipping the runes it has no
+// glyph for.
+func Digits(str string) Text {
+	symbols := make(Text, 0)
+	for _, r := range str {
+		if s, ok := digitFont[r]; ok {
+			symbols = append(symbols, s)
+		}
+	}
+	return symbols
+}
+
+// Scale enlarges a digit drawn in blocks n times, dropping the shadow of
+// the standard font, which doesn't scale well. Digits drawn in lines, as
+// slim and FIGlet fonts are, don't scale at all and are kept as they are.
+func Scale(s Symbol, n int) Symbol {
+	for _, line := range s {
+		for _, r := range line {
+			if r != ' ' && !strings.ContainsRune(shadow, r) && (r < '▀' || r > '▟') {
+				return s
+			}
+		}
+	}
+	scaled := make(Symbol, 0, len(s)*n)
+	for _, line := range s {
+		var b strings.Builder
+		for _, r := range line {
+			c := string(r)
+			if strings.ContainsRune(shadow, r) {
+				c = " "
+			}
+			b.WriteString(strings.Repeat(c, n))
+		}
+		for i := 0; i < n; i++ {
+			scaled = append(scaled, b.String())
+		}
+	}
+	return scaled
+}
+
+// Format prints d as MM:SS, or HH:MM:SS from an hour up.
+func Format(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+
+	if h < 1 {
+		return fmt.Sprintf("%02d:%02d", m, s)
+	}
+	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
+}
+
+// shadow is what the standard font draws its shadow with.
+const shadow = "╗╔╝╚║═"
+
+var defaultFont = Font{
+	':': {
+		"   ",
+		"██╗",
+		"╚═╝",
+		"██╗",
+		"╚═╝",
+		"   ",
+	},
+	'.': {
+		"   ",
+		"   ",
+		"   ",
+		"   ",
+		"██╗",
+		"╚═╝",
+	},
+	'0': {
+		" ██████╗ ",
+		"██╔═████╗",
+		"██║██╔██║",
+		"████╔╝██║",
+		"╚██████╔╝",
+		" ╚═════╝ ",
+	},
+	'1': {
+		" ██╗",
+		"███║",
+		"╚██║",
+		" ██║",
+		" ██║",
+		" ╚═╝",
+	},
+	'2': {
+		"██████╗ ",
+		"╚════██╗",
+		" █████╔╝",
+		"██╔═══╝ ",
+		"███████╗",
+		"╚══════╝",
+	},
+	'3': {
+		"██████╗ ",
+		"╚════██╗",
+		" █████╔╝",
+		" ╚═══██╗",
+		"██████╔╝",
+		"╚═════╝ ",
+	},
+	'4': {
+		"██╗  ██╗",
+		"██║  ██║",
+		"███████║",
+		"╚════██║",
+		"     ██║",
+		"     ╚═╝",
+	},
+	'5': {
+		"███████╗",
+		"██╔════╝",
+		"███████╗",
+		"╚════██║",
+		"███████║",
+		"╚══════╝",
+	},
+	'6': {
+		" ██████╗ ",
+		"██╔════╝ ",
+		"███████╗ ",
+		"██╔═══██╗",
+		"╚██████╔╝",
+		" ╚═════╝ ",
+	},
+	'7': {
+		"███████╗",
+		"╚════██║",
+		"    ██╔╝",
+		"   ██╔╝ ",
+		"   ██║  ",
+		"   ╚═╝  ",
+	},
+	'8': {
+		" █████╗ ",
+		"██╔══██╗",
+		"╚█████╔╝",
+		"██╔══██╗",
+		"╚█████╔╝",
+		" ╚════╝ ",
+	},
+	'9': {
+		" █████╗ ",
+		"██╔══██╗",
+		"╚██████║",
+		" ╚═══██║",
+		" █████╔╝",
+		" ╚════╝ ",
+	},
+}
+
+// slimFont draws the digits with thin lines, for small terminals.
+var slimFont = Font{
+	':': {"  ", ": ", "  "},
+	'.': {"  ", "  ", ". "},
+	'0': {"┌─┐ ", "│ │ ", "└─┘ "},
+	'1': {"  ╷ ", "  │ ", "  ╵ "},
+	'2': {"╶─┐ ", "┌─┘ ", "└─╴ "},
+	'3': {"╶─┐ ", " ─┤ ", "╶─┘ "},
+	'4': {"╷ ╷ ", "└─┤ ", "  ╵ "},
+	'5': {"┌─╴ ", "└─┐ ", "╶─┘ "},
+	'6': {"┌─╴ ", "├─┐ ", "└─┘ "},
+	'7': {"╶─┐ ", "  │ ", "  ╵ "},
+	'8': {"┌─┐ ", "├─┤ ", "└─┘ "},
+	'9': {"┌─┐ ", "└─┤ ", "╶─┘ "},
+}
+
+// blockFont draws the digits like a seven-segment display, in solid
+// blocks without a shadow.
+var blockFont = Font{
+	':': {"    ", "██  ", "    ", "██  ", "    "},
+	'.': {"    ", "    ", "    ", "    ", "██  "},
+	'0': {"██████ ", "██  ██ ", "██  ██ ", "██  ██ ", "██████ "},
+	'1': {"    ██ ", "    ██ ", "    ██ ", "    ██ ", "    ██ "},
+	'2': {"██████ ", "    ██ ", "██████ ", "██     ", "██████ "},
+	'3': {"██████ ", "    ██ ", "██████ ", "    ██ ", "██████ "},
+	'4': {"██  ██ ", "██  ██ ", "██████ ", "    ██ ", "    ██ "},
+	'5': {"██████ ", "██     ", "██████ ", "    ██ ", "██████ "},
+	'6': {"██████ ", "██     ", "██████ ", "██  ██ ", "██████ "},
+	'7': {"██████ ", "    ██ ", "    ██ ", "    ██ ", "    ██ "},
+	'8': {"██████ ", "██  ██ ", "██████ ", "██  ██ ", "██████ "},
+	'9': {"██████ ", "██  ██ ", "██████ ", "    ██ ", "██████ "},
+}
+
+// PausedText is shown under a paused countdown.
+var PausedText = Symbol{
+	"█▀▄ ▄▀▄ █ █ ▄▀▀ ██▀ █▀▄",
+	"█▀  █▀█ ▀▄█ ▄██ █▄▄ █▄▀",
+}
@@ -0,0 +1,19 @@
+package render
+
+import (
+	"testing"
+	"time"
+)
+
+func BenchmarkFormat(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Format(time.Duration(i%86400) * time.Second)
+	}
+}
+
+func BenchmarkDigits(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Digits("01:23:45")
+	}
+}
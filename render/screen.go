@@ -0,0 +1,23 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// ErrTerminalInit is wrapped by the error of OpenScreen.
+var ErrTerminalInit = errors.New("cannot initialize the terminal")
+
+// OpenScreen takes over the terminal to draw on.
+func OpenScreen() (tcell.Screen, error) {
+	s, err := tcell.NewScreen()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTerminalInit, err)
+	}
+	if err := s.Init(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTerminalInit, err)
+	}
+	return s, nil
+}
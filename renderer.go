@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/antonmedv/countdown/render"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Renderer draws the remaining time in the middle of the screen.
+// drawTime is called between clear and flush, present right after flush
+// for renderers that write to the terminal directly.
+type Renderer interface {
+	drawTime(str string, style tcell.Style, w, h int)
+	present()
+}
+
+func newRenderer(name string) (Renderer, error) {
+	if name == "auto" {
+		name = detectGraphics()
+	}
+	switch name {
+	case "cells":
+		return cellRenderer{}, nil
+	case "kitty":
+		return newImageRenderer(encodeKitty)
+	case "sixel":
+		return newImageRenderer(encodeSixel)
+	}
+	return nil, fmt.Errorf("unknown renderer %q", name)
+}
+
+// detectGraphics guesses the best graphics protocol from the environment;
+// querying the terminal would race tcell for the input.
+func detectGraphics() string {
+	if os.Getenv("TMUX") != "" || os.Getenv("STY") != "" {
+		return "cells"
+	}
+	if _, _, ok := terminalPixels(); !ok {
+		return "cells"
+	}
+	term, program := os.Getenv("TERM"), os.Getenv("TERM_PROGRAM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "", strings.Contains(term, "kitty"),
+		program == "WezTerm", program == "ghostty":
+		return "kitty"
+	case strings.Contains(term, "sixel"), strings.HasPrefix(term, "foot"),
+		strings.HasPrefix(term, "mlterm"), program == "iTerm.app", program == "mintty":
+		return "sixel"
+	}
+	return "cells"
+}
+
+// digitScale enlarges the digits where they fit.
+var digitScale = 1
+
+type cellRenderer struct{}
+
+func (cellRenderer) drawTime(str string, style tcell.Style, w, h int) {
+	text := render.Digits(str)
+	if digitScale > 1 && text.Width()*digitScale <= w && text.Height()*digitScale <= h {
+		for i, s := range text {
+			text[i] = render.Scale(s, digitScale)
+		}
+	}
+
+	startX, startY := w/2-text.Width()/2, h/2-text.Height()/2
+
+	x, y := startX, startY
+	for _, s := range text {
+		echo(s, x, y, style)
+		x += s.Width()
+	}
+}
+
+func (cellRenderer) present() {}
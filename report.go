@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/antonmedv/countdown/logbook"
+)
+
+// reportPeriod is a span of the log "countdown report" sums up.
+type reportPeriod struct {
+	name  string
+	title func(start time.Time) string
+	start func(now time.Time) time.Time
+}
+
+var reportPeriods = []reportPeriod{
+	{"today", func(t time.Time) string { return "Today, " + t.Format("Mon Jan 2") }, startOfDay},
+	{"week", func(t time.Time) string { return "This week, since " + t.Format("Mon Jan 2") }, func(now time.Time) time.Time {
+		// Weeks start on Monday.
+		day := startOfDay(now)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}},
+	{"month", func(t time.Time) string { return "This month, " + t.Format("January 2006") }, func(now time.Time) time.Time {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}},
+}
+
+func findPeriod(name string) (reportPeriod, bool) {
+	for _, p := range reportPeriods {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return reportPeriod{}, false
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// tagTotal is the time spent on a tag in a period, pauses excluded.
+type tagTotal struct {
+	tag      string
+	active   time.Duration
+	paused   time.Duration
+	sessions int
+}
+
+// reportCommand prints the time spent per tag today, this week and this
+// month (or only the given periods), and the longest run of days with a
+// session. Sessions count in the period they started in.
+func reportCommand(logPath string, args []string) {
+	periods := reportPeriods
+	if len(args) > 0 {
+		periods = nil
+		for _, a := range args {
+			p, ok := findPeriod(a)
+			if !ok {
+				stderr("error: unknown period %q, want today, week or month\n", a)
+				os.Exit(2)
+			}
+			periods = append(periods, p)
+		}
+	}
+	events, err := logbook.Read(logPath)
+	if err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(1)
+	}
+	sessions := logbook.Sessions(events)
+	now := time.Now()
+
+	for i, p := range periods {
+		if i > 0 {
+			fmt.Println()
+		}
+		start := p.start(now)
+		fmt.Println(p.title(start))
+		totals := tagTotals(sessions, start)
+		if len(totals) == 0 {
+			fmt.Println("  no sessions")
+			continue
+		}
+		all := tagTotal{tag: "Total"}
+		for _, t := range totals {
+			all.active += t.active
+			all.paused += t.paused
+			all.sessions += t.sessions
+		}
+		printTagTotals(append(totals, all))
+	}
+
+	longest, longestEnd, current := streaks(sessions, now)
+	if longest > 0 {
+		fmt.Printf("\nLongest streak: %s, ending %s; current: %s\n",
+			plural(longest, "day"), longestEnd.Format("Mon Jan 2 2006"), plural(current, "day"))
+	}
+}
+
+// tagTotals sums up the sessions that started since start, most time
+// first.
+func tagTotals(sessions []logbook.Session, start time.Time) []tagTotal {
+	byTag := make(map[string]*tagTotal)
+	var totals []*tagTotal
+	for _, s := range sessions {
+		if s.Start.Before(start) {
+			continue
+		}
+		t, ok := byTag[s.Tag]
+		if !ok {
+			t = &tagTotal{tag: s.Tag}
+			byTag[s.Tag] = t
+			totals = append(totals, t)
+		}
+		t.active += s.Active()
+		t.paused += s.Paused
+		t.sessions++
+	}
+	sort.SliceStable(totals, func(i, j int) bool { return totals[i].active > totals[j].active })
+	result := make([]tagTotal, len(totals))
+	for i, t := range totals {
+		result[i] = *t
+	}
+	return result
+}
+
+// printTagTotals prints a row per tag: the tag on the left, the numbers
+// right-aligned after it.
+func printTagTotals(totals []tagTotal) {
+	rows := make([][4]string, len(totals))
+	var widths [4]int
+	for i, t := range totals {
+		rows[i] = [4]string{t.tag, formatSpent(t.active), plural(t.sessions, "session"), ""}
+		if t.paused > 0 {
+			rows[i][3] = "paused " + formatSpent(t.paused)
+		}
+		for j, cell := range rows[i] {
+			if n := utf8.RuneCountInString(cell); n > widths[j] {
+				widths[j] = n
+			}
+		}
+	}
+	for _, row := range rows {
+		line := fmt.Sprintf("  %-*s  %*s  %*s  %s", widths[0], row[0], widths[1], row[1], widths[2], row[2], row[3])
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
+
+// streaks returns the longest run of consecutive days with a session, the
+// day it ended, and the current run, which may end today or yesterday.
+func streaks(sessions []logbook.Session, now time.Time) (longest int, longestEnd time.Time, current int) {
+	days := make(map[time.Time]bool)
+	for _, s := range sessions {
+		days[startOfDay(s.Start)] = true
+	}
+	var sorted []time.Time
+	for d := range days {
+		sorted = append(sorted, d)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+	run := 0
+	for i, d := range sorted {
+		// AddDate rather than 24h, which is off on the days the clocks
+		// change.
+		if i > 0 && sorted[i-1].AddDate(0, 0, 1).Equal(d) {
+			run++
+		} else {
+			run = 1
+		}
+		if run >= longest {
+			longest, longestEnd = run, d
+		}
+	}
+	today := startOfDay(now)
+	if n := len(sorted); n > 0 && (sorted[n-1].Equal(today) || sorted[n-1].AddDate(0, 0, 1).Equal(today)) {
+		current = run
+	}
+	return longest, longestEnd, current
+}
+
+// formatSpent writes d in hours and minutes, or seconds under a minute.
+func formatSpent(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	d = d.Round(time.Minute)
+	h, m := int(d.Hours()), int(d.Minutes())%60
+	if h == 0 {
+		return fmt.Sprintf("%dm", m)
+	}
+	return fmt.Sprintf("%dh%02dm", h, m)
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
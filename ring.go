@@ -0,0 +1,42 @@
+package main
+
+import (
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// brailleBits maps a dot position inside a cell (2 wide, 4 tall) to its
+// bit in the braille block.
+var brailleBits = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// drawRing draws an ellipse of braille dots around the edge of the screen,
+// covering the given fraction of it clockwise from the top.
+func drawRing(fraction float64, style tcell.Style, w, h int) {
+	dotsW, dotsH := w*2, h*4
+	rx, ry := float64(dotsW)/2-1, float64(dotsH)/2-1
+	if rx <= 0 || ry <= 0 {
+		return
+	}
+	cx, cy := float64(dotsW)/2, float64(dotsH)/2
+
+	cells := make(map[[2]int]rune)
+	steps := int(4 * (rx + ry) * fraction)
+	for i := 0; i <= steps; i++ {
+		angle := 2 * math.Pi * fraction * float64(i) / float64(steps+1)
+		x := int(cx + rx*math.Sin(angle))
+		y := int(cy - ry*math.Cos(angle))
+		if x < 0 || y < 0 || x >= dotsW || y >= dotsH {
+			continue
+		}
+		cells[[2]int{x / 2, y / 4}] |= brailleBits[y%4][x%2]
+	}
+	for pos, bits := range cells {
+		setCell(pos[0], pos[1], 0x2800+bits, style)
+	}
+}
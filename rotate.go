@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// heading is drawn in big letters above the digits.
+var heading string
+
+func parseRotation(list string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) < 2 {
+		return nil, fmt.Errorf("-rotate needs at least two names")
+	}
+	return names, nil
+}
+
+// rotate cycles the turn through names until it is stopped with Esc. Each
+// turn is logged with the name as notes, and the next person is told when
+// the turn changes.
+func rotate(ctx context.Context, names []string, turn time.Duration, tag string, logPath string) bool {
+	defer func() {
+		heading, caption = "", ""
+	}()
+
+	for i := 0; ; i++ {
+		name, next := names[i%len(names)], names[(i+1)%len(names)]
+		heading = name
+		caption = "next: " + next
+		if !countdown(ctx, turn, false, tag, name, logPath) {
+			return i > 0
+		}
+		ringBell(1)
+		_ = desktopNotify("countdown: "+next+"'s turn", fmt.Sprintf("%s takes over from %s", next, name))
+	}
+}
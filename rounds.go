@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/antonmedv/countdown/parse"
+)
+
+// Rounds is a round timer in the config file, e.g. for boxing or debate.
+// The bells are counts of terminal bell rings.
+//
+//	[rounds.boxing]
+//	rounds = 12
+//	round = "3m"
+//	rest = "1m"
+//	warning = "30s"
+//	warning_bells = 1
+//	end_bells = 2
+type Rounds struct {
+	Rounds       int    `toml:"rounds"`
+	Round        string `toml:"round"`
+	Rest         string `toml:"rest,omitempty"`
+	Warning      string `toml:"warning,omitempty"`
+	StartBells   int    `toml:"start_bells,omitempty"`
+	WarningBells int    `toml:"warning_bells,omitempty"`
+	EndBells     int    `toml:"end_bells,omitempty"`
+}
+
+// roundPlan is a parsed Rounds with the default bells filled in.
+type roundPlan struct {
+	count                              int
+	round, rest, warning               time.Duration
+	startBells, warningBells, endBells int
+}
+
+// warnBefore rings warnBells once a countdown gets this close to its end.
+var (
+	warnBefore time.Duration
+	warnBells  int
+)
+
+func parseRounds(name string, r Rounds) (*roundPlan, error) {
+	if r.Rounds == 0 && r.Round == "" {
+		return nil, fmt.Errorf("rounds %q is not defined", name)
+	}
+	if r.Rounds <= 0 {
+		return nil, fmt.Errorf("rounds %q: rounds must be positive", name)
+	}
+	p := &roundPlan{count: r.Rounds, startBells: 1, warningBells: 1, endBells: 2}
+	var err error
+	if p.round, err = parse.Literal(r.Round); err != nil || p.round <= 0 {
+		return nil, fmt.Errorf("rounds %q: invalid round %q", name, r.Round)
+	}
+	if r.Rest != "" {
+		if p.rest, err = parse.Literal(r.Rest); err != nil || p.rest < 0 {
+			return nil, fmt.Errorf("rounds %q: invalid rest %q", name, r.Rest)
+		}
+	}
+	if r.Warning != "" {
+		if p.warning, err = parse.Literal(r.Warning); err != nil || p.warning < 0 || p.warning >= p.round {
+			return nil, fmt.Errorf("rounds %q: invalid warning %q", name, r.Warning)
+		}
+	}
+	if r.StartBells != 0 {
+		p.startBells = r.StartBells
+	}
+	if r.WarningBells != 0 {
+		p.warningBells = r.WarningBells
+	}
+	if r.EndBells != 0 {
+		p.endBells = r.EndBells
+	}
+	return p, nil
+}
+
+// runRounds alternates rounds and rests. Rounds are logged with their
+// number as notes and rests with "rest".
+func runRounds(ctx context.Context, p *roundPlan, tag string, logPath string) bool {
+	defer func() {
+		caption = ""
+		warnBefore, warnBells = 0, 0
+		isBreak = false
+	}()
+
+	for i := 1; i <= p.count; i++ {
+		caption = fmt.Sprintf("Round %d/%d", i, p.count)
+		warnBefore, warnBells = p.warning, p.warningBells
+		isBreak = false
+		// Ringing waits between the bells, which would hold up the keys of
+		// the countdown; only the last bells are waited for, before exit.
+		go ringBell(p.startBells)
+		if !countdown(ctx, p.round, false, tag, caption, logPath) {
+			return false
+		}
+		if i == p.count {
+			ringBell(p.endBells)
+			continue
+		}
+		go ringBell(p.endBells)
+		if p.rest == 0 {
+			continue
+		}
+		warnBefore = 0
+		isBreak = dimBreaks
+		if !countdown(ctx, p.rest, false, tag, "rest", logPath) {
+			return false
+		}
+	}
+	return true
+}
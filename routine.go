@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/antonmedv/countdown/parse"
+)
+
+// RoutineStep is one labeled timer of a routine in the config file:
+//
+//	[[routines.morning]]
+//	label = "Stretch"
+//	duration = "5m"
+//	bell = 1
+type RoutineStep struct {
+	Label    string `toml:"label"`
+	Duration string `toml:"duration"`
+	Tag      string `toml:"tag,omitempty"`
+	// Bell is how many times the terminal bell rings when the step ends.
+	Bell int `toml:"bell,omitempty"`
+}
+
+func parseRoutine(name string, steps []RoutineStep) ([]time.Duration, error) {
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("routine %q is not defined", name)
+	}
+	durations := make([]time.Duration, len(steps))
+	for i, step := range steps {
+		d, err := parse.Literal(step.Duration)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("routine %q step %d: invalid duration %q", name, i+1, step.Duration)
+		}
+		durations[i] = d
+	}
+	return durations, nil
+}
+
+// runRoutine steps through a routine. Each step is logged with its label
+// as notes, and can be skipped with n or extended with +.
+func runRoutine(ctx context.Context, steps []RoutineStep, durations []time.Duration, countUp bool, tag string, logPath string) bool {
+	stepControls = true
+	defer func() {
+		stepControls = false
+		caption = ""
+	}()
+
+	for i, step := range steps {
+		stepTag := step.Tag
+		if stepTag == "" {
+			stepTag = tag
+		}
+		caption = fmt.Sprintf("%s (%d/%d)", step.Label, i+1, len(steps))
+		if !countdown(ctx, durations[i], countUp, stepTag, step.Label, logPath) {
+			return false
+		}
+		// The bells of a step ring into the next one; the last are waited
+		// for, before exit.
+		if i == len(steps)-1 {
+			ringBell(step.Bell)
+		} else {
+			go ringBell(step.Bell)
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/antonmedv/countdown/render"
+)
+
+const (
+	// runGrace is how long a timed out command gets to exit before it is
+	// killed.
+	runGrace = 5 * time.Second
+	// runTimeoutStatus is the exit status after a timeout, as in timeout(1).
+	runTimeoutStatus = 124
+)
+
+// run counts up while the command runs, showing the elapsed time in the
+// terminal title, and stops it after timeout if that is set. The command is
+// logged as notes, and its exit status and duration as "exit=0
+// duration=1m23s" notes with the end. It returns the exit status to exit
+// with.
+func run(ctx context.Context, args []string, timeout time.Duration, ci Annotator, tag string, logPath string) int {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	title := isTerminal(os.Stderr)
+	if title {
+		// Save the title, to be restored at the end.
+		stderr("\033[22;0t")
+		defer stderr("\033[23;0t")
+	}
+
+	commandLine := strings.Join(args, " ")
+	ci.begin(commandLine)
+	began := time.Now()
+	if err := cmd.Start(); err != nil {
+		stderr("error: %v\n", err)
+		ci.end(commandLine, 127, 0)
+		return 127
+	}
+	bus.Publish(Event{Kind: SessionStarted, Tag: tag, Notes: commandLine, LogPath: logPath})
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var kill <-chan time.Time
+	stopping := ctx.Done()
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	timedOut := false
+	for {
+		select {
+		case <-ticker.C:
+			if title {
+				stderr("\033]2;%s %s\007", render.Format(time.Since(began)), commandLine)
+			}
+		case <-stopping:
+			// Stop the command on a timeout, or when countdown is stopped.
+			stopping = nil
+			timedOut = ctx.Err() == context.DeadlineExceeded
+			if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+				_ = cmd.Process.Kill()
+			}
+			kill = time.After(runGrace)
+		case <-kill:
+			_ = cmd.Process.Kill()
+		case err := <-done:
+			elapsed := time.Since(began).Round(time.Millisecond)
+			status := exitStatus(err)
+			if timedOut {
+				status = runTimeoutStatus
+			}
+			outcome := fmt.Sprintf("exit=%d duration=%s", status, elapsed)
+			if timedOut {
+				outcome += " timeout"
+			}
+			ci.end(commandLine, status, elapsed)
+			bus.Publish(Event{Kind: SessionEnded, Tag: tag, Notes: outcome, LogPath: logPath, Completed: status == 0})
+			stderr("countdown: %s: %s\n", commandLine, outcome)
+			return status
+		}
+	}
+}
+
+func exitStatus(err error) int {
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr) && exitErr.ExitCode() >= 0:
+		return exitErr.ExitCode()
+	}
+	// Killed by a signal.
+	return 1
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/antonmedv/countdown/logbook"
+	"github.com/antonmedv/countdown/parse"
+	"github.com/antonmedv/countdown/render"
+)
+
+// ScriptConfig holds the [script] templates, which decide with the
+// session at hand (see scriptContext) what plain flags cannot:
+//
+//	[script]
+//	duration = '{{if ge .Hour 17}}25m{{else}}50m{{end}}'
+//	next = '{{if ne .Tag "rest"}}{{if ge .Today (hours 2)}}15m{{else}}5m{{end}} rest{{end}}'
+//	notify = '{{.Tag}} done, {{format .Today}} today'
+type ScriptConfig struct {
+	// Duration is used when countdown is run without one.
+	Duration string `toml:"duration,omitempty"`
+	// Next is the duration and tag of the countdown started after one
+	// completes, as with ":start"; empty for none.
+	Next string `toml:"next,omitempty"`
+	// Notify is the body of the -notify notification.
+	Notify string `toml:"notify,omitempty"`
+}
+
+// scripts are the parsed [script] templates; a nil one is not set.
+var scripts struct {
+	duration, next, notify *template.Template
+}
+
+// scriptContext is what the scripts see. Today is the time spent on the
+// tag today, pauses excluded, as logged so far.
+type scriptContext struct {
+	Tag       string
+	Notes     string
+	Now       time.Time
+	Hour      int
+	Weekday   string
+	Total     time.Duration
+	Completed bool
+	Today     time.Duration
+	Sessions  int
+}
+
+var scriptFuncs = template.FuncMap{
+	"minutes":  func(n float64) time.Duration { return time.Duration(n * float64(time.Minute)) },
+	"hours":    func(n float64) time.Duration { return time.Duration(n * float64(time.Hour)) },
+	"add":      func(a, b time.Duration) time.Duration { return a + b },
+	"sub":      func(a, b time.Duration) time.Duration { return a - b },
+	"mul":      func(d time.Duration, f float64) time.Duration { return time.Duration(float64(d) * f) },
+	"format":   render.Format,
+	"contains": strings.Contains,
+}
+
+func parseScripts(c ScriptConfig) error {
+	var err error
+	compile := func(name, text string) *template.Template {
+		if text == "" || err != nil {
+			return nil
+		}
+		var t *template.Template
+		if t, err = template.New(name).Funcs(scriptFuncs).Parse(text); err != nil {
+			err = fmt.Errorf("script %s: %v", name, err)
+		}
+		return t
+	}
+	scripts.duration = compile("duration", c.Duration)
+	scripts.next = compile("next", c.Next)
+	scripts.notify = compile("notify", c.Notify)
+	return err
+}
+
+// newScriptContext describes the session with the tag, reading the log
+// for today's totals.
+func newScriptContext(logPath, tag, notes string) scriptContext {
+	now := time.Now()
+	c := scriptContext{Tag: tag, Notes: notes, Now: now, Hour: now.Hour(), Weekday: now.Weekday().String()}
+	events, err := logbook.Read(logPath)
+	if err != nil {
+		return c
+	}
+	today := startOfDay(now)
+	for _, s := range logbook.Sessions(events) {
+		if s.Tag == tag && !s.Start.Before(today) {
+			c.Today += s.Active()
+			c.Sessions++
+		}
+	}
+	return c
+}
+
+func runScript(t *template.Template, c scriptContext) (string, error) {
+	var b bytes.Buffer
+	if err := t.Execute(&b, c); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// scriptDuration runs the duration script; empty output means none.
+func scriptDuration(logPath, tag, notes, unit string) (string, error) {
+	out, err := runScript(scripts.duration, newScriptContext(logPath, tag, notes))
+	if err != nil || out == "" {
+		return "", err
+	}
+	if _, err := parse.Duration([]string{out}, true, unit); err != nil {
+		return "", fmt.Errorf("script duration %q: %v", out, err)
+	}
+	return out, nil
+}
+
+// scriptEvents returns the subscriber that runs the next script when a
+// session completes, planning the countdown it names. A script that
+// fails plans nothing.
+func scriptEvents() func(Event) {
+	var notes string
+	return func(e Event) {
+		switch e.Kind {
+		case SessionStarted:
+			notes = e.Notes
+		case SessionEnded:
+			if !e.Completed || !canPlan || nextSession != nil {
+				return
+			}
+			c := newScriptContext(e.LogPath, e.Tag, notes)
+			c.Total, c.Completed = e.Total, true
+			out, err := runScript(scripts.next, c)
+			if err != nil || out == "" {
+				return
+			}
+			words := strings.Fields(out)
+			d, err := parse.Duration(words[:1], true, "")
+			if err != nil || d <= 0 {
+				return
+			}
+			next := plannedSession{duration: d, tag: e.Tag}
+			if len(words) > 1 {
+				next.tag = strings.Join(words[1:], " ")
+			}
+			nextSession = &next
+		}
+	}
+}
+
+// scriptNotify is the notification body from the notify script, or
+// fallback without one.
+func scriptNotify(e Event, notes, fallback string) string {
+	if scripts.notify == nil {
+		return fallback
+	}
+	c := newScriptContext(e.LogPath, e.Tag, notes)
+	c.Total, c.Completed = e.Total, e.Completed
+	out, err := runScript(scripts.notify, c)
+	if err != nil {
+		return fallback
+	}
+	return out
+}
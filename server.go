@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antonmedv/countdown/logbook"
+)
+
+const serverUsage = `
+ countdown server [-addr :8420] [-data <dir>] [-tls-cert <file> -tls-key <file>]
+
+ Keeps the sessions that countdown sync uploads from several devices and
+ serves stats on them. Requests need the token in COUNTDOWN_SERVER_TOKEN
+ as "Authorization: Bearer <token>".
+
+  POST /v1/records   {"records": [<sync records>]}
+  GET  /v1/records
+  GET  /v1/sessions  [?from=2024-01-01&to=2024-01-31]
+  GET  /v1/stats     [?from=2024-01-01&to=2024-01-31]
+`
+
+// maxUpload caps the body of POST /v1/records.
+const maxUpload = 32 << 20
+
+var deviceName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// syncUpload is the body of POST /v1/records and GET /v1/records. Records
+// the server has already are skipped, so a device may send them again,
+// and send records of other devices it has seen.
+type syncUpload struct {
+	Records []syncRecord `json:"records"`
+}
+
+// serverSession is a session in GET /v1/sessions.
+type serverSession struct {
+	Device        string    `json:"device"`
+	Tag           string    `json:"tag"`
+	Notes         string    `json:"notes,omitempty"`
+	Outcome       string    `json:"outcome,omitempty"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	ActiveSeconds float64   `json:"active_seconds"`
+	PausedSeconds float64   `json:"paused_seconds"`
+}
+
+// serverTagTotal is a tag in GET /v1/stats, summed over the devices.
+type serverTagTotal struct {
+	Tag           string  `json:"tag"`
+	ActiveSeconds float64 `json:"active_seconds"`
+	PausedSeconds float64 `json:"paused_seconds"`
+	Sessions      int     `json:"sessions"`
+}
+
+// syncServer keeps every device's records in a file of its own,
+// <device>.jsonl in dir.
+type syncServer struct {
+	dir   string
+	token string
+	// mu keeps uploads from interleaving their appends.
+	mu sync.Mutex
+}
+
+func serverCommand(args []string) {
+	fs := flag.NewFlagSet("server", flag.ExitOnError)
+	addr := fs.String("addr", ":8420", "address to listen on")
+	dir := fs.String("data", "", "folder of the uploaded logs (default: countdown-server in the user data folder)")
+	certFile := fs.String("tls-cert", "", "serve HTTPS with this certificate")
+	keyFile := fs.String("tls-key", "", "the key of -tls-cert")
+	fs.Usage = func() {
+		stderr(serverUsage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 || (*certFile == "") != (*keyFile == "") {
+		fs.Usage()
+		os.Exit(2)
+	}
+	token := os.Getenv("COUNTDOWN_SERVER_TOKEN")
+	if token == "" {
+		stderr("error: set COUNTDOWN_SERVER_TOKEN to the token clients use\n")
+		os.Exit(2)
+	}
+	if *dir == "" {
+		*dir = defaultServerDir()
+	}
+	if err := os.MkdirAll(*dir, 0700); err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	s := &syncServer{dir: *dir, token: token}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/records", s.auth(s.records))
+	mux.HandleFunc("/v1/sessions", s.auth(s.sessions))
+	mux.HandleFunc("/v1/stats", s.auth(s.stats))
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	stderr("countdown server on %s, data in %s\n", *addr, *dir)
+	var err error
+	if *certFile != "" {
+		err = srv.ListenAndServeTLS(*certFile, *keyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	stderr("error: %v\n", err)
+	os.Exit(1)
+}
+
+func defaultServerDir() string {
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return "countdown-server"
+	}
+	return filepath.Join(dir, ".local", "share", "countdown-server")
+}
+
+func (s *syncServer) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *syncServer) records(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		records, err := s.load()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if records == nil {
+			records = []syncRecord{}
+		}
+		writeJSON(w, syncUpload{Records: records})
+	case http.MethodPost:
+		var u syncUpload
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUpload)).Decode(&u); err != nil {
+			http.Error(w, "invalid upload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, rec := range u.Records {
+			if err := rec.check(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		added, err := s.add(u.Records)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]int{"added": added})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// add appends the records the server does not have yet, each to the file
+// of its device.
+func (s *syncServer) add(records []syncRecord) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+	known := make(map[string]bool, len(old))
+	for _, r := range old {
+		known[r.ID] = true
+	}
+	added := 0
+	for _, r := range records {
+		if known[r.ID] {
+			continue
+		}
+		known[r.ID] = true
+		b, err := json.Marshal(r)
+		if err != nil {
+			return added, err
+		}
+		if err := appendRecord(filepath.Join(s.dir, r.Device+".jsonl"), b); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+func appendRecord(path string, b []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// load reads the records of all devices.
+func (s *syncServer) load() ([]syncRecord, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	var records []syncRecord
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, maxUpload)
+		for n := 1; scanner.Scan(); n++ {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var r syncRecord
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+			}
+			records = append(records, r)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// read pairs every device's live events into sessions, oldest start
+// first, of those that started in [from, to).
+func (s *syncServer) read(from, to time.Time) ([]serverSession, error) {
+	records, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var all []serverSession
+	for device, events := range liveEvents(records) {
+		for _, ls := range logbook.Sessions(events) {
+			if ls.Start.Before(from) || !to.IsZero() && !ls.Start.Before(to) {
+				continue
+			}
+			all = append(all, serverSession{
+				Device: device, Tag: ls.Tag, Notes: ls.Notes, Outcome: ls.Outcome,
+				Start: ls.Start, End: ls.End,
+				ActiveSeconds: ls.Active().Seconds(), PausedSeconds: ls.Paused.Seconds(),
+			})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Start.Before(all[j].Start) })
+	return all, nil
+}
+
+// span reads ?from= and ?to= (inclusive) as dates, like countdown export.
+func span(r *http.Request) (from, to time.Time, err error) {
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.ParseInLocation(dateLayout, v, time.Local); err != nil {
+			return from, to, fmt.Errorf("invalid from %q, want e.g. 2024-01-31", v)
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.ParseInLocation(dateLayout, v, time.Local); err != nil {
+			return from, to, fmt.Errorf("invalid to %q, want e.g. 2024-01-31", v)
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+	return from, to, nil
+}
+
+func (s *syncServer) sessions(w http.ResponseWriter, r *http.Request) {
+	from, to, err := span(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sessions, err := s.read(from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if sessions == nil {
+		sessions = []serverSession{}
+	}
+	writeJSON(w, sessions)
+}
+
+// stats sums the time per tag over all devices, most time first.
+func (s *syncServer) stats(w http.ResponseWriter, r *http.Request) {
+	from, to, err := span(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sessions, err := s.read(from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	byTag := make(map[string]*serverTagTotal)
+	totals := []*serverTagTotal{}
+	devices := make(map[string]bool)
+	for _, ss := range sessions {
+		t, ok := byTag[ss.Tag]
+		if !ok {
+			t = &serverTagTotal{Tag: ss.Tag}
+			byTag[ss.Tag] = t
+			totals = append(totals, t)
+		}
+		t.ActiveSeconds += ss.ActiveSeconds
+		t.PausedSeconds += ss.PausedSeconds
+		t.Sessions++
+		devices[ss.Device] = true
+	}
+	sort.SliceStable(totals, func(i, j int) bool { return totals[i].ActiveSeconds > totals[j].ActiveSeconds })
+	names := []string{}
+	for d := range devices {
+		names = append(names, d)
+	}
+	sort.Strings(names)
+	writeJSON(w, map[string]interface{}{"tags": totals, "devices": names})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
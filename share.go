@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"html/template"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// shareStatus is what a share link shows of the daemon's timer: no notes,
+// as they are not meant for the people the link is dropped to.
+type shareStatus struct {
+	State string  `json:"state"`
+	Tag   string  `json:"tag,omitempty"`
+	Left  float64 `json:"left_seconds"`
+	Total float64 `json:"total_seconds"`
+}
+
+// sharePage counts down in the browser between polls of the status, so
+// it stays live without a request per second.
+var sharePage = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>countdown</title>
+<style>
+body { margin: 0; height: 100vh; display: flex; flex-direction: column; align-items: center; justify-content: center; font-family: sans-serif; background: #111; color: #eee; }
+#time { font: bold 18vw monospace; }
+#tag { font-size: 4vw; color: #aaa; }
+</style>
+</head>
+<body>
+<div id="tag"></div>
+<div id="time">--:--</div>
+<script>
+var current = {state: "idle", left_seconds: 0}, at = Date.now();
+function pad(n) { return (n < 10 ? "0" : "") + n; }
+function show() {
+  var left = current.left_seconds;
+  if (current.state === "running") left = Math.max(0, left - (Date.now() - at) / 1000);
+  left = Math.ceil(left);
+  var h = Math.floor(left / 3600), m = Math.floor(left / 60) % 60, s = left % 60;
+  var text = (h > 0 ? h + ":" + pad(m) : m) + ":" + pad(s);
+  if (current.state === "idle") text = "--:--";
+  if (current.state === "paused") text += " paused";
+  document.getElementById("time").textContent = text;
+  document.getElementById("tag").textContent = current.tag || "";
+}
+function poll() {
+  fetch({{.}} + "/status").then(function (r) { return r.json(); }).then(function (s) {
+    current = s; at = Date.now(); show();
+  }).catch(function () {});
+}
+poll();
+setInterval(poll, 5000);
+setInterval(show, 250);
+</script>
+</body>
+</html>
+`))
+
+// shareServer serves read-only views of the daemon's timer under a
+// random path, which is the link's only secret.
+type shareServer struct {
+	timer *daemonTimer
+	path  string
+	url   string
+}
+
+// startShare listens on addr for share links and returns the link.
+func startShare(t *daemonTimer, addr string) (string, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		ln.Close()
+		return "", err
+	}
+	s := &shareServer{timer: t, path: "/s/" + hex.EncodeToString(token)}
+	s.url = "http://" + shareHost(ln.Addr()) + s.path
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(s.path, s.page)
+	mux.HandleFunc(s.path+"/status", s.status)
+	mux.HandleFunc(s.path+"/badge.svg", s.badge)
+	mux.HandleFunc(s.path+"/badge.png", s.badge)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = srv.Serve(ln) }()
+	return s.url, nil
+}
+
+// shareHost names the machine in links, by the address it listens on or
+// else by its host name.
+func shareHost(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		if name, err := os.Hostname(); err == nil {
+			host = name
+		}
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func (s *shareServer) page(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = sharePage.Execute(w, s.path)
+}
+
+func (s *shareServer) status(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, s.timer.share())
+}
+
+// share is the daemon's timer as a share link shows it.
+func (t *daemonTimer) share() shareStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := shareStatus{State: t.state, Tag: t.tag}
+	if t.timer != nil {
+		s.Left, s.Total = t.timer.Left().Seconds(), t.timer.Total().Seconds()
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var (
+	soundPath   string
+	volume      int
+	repeatAlarm bool
+)
+
+// soundCommand plays path at volume, from 0 to 100, with the platform's
+// player: afplay on macOS, paplay, ffplay or aplay elsewhere and the
+// media player on Windows. aplay and Windows ignore the volume. It is nil
+// when there is no player.
+func soundCommand(path string, volume int) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("afplay", "-v", fmt.Sprintf("%.2f", float64(volume)/100), path)
+	case "windows":
+		return exec.Command("powershell", "-NoProfile", "-Command",
+			"(New-Object Media.SoundPlayer "+powershellString(path)+").PlaySync()")
+	}
+	if p, err := exec.LookPath("paplay"); err == nil {
+		return exec.Command(p, fmt.Sprintf("--volume=%d", 65536*volume/100), path)
+	}
+	if p, err := exec.LookPath("ffplay"); err == nil {
+		return exec.Command(p, "-nodisp", "-autoexit", "-loglevel", "quiet", "-volume", fmt.Sprint(volume), path)
+	}
+	if p, err := exec.LookPath("aplay"); err == nil {
+		return exec.Command(p, "-q", path)
+	}
+	return nil
+}
+
+// alarm plays -sound when the countdown completes, falling back to the
+// terminal bell, and with -repeat plays it again until a key is pressed.
+// A key also cuts the sound short.
+func alarm() {
+	for {
+		played, stop := playAlarm()
+	wait:
+		for {
+			select {
+			case <-played:
+				break wait
+			case ev := <-queues:
+				if _, ok := ev.(*tcell.EventKey); ok {
+					stop()
+					return
+				}
+			}
+		}
+		if !repeatAlarm {
+			return
+		}
+	}
+}
+
+// playAlarm plays the sound once; played is closed when it is over and
+// stop cuts it short.
+func playAlarm() (played <-chan struct{}, stop func()) {
+	done := make(chan struct{})
+	var cmd *exec.Cmd
+	if soundPath != "" {
+		cmd = soundCommand(soundPath, volume)
+	}
+	if cmd == nil || cmd.Start() != nil {
+		ringBell(1)
+		time.AfterFunc(bellInterval, func() { close(done) })
+		return done, func() {}
+	}
+	go func() {
+		_ = cmd.Wait()
+		close(done)
+	}()
+	return done, func() {
+		_ = cmd.Process.Kill()
+		<-done
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sprintTimeout caps a sample, so a stuck command does not stall the end
+// of the session.
+const sprintTimeout = 10 * time.Second
+
+// firstNumber finds the count in a sample's output, e.g. 412 in
+// "412 draft.md" from wc -w.
+var firstNumber = regexp.MustCompile(`-?\d+`)
+
+// Sprint is -sprint: a writing sprint that samples the word count with a
+// command every so often and shows the pace against -wpm under the
+// digits. The words written are logged with the end.
+type Sprint struct {
+	command string
+	every   time.Duration
+	target  float64
+
+	mu      sync.Mutex
+	start   int
+	words   int
+	sampled bool
+	err     error
+	// stop ends the sampling in the background.
+	stop func()
+}
+
+var sprint *Sprint
+
+// sample runs the command and reads the first number it prints.
+func (s *Sprint) sample(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, sprintTimeout)
+	defer cancel()
+	out, err := shellCommandContext(ctx, s.command).Output()
+	if err != nil {
+		return 0, fmt.Errorf("-sprint: %v", err)
+	}
+	n := firstNumber.Find(out)
+	if n == nil {
+		return 0, fmt.Errorf("-sprint: no count in %q", out)
+	}
+	return strconv.Atoi(string(n))
+}
+
+// begin takes the count to start from and samples in the background from
+// then on, until stop.
+func (s *Sprint) begin(ctx context.Context) error {
+	n, err := s.sample(ctx)
+	if err != nil {
+		return err
+	}
+	s.start, s.words, s.sampled = n, n, true
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	s.stop = func() {
+		cancel()
+		<-done
+	}
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(s.every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			n, err := s.sample(ctx)
+			s.mu.Lock()
+			if err == nil {
+				s.words = n
+			}
+			s.err = err
+			s.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// pace is the words written so far and the words per minute over elapsed.
+func (s *Sprint) pace(elapsed time.Duration) (int, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	written := s.words - s.start
+	if elapsed < time.Second {
+		return written, 0
+	}
+	return written, float64(written) / elapsed.Minutes()
+}
+
+// caption is the line under the digits.
+func (s *Sprint) caption(elapsed time.Duration) string {
+	s.mu.Lock()
+	err := s.err
+	s.mu.Unlock()
+	if err != nil {
+		return err.Error()
+	}
+	written, wpm := s.pace(elapsed)
+	line := fmt.Sprintf("%+d words  %.1f wpm", written, wpm)
+	if s.target > 0 {
+		mark := "behind"
+		if wpm >= s.target {
+			mark = "on pace"
+		}
+		line += fmt.Sprintf(" / %.0f target, %s", s.target, mark)
+	}
+	return line
+}
+
+// sprintEvents keeps the caption current on every tick.
+func sprintEvents(e Event) {
+	if e.Kind == Tick && sprint != nil && sprint.sampled {
+		caption = sprint.caption(e.Total - e.Left)
+	}
+}
+
+// sprintNote is the log note for a sprint, empty without -sprint. It takes
+// a last sample, so the count is the one at the end.
+func sprintNote(elapsed time.Duration) string {
+	if sprint == nil || !sprint.sampled {
+		return ""
+	}
+	if n, err := sprint.sample(context.Background()); err == nil {
+		sprint.mu.Lock()
+		sprint.words = n
+		sprint.mu.Unlock()
+	}
+	written, wpm := sprint.pace(elapsed)
+	return fmt.Sprintf("words %+d (%d to %d, %.1f wpm)", written, sprint.start, sprint.start+written, wpm)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antonmedv/countdown/logbook"
+)
+
+// sqliteScheme starts a -log URL for the SQLite backend.
+const sqliteScheme = "sqlite://"
+
+// sqliteSchema is created in every database on first use. WAL and the
+// busy timeout let several countdowns write to one database at once.
+const sqliteSchema = `PRAGMA journal_mode=WAL;
+CREATE TABLE IF NOT EXISTS sessions (
+	id TEXT PRIMARY KEY,
+	tag TEXT NOT NULL,
+	notes TEXT NOT NULL,
+	started TEXT NOT NULL,
+	ended TEXT,
+	total_seconds REAL,
+	completed INTEGER,
+	outcome TEXT
+);
+CREATE TABLE IF NOT EXISTS events (
+	id INTEGER PRIMARY KEY,
+	session TEXT NOT NULL REFERENCES sessions(id),
+	kind TEXT NOT NULL,
+	time TEXT NOT NULL,
+	left_seconds REAL,
+	total_seconds REAL
+);
+CREATE INDEX IF NOT EXISTS sessions_started ON sessions(started);
+CREATE INDEX IF NOT EXISTS events_session ON events(session);
+`
+
+// sqliteTimeout is how long a write waits for another countdown to
+// finish its own.
+const sqliteTimeout = 5 * time.Second
+
+// sqlitePath returns the database of a -log URL such as
+// sqlite:///home/me/countdown.db.
+func sqlitePath(url string) (string, error) {
+	if !strings.HasPrefix(url, sqliteScheme) {
+		return "", fmt.Errorf("unsupported -log %q, want %s/path/to.db", url, sqliteScheme)
+	}
+	path := strings.TrimPrefix(url, sqliteScheme)
+	if path == "" {
+		return "", fmt.Errorf("-log %q has no path", url)
+	}
+	return expandHome(path), nil
+}
+
+// runSQLite runs statements on the database with the sqlite3 shell, which
+// does the locking, so countdown needs no driver.
+func runSQLite(path, statements string) error {
+	cmd := exec.Command("sqlite3", "-bail", "-cmd", fmt.Sprintf(".timeout %d", sqliteTimeout.Milliseconds()), path)
+	cmd.Stdin = strings.NewReader(statements)
+	var out bytes.Buffer
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return fmt.Errorf("%s: %s", path, msg)
+		}
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// openSQLite checks that the database can be written, creating the
+// tables if needed.
+func openSQLite(path string) error {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return fmt.Errorf("-log sqlite needs the sqlite3 command: %v", err)
+	}
+	return runSQLite(path, sqliteSchema)
+}
+
+// sqliteEvents returns the subscriber that writes the sessions and their
+// events to the database. Session ids add the process and a count to the
+// start time, as several countdowns may start in the same second, in
+// other processes or side by side with -parallel; those are told apart by
+// tag, as in the log.
+func sqliteEvents(path string) func(Event) {
+	sessions := make(map[string]string)
+	var last string
+	started := 0
+	return func(e Event) {
+		session, ok := sessions[e.Tag]
+		if !ok {
+			session = last
+		}
+		var kind, sql string
+		switch e.Kind {
+		case SessionStarted:
+			kind = "start"
+			started++
+			session = fmt.Sprintf("%s-%d-%d", e.Time.Format(sessionID), os.Getpid(), started)
+			sessions[e.Tag], last = session, session
+			sql = fmt.Sprintf("INSERT INTO sessions (id, tag, notes, started, total_seconds) VALUES (%s, %s, %s, %s, %s);\n",
+				sqlQuote(session), sqlQuote(e.Tag), sqlQuote(e.Notes), sqlTime(e.Time), sqlSeconds(e.Total))
+		case SessionPaused:
+			kind = "pause"
+		case SessionResumed:
+			kind = "resume"
+		case SessionChanged:
+			kind = "change"
+			sql = fmt.Sprintf("UPDATE sessions SET total_seconds = %s WHERE id = %s;\n", sqlSeconds(e.Total), sqlQuote(session))
+		case SessionLap:
+			kind = "lap"
+		case SessionEnded:
+			kind = "end"
+			delete(sessions, e.Tag)
+			completed := 0
+			if e.Completed {
+				completed = 1
+			}
+			sql = fmt.Sprintf("UPDATE sessions SET ended = %s, completed = %d, outcome = %s WHERE id = %s;\n",
+				sqlTime(e.Time), completed, sqlQuote(e.Notes), sqlQuote(session))
+		default:
+			return
+		}
+		if session == "" {
+			return
+		}
+		left, total := "NULL", "NULL"
+		if e.Kind == SessionStarted || e.Kind == SessionChanged {
+			left, total = sqlSeconds(e.Left), sqlSeconds(e.Total)
+		}
+		if e.Kind == SessionLap {
+			total = sqlSeconds(e.Total)
+		}
+		sql = "BEGIN;\n" + sql + fmt.Sprintf("INSERT INTO events (session, kind, time, left_seconds, total_seconds) VALUES (%s, %s, %s, %s, %s);\nCOMMIT;\n",
+			sqlQuote(session), sqlQuote(kind), sqlTime(e.Time), left, total)
+		if err := runSQLite(path, sql); err != nil {
+			fail(fmt.Errorf("%w: %v", logbook.ErrUnavailable, err))
+		}
+	}
+}
+
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlTime stores times in UTC with a fixed width, so they sort as text.
+func sqlTime(t time.Time) string {
+	return sqlQuote(t.UTC().Format("2006-01-02T15:04:05.000Z"))
+}
+
+func sqlSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
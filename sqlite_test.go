@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLiteParallelSessions(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("no sqlite3")
+	}
+	path := filepath.Join(t.TempDir(), "countdown.db")
+	if err := openSQLite(path); err != nil {
+		t.Fatal(err)
+	}
+	write := sqliteEvents(path)
+	now := time.Date(2024, 1, 3, 10, 30, 0, 0, time.UTC)
+	write(Event{Kind: SessionStarted, Time: now, Tag: "tea", Left: 3 * time.Minute, Total: 3 * time.Minute})
+	write(Event{Kind: SessionStarted, Time: now, Tag: "eggs", Left: 7 * time.Minute, Total: 7 * time.Minute})
+	write(Event{Kind: SessionPaused, Time: now.Add(time.Second), Tag: "tea"})
+	write(Event{Kind: SessionEnded, Time: now.Add(3 * time.Minute), Tag: "eggs"})
+	write(Event{Kind: SessionEnded, Time: now.Add(4 * time.Minute), Tag: "tea", Completed: true})
+
+	out, err := exec.Command("sqlite3", path,
+		"SELECT s.tag, s.completed, group_concat(e.kind, ' ') FROM sessions s JOIN events e ON e.session = s.id GROUP BY s.id ORDER BY s.tag;").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "eggs|0|start end\ntea|1|start pause end"
+	if got := strings.TrimSpace(string(out)); got != want {
+		t.Errorf("sessions:\n%s\nwant:\n%s", got, want)
+	}
+}
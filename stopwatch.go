@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/antonmedv/countdown/render"
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	stopwatchHints   = "l or Enter: lap  Space: pause  Esc: stop"
+	stopwatchRefresh = 50 * time.Millisecond
+)
+
+// stopwatch counts up until it is stopped with Esc, which ends the
+// session. l or Enter records a lap: it is logged with its number, its
+// time and the time so far as notes, e.g. "lap 3 1:02.35 at 3:10.80".
+func stopwatch(ctx context.Context, tag, notes, logPath string) bool {
+	var laps []time.Duration
+	var elapsed, lapStart time.Duration
+	started := time.Now()
+	paused := false
+	now := func() time.Duration {
+		if paused {
+			return elapsed
+		}
+		return elapsed + time.Since(started)
+	}
+	var resized <-chan time.Time
+	ticker := time.NewTicker(stopwatchRefresh)
+	defer ticker.Stop()
+	w, h = screen.Size()
+	bus.Publish(Event{Kind: SessionStarted, Tag: tag, Notes: notes, LogPath: logPath})
+	end := func(completed bool) bool {
+		outcome := plural(len(laps), "lap")
+		if len(laps) == 0 {
+			outcome = ""
+		}
+		bus.Publish(Event{Kind: SessionEnded, Tag: tag, Notes: outcome, LogPath: logPath, Completed: completed, Quiet: true, Total: now()})
+		return completed
+	}
+	drawStopwatch(now(), now()-lapStart, laps, paused, w, h)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return end(false)
+		case ev := <-queues:
+			if _, ok := ev.(*tcell.EventResize); ok {
+				resized = time.After(resizeDelay)
+				break
+			}
+			key, ok := ev.(*tcell.EventKey)
+			if !ok {
+				break
+			}
+			switch {
+			case pressed("quit", key):
+				return end(true)
+			case pressed("pause", key):
+				if paused {
+					started, paused = time.Now(), false
+					bus.Publish(Event{Kind: SessionResumed, Tag: tag, LogPath: logPath})
+				} else {
+					elapsed, paused = now(), true
+					bus.Publish(Event{Kind: SessionPaused, Tag: tag, LogPath: logPath})
+				}
+			case pressed("lap", key) && !paused:
+				at := now()
+				lap := at - lapStart
+				laps, lapStart = append(laps, lap), at
+				bus.Publish(Event{Kind: SessionLap, Tag: tag, LogPath: logPath, Total: at,
+					Notes: fmt.Sprintf("lap %d %s at %s", len(laps), formatSolve(lap), formatSolve(at))})
+			}
+		case <-resized:
+			resized = nil
+			w, h = screen.Size()
+		case <-ticker.C:
+		}
+		drawStopwatch(now(), now()-lapStart, laps, paused, w, h)
+	}
+}
+
+// drawStopwatch shows the time so far, the current lap under it and the
+// laps, latest first, as far as they fit.
+func drawStopwatch(elapsed, lap time.Duration, laps []time.Duration, paused bool, w, h int) {
+	clear()
+	style := tcell.StyleDefault
+	if paused {
+		style = style.Dim(true)
+	}
+	renderer.drawTime(formatSolve(elapsed), style, w, h)
+
+	y := h/2 + render.Digits("0").Height()/2 + 1
+	if len(laps) > 0 {
+		line := fmt.Sprintf("lap %d  %s", len(laps)+1, formatSolve(lap))
+		echoString(line, w/2-utf8.RuneCountInString(line)/2, y, tcell.StyleDefault)
+	}
+	best, worst := 0, 0
+	for i, d := range laps {
+		if d < laps[best] {
+			best = i
+		}
+		if d > laps[worst] {
+			worst = i
+		}
+	}
+	for i := len(laps) - 1; i >= 0 && y+1+len(laps)-i < h-2; i-- {
+		line := fmt.Sprintf("%3d  %9s", i+1, formatSolve(laps[i]))
+		lapStyle := tcell.StyleDefault.Dim(true)
+		switch {
+		case len(laps) > 1 && i == best:
+			lapStyle = tcell.StyleDefault.Foreground(tcell.ColorGreen)
+		case len(laps) > 1 && i == worst:
+			lapStyle = tcell.StyleDefault.Foreground(tcell.ColorRed)
+		}
+		echoString(line, w/2-utf8.RuneCountInString(line)/2, y+1+len(laps)-i, lapStyle)
+	}
+	hint := stopwatchHints
+	if paused {
+		hint = "paused, Space: resume  Esc: stop"
+	}
+	echoString(hint, w/2-utf8.RuneCountInString(hint)/2, h-1, tcell.StyleDefault.Dim(true))
+	flush()
+	renderer.present()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/antonmedv/countdown/render"
+	"github.com/antonmedv/countdown/timer"
+)
+
+// Status is the data available to -format templates.
+type Status struct {
+	// State is "idle", "running" or "paused"; only set by the daemon.
+	State string `json:",omitempty"`
+	// Time is the time as the countdown draws it: Remaining, or Elapsed
+	// with -up.
+	Time      string
+	Remaining string
+	Elapsed   string
+	Total     string
+	Percent   int
+	Tag       string
+	Notes     string
+	// IDs are the tag's IDs in other services, from countdown tag set.
+	IDs map[string]string `json:",omitempty"`
+}
+
+func newStatus(timeLeft, totalDuration time.Duration, tag, notes string) Status {
+	return Status{
+		Time:      render.Format(timeLeft),
+		Remaining: render.Format(timeLeft),
+		Elapsed:   render.Format(totalDuration - timeLeft),
+		Total:     render.Format(totalDuration),
+		Percent:   int(100 * elapsedFraction(timeLeft, totalDuration)),
+		Tag:       tag,
+		Notes:     notes,
+		IDs:       tags[tag].IDs,
+	}
+}
+
+// streamStatus is the Status of a stream, counting up with countUp.
+func streamStatus(timeLeft, totalDuration time.Duration, countUp bool, tag, notes string) Status {
+	s := newStatus(timeLeft, totalDuration, tag, notes)
+	s.Time = render.Format(durationToDraw(timeLeft, totalDuration, countUp))
+	return s
+}
+
+// stream prints one templated line per tick to stdout instead of drawing
+// the TUI, for status bars and scripts. It exits like the TUI when
+// aborted.
+func stream(ctx context.Context, tmpl *template.Template, totalDuration time.Duration, countUp bool, tag string, notes string, logPath string) {
+	t := timer.New(totalDuration, tick)
+	events := t.Subscribe()
+	defer t.Stop()
+	t.Start()
+	bus.Publish(Event{Kind: SessionStarted, Tag: tag, Notes: notes, LogPath: logPath, Left: totalDuration, Total: totalDuration})
+	printStatus(tmpl, streamStatus(totalDuration, totalDuration, countUp, tag, notes))
+
+	for {
+		select {
+		case <-ctx.Done():
+			bus.Publish(Event{Kind: SessionEnded, Tag: tag, LogPath: logPath})
+			stopProfile()
+			os.Exit(exitAborted)
+		case ev := <-events:
+			bus.Publish(Event{Kind: Tick, Tag: tag, Left: ev.Left, Total: ev.Total})
+			printStatus(tmpl, streamStatus(ev.Left, totalDuration, countUp, tag, notes))
+		case <-t.Done():
+			bus.Publish(Event{Kind: SessionEnded, Tag: tag, LogPath: logPath, Completed: true})
+			printStatus(tmpl, streamStatus(0, totalDuration, countUp, tag, notes))
+			stopProfile()
+			return
+		}
+	}
+}
+
+func printStatus(tmpl *template.Template, s Status) {
+	if err := tmpl.Execute(os.Stdout, s); err != nil {
+		stderr("error: %v\n", err)
+		stopProfile()
+		os.Exit(exitUsage)
+	}
+	os.Stdout.WriteString("\n")
+}
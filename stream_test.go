@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreamStatus(t *testing.T) {
+	tests := []struct {
+		left    time.Duration
+		countUp bool
+		time    string
+	}{
+		{25 * time.Minute, false, "25:00"},
+		{25 * time.Minute, true, "00:00"},
+		{10 * time.Minute, false, "10:00"},
+		{10 * time.Minute, true, "15:00"},
+		{0, true, "25:00"},
+	}
+	for _, tt := range tests {
+		s := streamStatus(tt.left, 25*time.Minute, tt.countUp, "work", "")
+		if s.Time != tt.time {
+			t.Errorf("streamStatus(%v, up %v).Time = %q, want %q", tt.left, tt.countUp, s.Time, tt.time)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// SuggestConfig turns on tag suggestions from the focused window. It is
+// off unless enabled: the title is read once at start, only matched
+// against the rules and never logged or kept.
+//
+//	[suggest]
+//	enabled = true
+//	rules = [
+//	  {match = "IntelliJ|GoLand", tag = "coding"},
+//	  {match = "Slack", tag = "chat"},
+//	]
+type SuggestConfig struct {
+	Enabled bool          `toml:"enabled,omitempty"`
+	Rules   []SuggestRule `toml:"rules,omitempty"`
+}
+
+// SuggestRule suggests Tag when the window title matches Match, a
+// case-insensitive regular expression.
+type SuggestRule struct {
+	Match string `toml:"match"`
+	Tag   string `toml:"tag"`
+}
+
+// activeWindow is the title of the focused window: from xdotool or xprop
+// on X11, swaymsg on Sway and System Events on macOS, which needs the
+// accessibility permission. It is empty when there is no way to tell.
+func activeWindow() string {
+	var cmd *exec.Cmd
+	switch {
+	case runtime.GOOS == "darwin":
+		cmd = exec.Command("osascript", "-e",
+			`tell application "System Events" to get name of first application process whose frontmost is true`)
+	case os.Getenv("SWAYSOCK") != "":
+		return swayFocused()
+	case os.Getenv("DISPLAY") != "":
+		if _, err := exec.LookPath("xdotool"); err == nil {
+			cmd = exec.Command("xdotool", "getactivewindow", "getwindowname")
+		} else {
+			return xpropFocused()
+		}
+	default:
+		return ""
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+var xpropName = regexp.MustCompile(`_NET_WM_NAME\(UTF8_STRING\) = "(.*)"`)
+
+func xpropFocused() string {
+	out, err := exec.Command("xprop", "-root", "_NET_ACTIVE_WINDOW").Output()
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return ""
+	}
+	out, err = exec.Command("xprop", "-id", fields[len(fields)-1], "_NET_WM_NAME").Output()
+	if err != nil {
+		return ""
+	}
+	if m := xpropName.FindSubmatch(out); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+// swayNode is the part of a node of "swaymsg -t get_tree" that is needed
+// to find the focused window.
+type swayNode struct {
+	Name          string     `json:"name"`
+	Focused       bool       `json:"focused"`
+	Nodes         []swayNode `json:"nodes"`
+	FloatingNodes []swayNode `json:"floating_nodes"`
+}
+
+func swayFocused() string {
+	out, err := exec.Command("swaymsg", "-t", "get_tree").Output()
+	if err != nil {
+		return ""
+	}
+	var root swayNode
+	if json.Unmarshal(out, &root) != nil {
+		return ""
+	}
+	return root.focused()
+}
+
+func (n swayNode) focused() string {
+	if n.Focused {
+		return n.Name
+	}
+	for _, c := range append(n.Nodes, n.FloatingNodes...) {
+		if name := c.focused(); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// suggestTag asks whether to use the tag of the first rule that matches
+// the focused window, and returns it if so.
+func suggestTag(c SuggestConfig) (string, bool) {
+	title := activeWindow()
+	if title == "" {
+		return "", false
+	}
+	for _, rule := range c.Rules {
+		re, err := regexp.Compile("(?i)" + rule.Match)
+		if err != nil {
+			stderr("error: suggest rule %q: %v\n", rule.Match, err)
+			os.Exit(2)
+		}
+		found := re.FindString(title)
+		if found == "" {
+			continue
+		}
+		stderr("Looks like you're in %s, tag as %q? [Y/n] ", found, rule.Tag)
+		answer, _ := stdin.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "", "y", "yes":
+			return rule.Tag, true
+		}
+		return "", false
+	}
+	return "", false
+}
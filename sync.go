@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/antonmedv/countdown/logbook"
+)
+
+// syncBatch is how many records go in one upload.
+const syncBatch = 1000
+
+// SyncConfig is the [sync] section of the config file: the countdown
+// server the log is uploaded to by countdown sync, and the name of this
+// device there, the host name by default. The token is kept with
+// countdown auth add sync, or given in COUNTDOWN_SYNC_TOKEN.
+//
+//	[sync]
+//	url = "https://countdown.example.com"
+//	device = "laptop"
+type SyncConfig struct {
+	URL    string `toml:"url,omitempty"`
+	Device string `toml:"device,omitempty"`
+}
+
+// syncState is what of the log has been turned into records for a server
+// and device: the device's Lamport clock, the IDs of the records of the
+// events sent, by eventKey, and the records not yet on the server.
+type syncState struct {
+	URL     string              `json:"url"`
+	Device  string              `json:"device"`
+	Clock   uint64              `json:"clock"`
+	Sent    map[string][]string `json:"sent,omitempty"`
+	Pending []syncRecord        `json:"pending,omitempty"`
+}
+
+// syncStatePath is kept with the config rather than in the cache: a
+// device that forgot its state would make records under IDs it has used.
+func syncStatePath() string {
+	name := "sync.json"
+	if profile != "" {
+		name = "sync-" + profile + ".json"
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "countdown", name)
+}
+
+// readSyncStates reads the state of every server and device synced with,
+// or the state of a single one as older versions kept it in the cache.
+func readSyncStates(path string) []syncState {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if dir, err := os.UserCacheDir(); err == nil {
+			b, _ = os.ReadFile(filepath.Join(dir, "countdown", filepath.Base(path)))
+		}
+	}
+	var states []syncState
+	if json.Unmarshal(b, &states) != nil {
+		var state syncState
+		if json.Unmarshal(b, &state) == nil && state.URL != "" {
+			states = []syncState{state}
+		}
+	}
+	return states
+}
+
+// merge catches the state up with the records on the server: the clock
+// moves past every record seen, as a Lamport clock does, and a state that
+// was lost learns back which events of the device the server has.
+func (s *syncState) merge(records []syncRecord) {
+	fresh := s.Clock == 0 && len(s.Sent) == 0 && len(s.Pending) == 0
+	replaced := make(map[string]bool)
+	for _, r := range records {
+		if r.Clock > s.Clock {
+			s.Clock = r.Clock
+		}
+		if r.Replaces != "" {
+			replaced[r.Replaces] = true
+		}
+	}
+	if !fresh {
+		return
+	}
+	for _, r := range records {
+		if r.Device == s.Device && r.Event != nil && !replaced[r.ID] {
+			key := eventKey(*r.Event)
+			s.Sent[key] = append(s.Sent[key], r.ID)
+		}
+	}
+}
+
+// fetchRecords gets every record on the server.
+func fetchRecords(ctx context.Context, url, token string) ([]syncRecord, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{method: http.MethodGet, url: url, status: resp.Status, code: resp.StatusCode}
+	}
+	var u syncUpload
+	if err := json.NewDecoder(resp.Body).Decode(&u); err != nil {
+		return nil, fmt.Errorf("GET %s: %v", url, err)
+	}
+	return u.Records, nil
+}
+
+// syncCommand uploads the changes of the log since the last sync as
+// records: new events, amended ones and tombstones of removed ones. The
+// records are kept until the server has them, so a device may sync
+// whenever it is online, and the server merges them in any order. The
+// state is kept for every server and device, so switching between them
+// picks up where each left off.
+func syncCommand(ctx context.Context, c SyncConfig, logPath string) {
+	if c.URL == "" {
+		stderr("error: sync needs the server's url in [sync] of the config\n")
+		os.Exit(2)
+	}
+	device := c.Device
+	if device == "" {
+		device, _ = os.Hostname()
+		device = strings.SplitN(device, ".", 2)[0]
+	}
+	if !deviceName.MatchString(device) {
+		stderr("error: invalid device %q, set device in [sync] of the config\n", device)
+		os.Exit(2)
+	}
+	token := os.Getenv("COUNTDOWN_SYNC_TOKEN")
+	if token == "" {
+		token = lookupToken("sync")
+	}
+	if token == "" {
+		stderr("error: no token for the server, add one with countdown auth add sync\n")
+		os.Exit(2)
+	}
+
+	events, err := logbook.Read(logPath)
+	if err != nil && !os.IsNotExist(err) {
+		stderr("error: %v\n", err)
+		os.Exit(1)
+	}
+	path := syncStatePath()
+	if path == "" {
+		stderr("error: no config folder to keep the sync state in\n")
+		os.Exit(1)
+	}
+	states := readSyncStates(path)
+	current := -1
+	for i, s := range states {
+		if s.URL == c.URL && s.Device == device {
+			current = i
+		}
+	}
+	if current < 0 {
+		states = append(states, syncState{URL: c.URL, Device: device})
+		current = len(states) - 1
+	}
+	state := &states[current]
+	if state.Sent == nil {
+		state.Sent = make(map[string][]string)
+	}
+
+	url := strings.TrimSuffix(c.URL, "/") + "/v1/records"
+	var remote []syncRecord
+	err = retry(ctx, func(ctx context.Context) (err error) {
+		remote, err = fetchRecords(ctx, url, token)
+		return err
+	})
+	if err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(1)
+	}
+	state.merge(remote)
+	state.Pending = append(state.Pending, diffLog(events, state.Sent, device, &state.Clock)...)
+	// The records are kept before they are sent, so their IDs are never
+	// given to other changes.
+	if err := writeSyncStates(path, states); err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	sent := 0
+	for len(state.Pending) > 0 {
+		batch := state.Pending
+		if len(batch) > syncBatch {
+			batch = batch[:syncBatch]
+		}
+		body, err := json.Marshal(syncUpload{Records: batch})
+		if err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(1)
+		}
+		err = retry(ctx, func(ctx context.Context) error {
+			return sendBody(ctx, http.MethodPost, url, token, "", body)
+		})
+		if err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(1)
+		}
+		state.Pending = state.Pending[len(batch):]
+		sent += len(batch)
+		if err := writeSyncStates(path, states); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("%s sent to %s as %s\n", plural(sent, "record"), c.URL, device)
+}
+
+func writeSyncStates(path string, states []syncState) error {
+	b, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0600)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/antonmedv/countdown/logbook"
+)
+
+func TestSyncStateMerge(t *testing.T) {
+	now := time.Date(2024, 1, 3, 10, 30, 0, 0, time.UTC)
+	start := logbook.Event{State: logbook.Start, Time: now, Tag: "work"}
+	end := logbook.Event{State: logbook.End, Time: now.Add(25 * time.Minute), Tag: "work"}
+	other := logbook.Event{State: logbook.Start, Time: now, Tag: "home"}
+	remote := []syncRecord{
+		{ID: "laptop:1", Device: "laptop", Clock: 1, Event: &start},
+		{ID: "laptop:2", Device: "laptop", Clock: 2, Event: &end},
+		{ID: "laptop:3", Device: "laptop", Clock: 3, Replaces: "laptop:2"},
+		{ID: "phone:7", Device: "phone", Clock: 7, Event: &other},
+	}
+
+	// A device that lost its state learns its events back and goes on
+	// past every clock it has seen.
+	state := syncState{Device: "laptop", Sent: make(map[string][]string)}
+	state.merge(remote)
+	if state.Clock != 7 {
+		t.Errorf("clock = %d, want 7", state.Clock)
+	}
+	records := diffLog([]logbook.Event{start, end}, state.Sent, "laptop", &state.Clock)
+	if len(records) != 1 || records[0].ID != "laptop:8" || records[0].Event == nil || records[0].Event.State != logbook.End {
+		t.Errorf("records = %+v, want the end again as laptop:8", records)
+	}
+
+	// A device that kept its state only moves its clock.
+	state = syncState{Device: "laptop", Clock: 9, Sent: map[string][]string{eventKey(start): {"laptop:1"}}}
+	state.merge(remote)
+	if state.Clock != 9 || len(state.Sent) != 1 {
+		t.Errorf("state = %+v, want it unchanged", state)
+	}
+}
+
+func TestReadSyncStates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sync.json")
+	states := []syncState{{URL: "https://a.example.com", Device: "laptop", Clock: 3}, {URL: "https://b.example.com", Device: "laptop", Clock: 5}}
+	if err := writeSyncStates(path, states); err != nil {
+		t.Fatal(err)
+	}
+	got := readSyncStates(path)
+	if len(got) != 2 || got[0].Clock != 3 || got[1].Clock != 5 {
+		t.Errorf("readSyncStates = %+v", got)
+	}
+
+	b, _ := json.Marshal(syncState{URL: "https://a.example.com", Device: "laptop", Clock: 4})
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	got = readSyncStates(path)
+	if len(got) != 1 || got[0].Clock != 4 {
+		t.Errorf("readSyncStates of a single state = %+v", got)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antonmedv/countdown/logbook"
+)
+
+// syncRecord is the unit of sync. Records are never changed or removed:
+// a device that amends an event of its log sends a record that replaces
+// the old one, and one that undoes an event sends a tombstone, a record
+// that replaces it with nothing. So the records of all devices merge by
+// union, in any order and as often as need be, into the same log.
+//
+// ID is the device and its Lamport clock, which is unique per device and
+// orders a device's records after all it had seen when it made them:
+// countdown sync moves the clock past every record on the server before
+// it makes new ones.
+type syncRecord struct {
+	ID     string         `json:"id"`
+	Device string         `json:"device"`
+	Clock  uint64         `json:"clock"`
+	Event  *logbook.Event `json:"event,omitempty"`
+	// Replaces is the ID of the record this one amends, or undoes when
+	// Event is nil.
+	Replaces string `json:"replaces,omitempty"`
+}
+
+func recordID(device string, clock uint64) string {
+	return fmt.Sprintf("%s:%d", device, clock)
+}
+
+// check rejects a record that could not have come from countdown sync.
+func (r syncRecord) check() error {
+	if !deviceName.MatchString(r.Device) {
+		return fmt.Errorf("invalid device %q", r.Device)
+	}
+	if r.Clock == 0 || r.ID != recordID(r.Device, r.Clock) {
+		return fmt.Errorf("invalid record id %q", r.ID)
+	}
+	if r.Event == nil && r.Replaces == "" {
+		return fmt.Errorf("record %s has neither an event nor a record it replaces", r.ID)
+	}
+	return nil
+}
+
+// eventKey tells events apart; an event sent twice has the same key.
+func eventKey(e logbook.Event) string {
+	return e.State + " " + e.Time.UTC().Format(time.RFC3339Nano) + " " + e.Tag + " " + e.Notes
+}
+
+// liveEvents is what the records make of every device's log: the events
+// no record replaces, in the order they happened.
+func liveEvents(records []syncRecord) map[string][]logbook.Event {
+	replaced := make(map[string]bool)
+	for _, r := range records {
+		if r.Replaces != "" {
+			replaced[r.Replaces] = true
+		}
+	}
+	type live struct {
+		event logbook.Event
+		clock uint64
+	}
+	byDevice := make(map[string][]live)
+	for _, r := range records {
+		if r.Event != nil && !replaced[r.ID] {
+			byDevice[r.Device] = append(byDevice[r.Device], live{*r.Event, r.Clock})
+		}
+	}
+	logs := make(map[string][]logbook.Event, len(byDevice))
+	for device, events := range byDevice {
+		sort.Slice(events, func(i, j int) bool {
+			if !events[i].event.Time.Equal(events[j].event.Time) {
+				return events[i].event.Time.Before(events[j].event.Time)
+			}
+			return events[i].clock < events[j].clock
+		})
+		for _, l := range events {
+			logs[device] = append(logs[device], l.event)
+		}
+	}
+	return logs
+}
+
+// diffLog turns the changes of a log since the last sync into records.
+// sent holds the IDs of the records of the events sent so far, by their
+// keys. An event that is gone is undone, unless an event at the same time
+// and of the same state took its place, which amends it.
+func diffLog(events []logbook.Event, sent map[string][]string, device string, clock *uint64) []syncRecord {
+	left := make(map[string][]string, len(sent))
+	for key, ids := range sent {
+		left[key] = append([]string(nil), ids...)
+	}
+	var added []logbook.Event
+	for _, e := range events {
+		key := eventKey(e)
+		if ids := left[key]; len(ids) > 0 {
+			left[key] = ids[1:]
+			continue
+		}
+		added = append(added, e)
+	}
+	// gone holds the IDs of the events no longer in the log, by the state
+	// and time an amendment keeps.
+	gone := make(map[string][]string)
+	var goneKeys []string
+	for key, ids := range left {
+		for _, id := range ids {
+			at := slot(key)
+			if len(gone[at]) == 0 {
+				goneKeys = append(goneKeys, at)
+			}
+			gone[at] = append(gone[at], id)
+			sent[key] = removeID(sent[key], id)
+			if len(sent[key]) == 0 {
+				delete(sent, key)
+			}
+		}
+	}
+	sort.Strings(goneKeys)
+
+	var records []syncRecord
+	next := func() syncRecord {
+		*clock++
+		return syncRecord{ID: recordID(device, *clock), Device: device, Clock: *clock}
+	}
+	for i := range added {
+		e := added[i]
+		r := next()
+		r.Event = &e
+		at := slot(eventKey(e))
+		if ids := gone[at]; len(ids) > 0 {
+			r.Replaces, gone[at] = ids[0], ids[1:]
+		}
+		sent[eventKey(e)] = append(sent[eventKey(e)], r.ID)
+		records = append(records, r)
+	}
+	for _, at := range goneKeys {
+		for _, id := range gone[at] {
+			r := next()
+			r.Replaces = id
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+// slot is the state and time of an event key.
+func slot(key string) string {
+	parts := strings.SplitN(key, " ", 3)
+	return parts[0] + " " + parts[1]
+}
+
+func removeID(ids []string, id string) []string {
+	for i, v := range ids {
+		if v == id {
+			return append(ids[:i:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
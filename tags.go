@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/antonmedv/countdown/parse"
+	"github.com/gdamore/tcell/v2"
+)
+
+const tagUsage = `
+ countdown tag set <tag> key=value...
+ countdown tag get <tag> [key]
+ countdown tag list
+
+ Keys are color, duration, rate and id.<service>, e.g.
+  countdown tag set work color=#ff8800 duration=50m rate=$80/h id.jira=OPS-12
+ An empty value removes the key.
+`
+
+// TagMeta is what is known about a tag, kept in the tags file so the
+// settings follow the tag rather than being repeated as flags.
+type TagMeta struct {
+	// Color tints the digits, a name or #rrggbb.
+	Color string `toml:"color,omitempty"`
+	// Duration is used when the tag is run without a duration.
+	Duration string `toml:"duration,omitempty"`
+	// Rate is charged like -rate, e.g. "$80/h".
+	Rate string `toml:"rate,omitempty"`
+	// IDs are the tag's names in other services, e.g. jira = "OPS-12".
+	IDs map[string]string `toml:"ids,omitempty"`
+}
+
+// tags is the metadata of every tag, loaded at startup.
+var tags map[string]TagMeta
+
+// tagsPath is next to the config file, so every profile has its own tags.
+func tagsPath() string {
+	path := configPath()
+	if path == "" {
+		return ""
+	}
+	return strings.TrimSuffix(path, ".toml") + ".tags.toml"
+}
+
+// loadTags reads the tags file. A missing file yields no tags.
+func loadTags(path string) (map[string]TagMeta, error) {
+	meta := make(map[string]TagMeta)
+	if path == "" {
+		return meta, nil
+	}
+	_, err := toml.DecodeFile(path, &meta)
+	if errors.Is(err, os.ErrNotExist) {
+		return meta, nil
+	}
+	return meta, err
+}
+
+// set changes one key; an empty value removes it.
+func (m *TagMeta) set(key, value string) error {
+	switch {
+	case key == "color":
+		if value != "" && tcell.GetColor(value) == tcell.ColorDefault {
+			return fmt.Errorf("unknown color %q", value)
+		}
+		m.Color = value
+	case key == "duration":
+		if value != "" {
+			if _, err := parse.Duration([]string{value}, true, ""); err != nil {
+				return err
+			}
+		}
+		m.Duration = value
+	case key == "rate":
+		if value != "" {
+			if _, err := parseRate(value); err != nil {
+				return err
+			}
+		}
+		m.Rate = value
+	case strings.HasPrefix(key, "id.") && len(key) > len("id."):
+		service := strings.TrimPrefix(key, "id.")
+		if value == "" {
+			delete(m.IDs, service)
+			break
+		}
+		if m.IDs == nil {
+			m.IDs = make(map[string]string)
+		}
+		m.IDs[service] = value
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
+
+// fields lists the keys that are set, in a stable order.
+func (m TagMeta) fields() [][2]string {
+	var fields [][2]string
+	if m.Color != "" {
+		fields = append(fields, [2]string{"color", m.Color})
+	}
+	if m.Duration != "" {
+		fields = append(fields, [2]string{"duration", m.Duration})
+	}
+	if m.Rate != "" {
+		fields = append(fields, [2]string{"rate", m.Rate})
+	}
+	services := make([]string, 0, len(m.IDs))
+	for s := range m.IDs {
+		services = append(services, s)
+	}
+	sort.Strings(services)
+	for _, s := range services {
+		fields = append(fields, [2]string{"id." + s, m.IDs[s]})
+	}
+	return fields
+}
+
+// tagColor is the color of the digits for a tag, tcell.ColorDefault if
+// it has none.
+func tagColor(tag string) tcell.Color {
+	if c := tags[tag].Color; c != "" {
+		return tcell.GetColor(c)
+	}
+	return tcell.ColorDefault
+}
+
+func tagCommand(args []string) {
+	fs := flag.NewFlagSet("tag", flag.ExitOnError)
+	fs.StringVar(&profile, "profile", os.Getenv("COUNTDOWN_PROFILE"), "use the tags of a profile")
+	fs.Usage = func() {
+		stderr(tagUsage)
+	}
+	_ = fs.Parse(args)
+	args = fs.Args()
+	if profile != "" && !validProfile(profile) {
+		stderr("error: invalid profile %q\n", profile)
+		os.Exit(2)
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	path := tagsPath()
+	meta, err := loadTags(path)
+	if err != nil {
+		stderr("error: invalid tags file: %v\n", err)
+		os.Exit(2)
+	}
+
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		names := make([]string, 0, len(meta))
+		for name := range meta {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			line := name
+			for _, f := range meta[name].fields() {
+				line += "  " + f[0] + "=" + f[1]
+			}
+			fmt.Println(line)
+		}
+	case args[0] == "get" && (len(args) == 2 || len(args) == 3):
+		m, ok := meta[args[1]]
+		if !ok {
+			stderr("error: no metadata for tag %q\n", args[1])
+			os.Exit(1)
+		}
+		for _, f := range m.fields() {
+			if len(args) == 2 {
+				fmt.Println(f[0] + "=" + f[1])
+			} else if f[0] == args[2] {
+				fmt.Println(f[1])
+				return
+			}
+		}
+		if len(args) == 3 {
+			os.Exit(1)
+		}
+	case args[0] == "set" && len(args) >= 3:
+		m := meta[args[1]]
+		for _, kv := range args[2:] {
+			i := strings.Index(kv, "=")
+			if i < 0 {
+				stderr("error: expected key=value, got %q\n", kv)
+				os.Exit(2)
+			}
+			if err := m.set(kv[:i], kv[i+1:]); err != nil {
+				stderr("error: %v\n", err)
+				os.Exit(2)
+			}
+		}
+		if len(m.fields()) == 0 {
+			delete(meta, args[1])
+		} else {
+			meta[args[1]] = m
+		}
+		if err := saveTOML(path, meta); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
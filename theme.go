@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Theme colors the countdown, with names or #rrggbb; a color left empty
+// is the terminal's. Warn and Critical color the digits once the time
+// left is within -warn and -critical. Themes of one's own go in the
+// config:
+//
+//	theme = "dusk"
+//
+//	[themes.dusk]
+//	digits = "#c0a0ff"
+//	text = "gray"
+//	warn = "orange"
+//	critical = "#ff3050"
+//	fill = "navy"
+type Theme struct {
+	Digits   string `toml:"digits,omitempty"`
+	Text     string `toml:"text,omitempty"`
+	Warn     string `toml:"warn,omitempty"`
+	Critical string `toml:"critical,omitempty"`
+	Fill     string `toml:"fill,omitempty"`
+}
+
+// builtinThemes are the themes -theme knows without a config.
+var builtinThemes = map[string]Theme{
+	"default":   {Warn: "yellow", Critical: "red", Fill: "blue"},
+	"mono":      {Fill: "gray"},
+	"solarized": {Digits: "#268bd2", Text: "#93a1a1", Warn: "#b58900", Critical: "#dc322f", Fill: "#073642"},
+	"dracula":   {Digits: "#bd93f9", Text: "#6272a4", Warn: "#f1fa8c", Critical: "#ff5555", Fill: "#44475a"},
+	"nord":      {Digits: "#88c0d0", Text: "#d8dee9", Warn: "#ebcb8b", Critical: "#bf616a", Fill: "#3b4252"},
+	"gruvbox":   {Digits: "#fabd2f", Text: "#a89984", Warn: "#fe8019", Critical: "#fb4934", Fill: "#3c3836"},
+}
+
+// themeColors are a theme's colors for tcell.
+type themeColors struct {
+	digits, text, warn, critical, fill tcell.Color
+}
+
+var (
+	theme = themeColors{
+		digits: tcell.ColorDefault, text: tcell.ColorDefault,
+		warn: tcell.ColorYellow, critical: tcell.ColorRed, fill: tcell.ColorBlue,
+	}
+	// warnAt and criticalAt are -warn and -critical: how close to its end
+	// a countdown turns the digits to the theme's warn and critical
+	// colors. Zero is never.
+	warnAt, criticalAt time.Duration
+)
+
+// lookupTheme finds a theme of the config, or else a built-in one.
+func lookupTheme(name string, custom map[string]Theme) (themeColors, error) {
+	t, ok := custom[name]
+	if !ok {
+		t, ok = builtinThemes[name]
+	}
+	if !ok {
+		var names []string
+		for n := range builtinThemes {
+			names = append(names, n)
+		}
+		for n := range custom {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return themeColors{}, fmt.Errorf("unknown theme %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	var c themeColors
+	for _, f := range []struct {
+		name  string
+		value string
+		color *tcell.Color
+	}{
+		{"digits", t.Digits, &c.digits},
+		{"text", t.Text, &c.text},
+		{"warn", t.Warn, &c.warn},
+		{"critical", t.Critical, &c.critical},
+		{"fill", t.Fill, &c.fill},
+	} {
+		*f.color = tcell.ColorDefault
+		if f.value == "" {
+			continue
+		}
+		if *f.color = tcell.GetColor(f.value); *f.color == tcell.ColorDefault {
+			return themeColors{}, fmt.Errorf("theme %s: invalid %s color %q", name, f.name, f.value)
+		}
+	}
+	return c, nil
+}
+
+// thresholdColor is the color of the digits with left to go: the theme's
+// critical or warn color within those thresholds, else ok.
+func thresholdColor(left time.Duration, ok tcell.Color) tcell.Color {
+	switch {
+	case criticalAt > 0 && left <= criticalAt && theme.critical != tcell.ColorDefault:
+		return theme.critical
+	case warnAt > 0 && left <= warnAt && theme.warn != tcell.ColorDefault:
+		return theme.warn
+	}
+	return ok
+}
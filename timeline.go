@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/antonmedv/countdown/render"
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	// replayLength is how long a replay takes; short sessions replay in
+	// real time.
+	replayLength = 10 * time.Second
+	replayFrame  = 50 * time.Millisecond
+)
+
+// timelineEntry is one line of a timeline file: what happened, Offset
+// after the session started. Kind is start, pause, resume, change or end.
+type timelineEntry struct {
+	Offset    time.Duration `json:"offset"`
+	Kind      string        `json:"kind"`
+	Time      *time.Time    `json:"time,omitempty"`
+	Tag       string        `json:"tag,omitempty"`
+	Notes     string        `json:"notes,omitempty"`
+	Left      time.Duration `json:"left,omitempty"`
+	Total     time.Duration `json:"total,omitempty"`
+	Completed bool          `json:"completed,omitempty"`
+}
+
+// timelineDir keeps the timelines next to the log.
+func timelineDir(logPath string) string {
+	return filepath.Join(filepath.Dir(logPath), "timelines")
+}
+
+// timelineEvents returns the -timeline subscriber, which writes every
+// session to its own file in timelineDir. A timeline that cannot be
+// written is skipped rather than stopping the countdown.
+func timelineEvents() func(Event) {
+	var f *os.File
+	var enc *json.Encoder
+	var started time.Time
+	return func(e Event) {
+		now := e.Time
+		entry := timelineEntry{Offset: now.Sub(started)}
+		switch e.Kind {
+		case SessionStarted:
+			if f != nil {
+				f.Close()
+			}
+			f, started = nil, now
+			dir := timelineDir(e.LogPath)
+			if os.MkdirAll(dir, 0700) != nil {
+				return
+			}
+			var err error
+			path := filepath.Join(dir, now.Format(sessionID)+".jsonl")
+			if f, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600); err != nil {
+				f = nil
+				return
+			}
+			enc = json.NewEncoder(f)
+			entry = timelineEntry{Kind: "start", Time: &now, Tag: e.Tag, Notes: e.Notes, Left: e.Left, Total: e.Total}
+		case SessionPaused:
+			entry.Kind = "pause"
+		case SessionResumed:
+			entry.Kind = "resume"
+		case SessionChanged:
+			entry.Kind, entry.Left, entry.Total = "change", e.Left, e.Total
+		case SessionEnded:
+			entry.Kind, entry.Notes, entry.Completed = "end", e.Notes, e.Completed
+		default:
+			return
+		}
+		if f == nil {
+			return
+		}
+		_ = enc.Encode(entry)
+		if e.Kind == SessionEnded {
+			f.Close()
+			f = nil
+		}
+	}
+}
+
+func readTimeline(path string) ([]timelineEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []timelineEntry
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		var e timelineEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 || entries[0].Kind != "start" {
+		return nil, fmt.Errorf("%s: not a timeline", path)
+	}
+	return entries, nil
+}
+
+// timelineIDs lists the recorded timelines, oldest first.
+func timelineIDs(logPath string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(timelineDir(logPath), "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(paths))
+	for i, p := range paths {
+		ids[i] = strings.TrimSuffix(filepath.Base(p), ".jsonl")
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// replayCommand lists the timelines, or replays the one with the given id
+// ("last" for the latest) in the TUI.
+func replayCommand(ctx context.Context, logPath string, args []string) {
+	ids, err := timelineIDs(logPath)
+	if err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(args) == 0 {
+		for _, id := range ids {
+			entries, err := readTimeline(filepath.Join(timelineDir(logPath), id+".jsonl"))
+			if err != nil {
+				continue
+			}
+			start, end := entries[0], entries[len(entries)-1]
+			fmt.Printf("%s  %s  %s  %s\n", id, start.Tag, end.Offset.Round(time.Second), start.Notes)
+		}
+		return
+	}
+	id := args[0]
+	if id == "last" && len(ids) > 0 {
+		id = ids[len(ids)-1]
+	}
+	entries, err := readTimeline(filepath.Join(timelineDir(logPath), id+".jsonl"))
+	if err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err = openScreen(); err != nil {
+		fail(err)
+	}
+	defer restoreOnPanic()
+	queues, stopEvents = pollEvents()
+	replay(ctx, entries)
+	stopEvents()
+	closeScreen()
+}
+
+// timelineAt replays the entries up to offset: the time left then, and
+// whether the session was paused.
+func timelineAt(entries []timelineEntry, offset time.Duration) (left time.Duration, paused bool) {
+	last, ended := time.Duration(0), false
+	for _, e := range entries {
+		if e.Offset > offset || ended {
+			break
+		}
+		if !paused {
+			left -= e.Offset - last
+		}
+		last = e.Offset
+		switch e.Kind {
+		case "start", "change":
+			left = e.Left
+		case "pause":
+			paused = true
+		case "resume":
+			paused = false
+		case "end":
+			ended = true
+		}
+	}
+	if !paused && !ended {
+		left -= offset - last
+	}
+	if left < 0 {
+		left = 0
+	}
+	return left, paused
+}
+
+// replay plays the session back in fast motion: the digits as they were,
+// over a bar of the whole session with the pauses shaded and the changes
+// marked. Space pauses the replay, Esc ends it.
+func replay(ctx context.Context, entries []timelineEntry) {
+	end := entries[len(entries)-1].Offset
+	speed := float64(end) / float64(replayLength)
+	if speed < 1 {
+		speed = 1
+	}
+	frame := time.NewTicker(replayFrame)
+	defer frame.Stop()
+	var pos time.Duration
+	playing := true
+	w, h = screen.Size()
+	for {
+		drawReplay(entries, pos, end, w, h)
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-queues:
+			switch ev := ev.(type) {
+			case *tcell.EventResize:
+				w, h = screen.Size()
+			case *tcell.EventKey:
+				if pressed("quit", ev) || pressed("replay_quit", ev) {
+					return
+				}
+				if pressed("replay_play", ev) {
+					if pos >= end {
+						pos = 0
+					}
+					playing = !playing
+				}
+			}
+		case <-frame.C:
+			if playing && pos < end {
+				pos += time.Duration(float64(replayFrame) * speed)
+				if pos > end {
+					pos = end
+				}
+			}
+		}
+	}
+}
+
+func drawReplay(entries []timelineEntry, pos, end time.Duration, w, h int) {
+	clear()
+	start := entries[0]
+	heading := strings.TrimSpace(start.Tag + "  " + start.Notes)
+	if start.Time != nil {
+		heading += "  " + start.Time.Format("Mon Jan 2 15:04")
+	}
+	echoString(heading, w/2-utf8.RuneCountInString(heading)/2, 1, tcell.StyleDefault)
+
+	left, paused := timelineAt(entries, pos)
+	style := tcell.StyleDefault
+	if paused {
+		style = style.Dim(true)
+	}
+	renderer.drawTime(render.Format(left), style, w, h)
+
+	barW, y := w-4, h-4
+	if barW > 0 && end > 0 {
+		for x := 0; x < barW; x++ {
+			r := '█'
+			if _, p := timelineAt(entries, end*time.Duration(x)/time.Duration(barW)); p {
+				r = '░'
+			}
+			setCell(2+x, y, r, tcell.StyleDefault)
+		}
+		for _, e := range entries {
+			if e.Kind == "change" {
+				setCell(2+int(int64(barW-1)*int64(e.Offset)/int64(end)), y-1, '+', tcell.StyleDefault)
+			}
+		}
+		setCell(2+int(int64(barW-1)*int64(pos)/int64(end)), y+1, '▲', tcell.StyleDefault)
+	}
+
+	outcome := "aborted"
+	if last := entries[len(entries)-1]; last.Kind != "end" {
+		outcome = "unfinished"
+	} else if last.Completed {
+		outcome = "completed"
+	}
+	status := fmt.Sprintf("%s / %s  %s  space: pause  Esc: quit", pos.Round(time.Second), end.Round(time.Second), outcome)
+	echoString(status, w/2-utf8.RuneCountInString(status)/2, h-2, tcell.StyleDefault.Dim(true))
+	flush()
+	renderer.present()
+}
+
+// timelineCSVHeader is one row per timeline entry, in long format so the
+// rows load straight into a data frame: times in RFC 3339, durations in
+// seconds and empty cells for missing values.
+var timelineCSVHeader = []string{
+	"session", "session_start", "tag", "notes", "event", "time",
+	"offset_seconds", "left_seconds", "total_seconds", "paused", "completed",
+}
+
+// timelineCSVCommand writes the timelines with the given ids, or all of
+// them, as CSV to stdout.
+func timelineCSVCommand(logPath string, ids []string) {
+	if len(ids) == 0 {
+		var err error
+		if ids, err = timelineIDs(logPath); err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	out := csv.NewWriter(os.Stdout)
+	_ = out.Write(timelineCSVHeader)
+	for _, id := range ids {
+		entries, err := readTimeline(filepath.Join(timelineDir(logPath), id+".jsonl"))
+		if err != nil {
+			stderr("error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, row := range timelineRows(id, entries) {
+			_ = out.Write(row)
+		}
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func timelineRows(id string, entries []timelineEntry) [][]string {
+	seconds := func(d time.Duration) string {
+		return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+	}
+	start := entries[0]
+	var began string
+	if start.Time != nil {
+		began = start.Time.Format(time.RFC3339Nano)
+	}
+	total := start.Total
+	rows := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Kind == "start" || e.Kind == "change" {
+			total = e.Total
+		}
+		var when string
+		if start.Time != nil {
+			when = start.Time.Add(e.Offset).Format(time.RFC3339Nano)
+		}
+		left, paused := timelineAt(entries, e.Offset)
+		var completed string
+		if e.Kind == "end" {
+			completed = strconv.FormatBool(e.Completed)
+		}
+		rows = append(rows, []string{
+			id, began, start.Tag, start.Notes, e.Kind, when,
+			seconds(e.Offset), seconds(left), seconds(total), strconv.FormatBool(paused), completed,
+		})
+	}
+	return rows
+}
@@ -0,0 +1,60 @@
+package timer
+
+import "time"
+
+// Clock is what a Timer tells the time by. The wall time goes on while
+// the machine is suspended and the running time does not, which is how a
+// Timer notices a suspend. Real is the clock of the machine; tests may
+// pass a fake one to NewWithClock, such as countdowntest.Clock.
+type Clock interface {
+	// Now is the wall time.
+	Now() time.Time
+	// Running is the time the machine has been running since some fixed
+	// point, on the monotonic clock.
+	Running() time.Duration
+	// NewTimer fires once after d of running time.
+	NewTimer(d time.Duration) Alarm
+	// NewTicker fires every d of running time, dropping ticks for a slow
+	// receiver.
+	NewTicker(d time.Duration) Alarm
+}
+
+// Alarm is a time.Timer or time.Ticker of a Clock.
+type Alarm interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Real is the clock of the machine.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+// started is the fixed point of the running time.
+var started = time.Now()
+
+func (realClock) Now() time.Time {
+	return time.Now().Round(0)
+}
+
+func (realClock) Running() time.Duration {
+	return time.Since(started)
+}
+
+func (realClock) NewTimer(d time.Duration) Alarm {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Alarm {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.t.C }
+func (t realTimer) Stop()               { t.t.Stop() }
+
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+func (t realTicker) Stop()               { t.t.Stop() }
@@ -0,0 +1,313 @@
+// Package timer is countdown's timer engine. Each Timer has its own clock
+// and its own subscribers, so a program can run any number of them, and
+// tests can run them on a fake Clock.
+package timer
+
+import (
+	"sync"
+	"time"
+)
+
+// Timer is one countdown. A running Timer counts down to a deadline, so
+// a late tick does not make it drift. A paused Timer has nothing that
+// could wake it up.
+type Timer struct {
+	tick    time.Duration
+	clock   Clock
+	ops     chan timerOp
+	started bool
+	done    chan struct{}
+
+	mu        sync.Mutex
+	total     time.Duration
+	left      time.Duration
+	paused    bool
+	completed bool
+	subs      []chan Event
+	// While the Timer counts down, the alarms are armed and deadline is
+	// the running time it ends at. lastWall and lastRunning are when the
+	// Timer last woke up, to tell a suspend by.
+	alarm, ticker Alarm
+	deadline      time.Duration
+	lastWall      time.Time
+	lastRunning   time.Duration
+}
+
+// Event is published to the subscribers on every tick and change.
+type Event struct {
+	Kind  EventKind
+	Left  time.Duration
+	Total time.Duration
+}
+
+type EventKind int
+
+const (
+	Tick EventKind = iota
+	Paused
+	Resumed
+	// Changed is sent when the duration was extended or reset.
+	Changed
+)
+
+type timerOp struct {
+	apply func(t *Timer)
+	ack   chan struct{}
+}
+
+// suspendSlack is how far the wall clock may get ahead of the monotonic
+// clock between two ticks before the difference is taken for a suspend:
+// the monotonic clock stands still while the machine sleeps, the wall
+// clock does not.
+const suspendSlack = time.Second
+
+// subscriberBuffer is how many events a slow subscriber may fall behind;
+// after that it misses events rather than stalling the Timer.
+const subscriberBuffer = 16
+
+// New returns a Timer of d on the Real clock that ticks every tick once
+// started.
+func New(d, tick time.Duration) *Timer {
+	return NewWithClock(d, tick, Real)
+}
+
+// NewWithClock returns a Timer that tells the time by clock.
+func NewWithClock(d, tick time.Duration, clock Clock) *Timer {
+	return &Timer{
+		tick:  tick,
+		clock: clock,
+		ops:   make(chan timerOp),
+		done:  make(chan struct{}),
+		total: d,
+		left:  d,
+	}
+}
+
+// Subscribe returns a channel of the Timer's events. Subscribe before
+// Start to see every event.
+func (t *Timer) Subscribe() <-chan Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := make(chan Event, subscriberBuffer)
+	t.subs = append(t.subs, c)
+	return c
+}
+
+func (t *Timer) Start() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.started {
+		t.started = true
+		t.arm()
+		go t.run()
+	}
+}
+
+// Done is closed once the Timer ran out or was stopped; Completed tells
+// which.
+func (t *Timer) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *Timer) Completed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.completed
+}
+
+func (t *Timer) Left() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.remaining(t.clock.Running())
+}
+
+func (t *Timer) Total() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+func (t *Timer) Paused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused
+}
+
+func (t *Timer) Pause() {
+	t.do(func(t *Timer) {
+		if !t.paused {
+			t.paused = true
+			t.publish(Paused)
+		}
+	})
+}
+
+func (t *Timer) Resume() {
+	t.do(func(t *Timer) {
+		if t.paused {
+			t.paused = false
+			t.publish(Resumed)
+		}
+	})
+}
+
+// Extend adds d to both the time left and the total. A negative d takes
+// time off, though no more than is left.
+func (t *Timer) Extend(d time.Duration) {
+	t.do(func(t *Timer) {
+		if d < -t.left {
+			d = -t.left
+		}
+		t.left += d
+		t.total += d
+		t.publish(Changed)
+	})
+}
+
+// Reset starts over with a new duration d, keeping the pause.
+func (t *Timer) Reset(d time.Duration) {
+	t.do(func(t *Timer) {
+		t.left, t.total = d, d
+		t.publish(Changed)
+	})
+}
+
+// Stop ends the Timer without completing it. It is safe to call more than
+// once, and after the Timer ran out.
+func (t *Timer) Stop() {
+	t.mu.Lock()
+	started := t.started
+	t.started = true
+	t.mu.Unlock()
+	if !started {
+		close(t.done)
+		return
+	}
+	select {
+	case t.ops <- timerOp{}:
+	case <-t.done:
+	}
+	<-t.done
+}
+
+// do runs apply on the Timer's goroutine, with the lock held, and waits
+// for it. It does nothing once the Timer is done.
+func (t *Timer) do(apply func(t *Timer)) {
+	op := timerOp{apply: apply, ack: make(chan struct{})}
+	select {
+	case t.ops <- op:
+		<-op.ack
+	case <-t.done:
+	}
+}
+
+// publish sends an event to every subscriber that has room for it. The
+// lock must be held.
+func (t *Timer) publish(kind EventKind) {
+	ev := Event{Kind: kind, Left: t.left, Total: t.total}
+	for _, c := range t.subs {
+		select {
+		case c <- ev:
+		default:
+		}
+	}
+}
+
+// remaining is the time left at the running time now. The lock must be
+// held.
+func (t *Timer) remaining(now time.Duration) time.Duration {
+	if t.alarm == nil {
+		return t.left
+	}
+	if left := t.deadline - now; left > 0 {
+		return left
+	}
+	return 0
+}
+
+// arm sets the alarms for the time left, unless the Timer is paused. The
+// lock must be held.
+func (t *Timer) arm() {
+	if t.alarm != nil || t.paused {
+		return
+	}
+	t.lastWall, t.lastRunning = t.clock.Now(), t.clock.Running()
+	t.deadline = t.lastRunning + t.left
+	t.alarm, t.ticker = t.clock.NewTimer(t.left), t.clock.NewTicker(t.tick)
+}
+
+// disarm stops the alarms, keeping the time left. The lock must be held.
+func (t *Timer) disarm() {
+	if t.alarm != nil {
+		t.left = t.remaining(t.clock.Running())
+		t.alarm.Stop()
+		t.ticker.Stop()
+		t.alarm, t.ticker = nil, nil
+	}
+}
+
+func (t *Timer) run() {
+	defer close(t.done)
+	defer func() {
+		t.mu.Lock()
+		t.disarm()
+		t.mu.Unlock()
+	}()
+
+	for {
+		t.mu.Lock()
+		var alarmC, tickerC <-chan time.Time
+		if t.alarm != nil {
+			alarmC, tickerC = t.alarm.C(), t.ticker.C()
+		}
+		t.mu.Unlock()
+
+		select {
+		case op := <-t.ops:
+			if op.apply == nil {
+				return
+			}
+			t.mu.Lock()
+			t.left = t.remaining(t.clock.Running())
+			left, paused := t.left, t.paused
+			op.apply(t)
+			if t.left != left || t.paused != paused {
+				// Rearm for the new time left, or not at all while paused.
+				left = t.left
+				t.disarm()
+				t.left = left
+				t.arm()
+			}
+			t.mu.Unlock()
+			close(op.ack)
+		case <-tickerC:
+			t.mu.Lock()
+			wall, running := t.clock.Now(), t.clock.Running()
+			// The alarms go by the running time, so after a suspend the
+			// deadline is moved up by the time slept and rearmed.
+			slept := wall.Sub(t.lastWall) - (running - t.lastRunning)
+			t.lastWall, t.lastRunning = wall, running
+			if slept > suspendSlack {
+				t.deadline -= slept
+			}
+			t.left = t.remaining(running)
+			if t.left == 0 {
+				t.completed = true
+				t.mu.Unlock()
+				return
+			}
+			if slept > suspendSlack {
+				t.disarm()
+				t.arm()
+			}
+			t.publish(Tick)
+			t.mu.Unlock()
+		case <-alarmC:
+			t.mu.Lock()
+			t.disarm()
+			t.left, t.completed = 0, true
+			t.mu.Unlock()
+			return
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package timer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/antonmedv/countdown/countdowntest"
+	"github.com/antonmedv/countdown/timer"
+)
+
+// TestPausedSleeps checks the budget of a paused Timer: no wakeups at
+// all, so not a single event however many ticks go by.
+func TestPausedSleeps(t *testing.T) {
+	tm := timer.New(time.Hour, time.Millisecond)
+	events := tm.Subscribe()
+	defer tm.Stop()
+	tm.Start()
+	tm.Pause()
+	drain(events)
+	time.Sleep(50 * time.Millisecond)
+	if n := len(events); n != 0 {
+		t.Errorf("%d events while paused, want none", n)
+	}
+}
+
+func drain(events <-chan timer.Event) {
+	for {
+		select {
+		case <-events:
+		default:
+			return
+		}
+	}
+}
+
+// step is something done to a Timer on a fake clock.
+type step struct {
+	op string // advance, suspend, pause, resume, extend or reset
+	d  time.Duration
+}
+
+// run starts a Timer of d that ticks every second on a fake clock and
+// takes it through the steps. An advance waits for the tick it causes, or
+// for the end.
+func run(t *testing.T, d time.Duration, steps []step) (*timer.Timer, *countdowntest.Clock) {
+	t.Helper()
+	clock := countdowntest.NewClock(time.Date(2024, 1, 3, 10, 30, 0, 0, time.UTC))
+	tm := timer.NewWithClock(d, time.Second, clock)
+	events := tm.Subscribe()
+	tm.Start()
+	t.Cleanup(tm.Stop)
+	for _, s := range steps {
+		switch s.op {
+		case "advance":
+			drain(events)
+			clock.Advance(s.d)
+			if tm.Paused() || s.d < time.Second {
+				continue
+			}
+			waitTick(t, tm, events)
+		case "suspend":
+			clock.Suspend(s.d)
+		case "pause":
+			tm.Pause()
+		case "resume":
+			tm.Resume()
+		case "extend":
+			tm.Extend(s.d)
+		case "reset":
+			tm.Reset(s.d)
+		default:
+			t.Fatalf("unknown step %q", s.op)
+		}
+	}
+	return tm, clock
+}
+
+func waitTick(t *testing.T, tm *timer.Timer, events <-chan timer.Event) {
+	t.Helper()
+	for {
+		select {
+		case ev := <-events:
+			if ev.Kind == timer.Tick {
+				return
+			}
+		case <-tm.Done():
+			return
+		case <-time.After(time.Second):
+			t.Fatal("no tick")
+		}
+	}
+}
+
+// ended tells whether tm is done, giving it a moment to notice an alarm
+// that is due.
+func ended(tm *timer.Timer) bool {
+	select {
+	case <-tm.Done():
+		return true
+	case <-time.After(100 * time.Millisecond):
+		return false
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []step
+		left  time.Duration
+		done  bool
+	}{
+		{"running", []step{{"advance", 3 * time.Second}}, 7 * time.Second, false},
+		{"paused", []step{{"advance", 3 * time.Second}, {"pause", 0}, {"advance", time.Hour}}, 7 * time.Second, false},
+		{"resumed", []step{{"advance", 3 * time.Second}, {"pause", 0}, {"advance", time.Hour}, {"resume", 0}, {"advance", 3 * time.Second}}, 4 * time.Second, false},
+		{"paused twice", []step{{"pause", 0}, {"pause", 0}, {"advance", time.Hour}, {"resume", 0}, {"resume", 0}, {"advance", 2 * time.Second}}, 8 * time.Second, false},
+		{"paused from the start", []step{{"pause", 0}, {"advance", time.Minute}}, 10 * time.Second, false},
+		{"resumed to the end", []step{{"pause", 0}, {"advance", time.Minute}, {"resume", 0}, {"advance", 10 * time.Second}}, 0, true},
+		{"between ticks", []step{{"advance", 1500 * time.Millisecond}, {"pause", 0}, {"resume", 0}, {"advance", time.Second}}, 7500 * time.Millisecond, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm, _ := run(t, 10*time.Second, tt.steps)
+			if done := ended(tm); done != tt.done {
+				t.Errorf("done = %v, want %v", done, tt.done)
+			}
+			if left := tm.Left(); left != tt.left {
+				t.Errorf("left = %v, want %v", left, tt.left)
+			}
+			if tm.Completed() != tt.done {
+				t.Errorf("completed = %v, want %v", tm.Completed(), tt.done)
+			}
+		})
+	}
+}
+
+func TestExtendNearZero(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []step
+		left  time.Duration
+		total time.Duration
+		done  bool
+	}{
+		{"add", []step{{"advance", 4 * time.Second}, {"extend", 10 * time.Second}}, 11 * time.Second, 15 * time.Second, false},
+		{"add then run out", []step{{"advance", 4 * time.Second}, {"extend", 10 * time.Second}, {"advance", 11 * time.Second}}, 0, 15 * time.Second, true},
+		{"take off some", []step{{"advance", 2 * time.Second}, {"extend", -2 * time.Second}}, time.Second, 3 * time.Second, false},
+		{"take off all", []step{{"advance", 2 * time.Second}, {"extend", -3 * time.Second}}, 0, 2 * time.Second, true},
+		{"take off more than left", []step{{"advance", 2 * time.Second}, {"extend", -10 * time.Second}}, 0, 2 * time.Second, true},
+		{"take off more while paused", []step{{"pause", 0}, {"extend", -10 * time.Second}}, 0, 0, false},
+		{"resume with nothing left", []step{{"pause", 0}, {"extend", -10 * time.Second}, {"resume", 0}}, 0, 0, true},
+		{"reset at the last second", []step{{"advance", 4 * time.Second}, {"reset", time.Minute}}, time.Minute, time.Minute, false},
+		{"reset keeps the pause", []step{{"pause", 0}, {"reset", time.Minute}, {"advance", time.Hour}}, time.Minute, time.Minute, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm, _ := run(t, 5*time.Second, tt.steps)
+			if done := ended(tm); done != tt.done {
+				t.Errorf("done = %v, want %v", done, tt.done)
+			}
+			if left := tm.Left(); left != tt.left {
+				t.Errorf("left = %v, want %v", left, tt.left)
+			}
+			if total := tm.Total(); total != tt.total {
+				t.Errorf("total = %v, want %v", total, tt.total)
+			}
+		})
+	}
+}
+
+// TestIndependentTimers runs timers side by side on one clock, as
+// -parallel and the daemon do: each keeps its own pause and end.
+func TestIndependentTimers(t *testing.T) {
+	clock := countdowntest.NewClock(time.Date(2024, 1, 3, 10, 30, 0, 0, time.UTC))
+	tea := timer.NewWithClock(3*time.Minute, time.Second, clock)
+	eggs := timer.NewWithClock(7*time.Minute, time.Second, clock)
+	teaEvents, eggsEvents := tea.Subscribe(), eggs.Subscribe()
+	for _, tm := range []*timer.Timer{tea, eggs} {
+		tm.Start()
+		defer tm.Stop()
+	}
+
+	eggs.Pause()
+	clock.Advance(time.Minute)
+	waitTick(t, tea, teaEvents)
+	if left := tea.Left(); left != 2*time.Minute {
+		t.Errorf("tea: %v left, want 2m", left)
+	}
+	if left := eggs.Left(); left != 7*time.Minute {
+		t.Errorf("paused eggs: %v left, want 7m", left)
+	}
+
+	eggs.Resume()
+	drain(eggsEvents)
+	clock.Advance(2 * time.Minute)
+	waitTick(t, eggs, eggsEvents)
+	if !ended(tea) || !tea.Completed() {
+		t.Error("tea did not complete")
+	}
+	if left := eggs.Left(); left != 5*time.Minute || ended(eggs) {
+		t.Errorf("eggs: %v left, want 5m and running", left)
+	}
+
+	eggs.Stop()
+	if eggs.Completed() {
+		t.Error("stopped eggs completed")
+	}
+}
+
+// TestSuspend checks the time a suspended machine slept is taken off: the
+// running time stands still while the wall time goes on.
+func TestSuspend(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []step
+		left  time.Duration
+		done  bool
+	}{
+		{"a late tick does not drift", []step{{"advance", 3500 * time.Millisecond}}, 10*time.Minute - 3500*time.Millisecond, false},
+		{"slept", []step{{"advance", time.Minute}, {"suspend", 5 * time.Minute}, {"advance", time.Second}}, 3*time.Minute + 59*time.Second, false},
+		{"slept past the end", []step{{"suspend", 20 * time.Minute}, {"advance", time.Second}}, 0, true},
+		{"rearmed for the new end", []step{{"suspend", 9 * time.Minute}, {"advance", time.Second}, {"advance", 59 * time.Second}}, 0, true},
+		{"a second short of the new end", []step{{"suspend", 9 * time.Minute}, {"advance", time.Second}, {"advance", 58 * time.Second}}, time.Second, false},
+		{"clock jitter", []step{{"suspend", 500 * time.Millisecond}, {"advance", time.Second}}, 10*time.Minute - time.Second, false},
+		{"slept while paused", []step{{"pause", 0}, {"suspend", time.Hour}, {"resume", 0}, {"advance", time.Second}}, 10*time.Minute - time.Second, false},
+		{"slept, then paused", []step{{"suspend", 5 * time.Minute}, {"advance", time.Second}, {"pause", 0}, {"advance", time.Hour}}, 5*time.Minute - time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm, _ := run(t, 10*time.Minute, tt.steps)
+			if done := ended(tm); done != tt.done {
+				t.Errorf("done = %v, want %v", done, tt.done)
+			}
+			if left := tm.Left(); left != tt.left {
+				t.Errorf("left = %v, want %v", left, tt.left)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/antonmedv/countdown/render"
+)
+
+// toastInterval is how often the progress toast is updated; every update
+// starts PowerShell, so not on every tick.
+const toastInterval = 15 * time.Second
+
+// toastTag names the progress toast, so updates replace it in the Action
+// Center instead of piling up.
+const toastTag = "countdown"
+
+const toastTypes = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null;` +
+	`[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null;`
+
+// toastProgressEvents returns the subscriber that keeps a toast with a
+// progress bar in the Action Center on Windows while a session runs, for
+// -notify.
+func toastProgressEvents() func(Event) {
+	var title string
+	var last time.Time
+	var seq int
+	return func(e Event) {
+		var status string
+		switch e.Kind {
+		case SessionStarted:
+			title, seq = e.Tag, 1
+			last = e.Time
+			runToast(showProgressToast(title, e.Left, e.Total, "running", seq))
+			return
+		case SessionPaused:
+			status = "paused"
+		case SessionResumed, SessionChanged:
+			status = "running"
+		case Tick:
+			if e.Time.Sub(last) < toastInterval {
+				return
+			}
+			status = "running"
+		case SessionEnded:
+			runToast(toastTypes + `[Windows.UI.Notifications.ToastNotificationManager]::History.Remove(` +
+				powershellString(toastTag) + `, ` + powershellString(toastTag) + `, 'countdown')`)
+			return
+		default:
+			return
+		}
+		if seq == 0 {
+			return
+		}
+		seq++
+		last = e.Time
+		runToast(updateProgressToast(e.Left, e.Total, status, seq))
+	}
+}
+
+func runToast(script string) {
+	cmd := exec.Command("powershell", "-NoProfile", "-Command", script)
+	if cmd.Start() == nil {
+		go cmd.Wait()
+	}
+}
+
+// toastData binds the progress bar's values; updates with a lower seq
+// than the toast has seen are dropped, so they may arrive out of order.
+func toastData(left, total time.Duration, status string, seq int) string {
+	value := elapsedFraction(left, total)
+	return `$d = New-Object 'System.Collections.Generic.Dictionary[string,string]';` +
+		`$d.Add('progressValue', '` + fmt.Sprintf("%.3f", value) + `');` +
+		`$d.Add('progressValueString', ` + powershellString(render.Format(left)+" left") + `);` +
+		`$d.Add('progressStatus', ` + powershellString(status) + `);` +
+		fmt.Sprintf(`$data = New-Object 'Windows.UI.Notifications.NotificationData' -ArgumentList $d, %d;`, seq)
+}
+
+func showProgressToast(title string, left, total time.Duration, status string, seq int) string {
+	doc := `<toast><visual><binding template="ToastGeneric"><text>` + xmlEscape(title) + `</text>` +
+		`<progress value="{progressValue}" valueStringOverride="{progressValueString}" status="{progressStatus}"/>` +
+		`</binding></visual></toast>`
+	return toastTypes + toastData(left, total, status, seq) +
+		`$xml = New-Object Windows.Data.Xml.Dom.XmlDocument; $xml.LoadXml(` + powershellString(doc) + `);` +
+		`$toast = [Windows.UI.Notifications.ToastNotification]::new($xml);` +
+		`$toast.Tag = ` + powershellString(toastTag) + `; $toast.Group = ` + powershellString(toastTag) + `; $toast.Data = $data;` +
+		`[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('countdown').Show($toast)`
+}
+
+func updateProgressToast(left, total time.Duration, status string, seq int) string {
+	return toastTypes + toastData(left, total, status, seq) +
+		`[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('countdown').Update($data, ` +
+		powershellString(toastTag) + `, ` + powershellString(toastTag) + `) | Out-Null`
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -2,70 +2,131 @@ package main
 
 import (
 	"fmt"
-	"github.com/nsf/termbox-go"
 	"os"
-	"unicode/utf8"
+	"time"
+
+	"github.com/antonmedv/countdown/render"
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
 )
 
-type Symbol []string
+const bellInterval = 500 * time.Millisecond
 
-func (s Symbol) width() int {
-	return utf8.RuneCountInString(s[0])
+func echo(s render.Symbol, startX, startY int, style tcell.Style) {
+	x, y := startX, startY
+	for _, line := range s {
+		for _, r := range line {
+			setCell(x, y, r, style)
+			x++
+		}
+		x = startX
+		y++
+	}
 }
 
-func (s Symbol) height() int {
-	return len(s)
+// fillColumns paints the background of the first cols columns, keeping
+// whatever has already been drawn in them.
+func fillColumns(cols, h int, bg tcell.Color) {
+	for x := 0; x < cols; x++ {
+		for y := 0; y < h; y++ {
+			setBg(x, y, bg)
+		}
+	}
 }
 
-type Text []Symbol
-
-func (t Text) width() int {
-	w := 0
-	for _, s := range t {
-		w += s.width()
+// gradientColor fades from green to red as elapsed goes from 0 to 1.
+func gradientColor(elapsed float64) tcell.Color {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	if elapsed > 1 {
+		elapsed = 1
 	}
-	return w
+	return tcell.NewRGBColor(int32(255*elapsed), int32(255*(1-elapsed)), 0)
 }
 
-func (t Text) height() int {
-	return len(t[0])
+// echoString draws str from x on, giving wide runes two cells.
+func echoString(str string, x, y int, style tcell.Style) {
+	for _, r := range str {
+		setCell(x, y, r, style)
+		x += runewidth.RuneWidth(r)
+	}
 }
 
-func toText(str string) Text {
-	symbols := make(Text, 0)
-	for _, r := range str {
-		if s, ok := defaultFont[r]; ok {
-			symbols = append(symbols, s)
+// pollEvents forwards screen events on the returned channel until stop is
+// called. stop returns once the poller has left PollEvent, so that
+// closeScreen never races it.
+func pollEvents() (<-chan tcell.Event, func()) {
+	events := make(chan tcell.Event)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer restoreOnPanic()
+		for {
+			ev := screen.PollEvent()
+			// Only stop sends interrupts.
+			if _, ok := ev.(*tcell.EventInterrupt); ok || ev == nil {
+				return
+			}
+			select {
+			case events <- ev:
+			case <-done:
+			}
 		}
+	}()
+
+	stop := func() {
+		close(done)
+		_ = screen.PostEvent(tcell.NewEventInterrupt(nil))
+		<-stopped
 	}
-	return symbols
+	return events, stop
 }
 
-type Font map[rune]Symbol
-
-func echo(s Symbol, startX, startY int) {
-	x, y := startX, startY
-	for _, line := range s {
-		for _, r := range line {
-			termbox.SetCell(x, y, r, termbox.ColorDefault, termbox.ColorDefault)
-			x++
-		}
-		x = startX
-		y++
+// restoreOnPanic hands the terminal back before a panic carries on, so
+// the stack trace lands on a usable screen instead of one left in raw mode.
+// It must be deferred directly.
+func restoreOnPanic() {
+	if r := recover(); r != nil {
+		closeScreen()
+		panic(r)
 	}
 }
 
-func clear() {
-	err := termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+// openScreen takes over the terminal.
+func openScreen() error {
+	s, err := render.OpenScreen()
 	if err != nil {
-		panic(err)
+		return err
 	}
+	screen = s
+	return nil
+}
+
+// closeScreen hands the terminal back, if it was taken over.
+func closeScreen() {
+	if screen != nil {
+		screen.Fini()
+		screen = nil
+	}
+}
+
+func clear() {
+	back.reset(screen.Size())
 }
 
 func flush() {
-	err := termbox.Flush()
-	if err != nil {
-		panic(err)
+	present()
+	screen.Show()
+}
+
+func ringBell(n int) {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			time.Sleep(bellInterval)
+		}
+		fmt.Print("\a")
 	}
 }
 
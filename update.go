@@ -0,0 +1,263 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const latestReleaseURL = "https://api.github.com/repos/antonmedv/countdown/releases/latest"
+
+const updateTimeout = 2 * time.Minute
+
+type release struct {
+	Tag    string  `json:"tag_name"`
+	Assets []asset `json:"assets"`
+}
+
+type asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+func selfUpdateCommand(args []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), updateTimeout)
+	defer cancel()
+	if err := selfUpdate(ctx); err != nil {
+		stderr("error: self-update: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func selfUpdate(ctx context.Context) error {
+	var rel release
+	body, err := download(ctx, latestReleaseURL)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, &rel); err != nil {
+		return err
+	}
+
+	current, _, _ := buildInfo()
+	if rel.Tag == current {
+		fmt.Println("countdown", current, "is up to date")
+		return nil
+	}
+
+	bin, sums := findAssets(rel.Assets, runtime.GOOS, runtime.GOARCH)
+	if bin == nil {
+		return fmt.Errorf("release %s has no binary for %s/%s", rel.Tag, runtime.GOOS, runtime.GOARCH)
+	}
+	if sums == nil {
+		return fmt.Errorf("release %s has no checksums, refusing to install", rel.Tag)
+	}
+
+	data, err := download(ctx, bin.URL)
+	if err != nil {
+		return err
+	}
+	sumsData, err := download(ctx, sums.URL)
+	if err != nil {
+		return err
+	}
+	if err := verifyChecksum(data, bin.Name, string(sumsData)); err != nil {
+		return err
+	}
+	if data, err = extractBinary(bin.Name, data); err != nil {
+		return err
+	}
+	if err := replaceExecutable(data); err != nil {
+		return err
+	}
+	fmt.Println("countdown updated from", current, "to", rel.Tag)
+	return nil
+}
+
+func download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// assetFor tells whether the asset name is built for goos and goarch,
+// which it must have as words of their own, so that arm is not taken for
+// arm64, e.g. countdown_linux_arm64.tar.gz.
+func assetFor(name, goos, goarch string) bool {
+	var hasOS, hasArch bool
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		hasOS = hasOS || word == goos
+		hasArch = hasArch || word == goarch
+	}
+	return hasOS && hasArch
+}
+
+// archiveKinds are the suffixes of the assets countdown installs from, by
+// preference: the bare binary first, then the archives it extracts.
+var archiveKinds = []string{"", ".exe", ".tar.gz", ".tgz", ".zip"}
+
+// assetKind is the rank of name in archiveKinds, or -1 for an asset that
+// is not countdown, such as a package or a signature.
+func assetKind(name string) int {
+	for i, kind := range archiveKinds[1:] {
+		if strings.HasSuffix(name, kind) {
+			return i + 1
+		}
+	}
+	// The dots of a version, as in countdown_1.2.3_linux_amd64, are no
+	// extension.
+	i := strings.LastIndex(name, ".")
+	if i < 0 || strings.ContainsAny(name[i:], "_-") || strings.Trim(name[i+1:], "0123456789") == "" {
+		return 0
+	}
+	return -1
+}
+
+// findAssets picks the binary for goos and goarch from the release
+// assets, bare or archived, and the checksums to verify it with: the
+// <binary>.sha256 file, or else the checksums file of the release.
+func findAssets(assets []asset, goos, goarch string) (bin, sums *asset) {
+	for i := range assets {
+		a := &assets[i]
+		name := strings.ToLower(a.Name)
+		kind := assetKind(name)
+		if strings.HasSuffix(name, ".sha256") || kind < 0 || !assetFor(name, goos, goarch) {
+			continue
+		}
+		if bin == nil || kind < assetKind(strings.ToLower(bin.Name)) {
+			bin = a
+		}
+	}
+	if bin == nil {
+		return nil, nil
+	}
+	for i := range assets {
+		a := &assets[i]
+		name := strings.ToLower(a.Name)
+		switch {
+		case name == strings.ToLower(bin.Name)+".sha256":
+			return bin, a
+		case strings.Contains(name, "checksums") && sums == nil:
+			sums = a
+		}
+	}
+	return bin, sums
+}
+
+// verifyChecksum checks data against the "<sha256>  <name>" line for name,
+// or the lone sha256 of a <name>.sha256 file.
+func verifyChecksum(data []byte, name, sums string) error {
+	sum := sha256.Sum256(data)
+	got := hex.EncodeToString(sum[:])
+	scanner := bufio.NewScanner(strings.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 1 || len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			if !strings.EqualFold(fields[0], got) {
+				return fmt.Errorf("checksum mismatch for %s", name)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("no checksum for %s", name)
+}
+
+// extractBinary takes the countdown binary out of the asset name, if it
+// is a .tar.gz or .zip archive, or returns data as is.
+func extractBinary(name string, data []byte) ([]byte, error) {
+	isBinary := func(path string) bool {
+		base := filepath.Base(path)
+		return base == "countdown" || base == "countdown.exe"
+	}
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return nil, fmt.Errorf("no countdown binary in %s", name)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if isBinary(hdr.Name) {
+				return io.ReadAll(tr)
+			}
+		}
+	case strings.HasSuffix(name, ".zip"):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if !isBinary(f.Name) {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		}
+		return nil, fmt.Errorf("no countdown binary in %s", name)
+	}
+	return data, nil
+}
+
+// replaceExecutable swaps the running binary for data. The old binary is
+// moved aside first, which also works on Windows.
+func replaceExecutable(data []byte) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	tmp := exe + ".new"
+	if err := os.WriteFile(tmp, data, 0755); err != nil {
+		return err
+	}
+	old := exe + ".old"
+	_ = os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, exe); err != nil {
+		_ = os.Rename(old, exe)
+		return err
+	}
+	_ = os.Remove(old)
+	return nil
+}
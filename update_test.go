@@ -0,0 +1,124 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestAssetFor(t *testing.T) {
+	tests := []struct {
+		name, goos, goarch string
+		want               bool
+	}{
+		{"countdown_linux_amd64.tar.gz", "linux", "amd64", true},
+		{"countdown-darwin-arm64", "darwin", "arm64", true},
+		{"countdown_windows_amd64.exe", "windows", "amd64", true},
+		{"countdown_linux_arm64.tar.gz", "linux", "arm", false},
+		{"countdown_linux_arm.tar.gz", "linux", "arm", true},
+		{"countdown_linux_386.tar.gz", "linux", "amd64", false},
+		{"countdown_darwin_amd64.tar.gz", "linux", "amd64", false},
+	}
+	for _, tt := range tests {
+		if got := assetFor(tt.name, tt.goos, tt.goarch); got != tt.want {
+			t.Errorf("assetFor(%q, %s, %s) = %v, want %v", tt.name, tt.goos, tt.goarch, got, tt.want)
+		}
+	}
+}
+
+func TestFindAssets(t *testing.T) {
+	tests := []struct {
+		names    []string
+		bin, sum string
+	}{
+		{[]string{"countdown_linux_amd64.tar.gz", "countdown_linux_amd64", "checksums.txt"}, "countdown_linux_amd64", "checksums.txt"},
+		{[]string{"countdown_1.2.3_linux_amd64.zip", "countdown_1.2.3_linux_amd64.tar.gz", "checksums.txt"}, "countdown_1.2.3_linux_amd64.tar.gz", "checksums.txt"},
+		{[]string{"countdown_linux_amd64.deb", "countdown_linux_amd64.zip", "checksums.txt"}, "countdown_linux_amd64.zip", "checksums.txt"},
+		{[]string{"countdown_darwin_arm64.tar.gz.sha256", "countdown_linux_amd64.tar.gz.sha256", "countdown_linux_amd64.tar.gz", "countdown_darwin_arm64.tar.gz"}, "countdown_linux_amd64.tar.gz", "countdown_linux_amd64.tar.gz.sha256"},
+		{[]string{"countdown_linux_amd64.tar.gz", "countdown_linux_amd64.tar.gz.sha256", "checksums.txt"}, "countdown_linux_amd64.tar.gz", "countdown_linux_amd64.tar.gz.sha256"},
+		{[]string{"countdown_linux_amd64.tar.gz", "countdown_darwin_amd64.tar.gz.sha256"}, "countdown_linux_amd64.tar.gz", ""},
+		{[]string{"countdown_linux_arm64.tar.gz", "checksums.txt"}, "", ""},
+	}
+	for _, tt := range tests {
+		var assets []asset
+		for _, name := range tt.names {
+			assets = append(assets, asset{Name: name})
+		}
+		bin, sums := findAssets(assets, "linux", "amd64")
+		var gotBin, gotSum string
+		if bin != nil {
+			gotBin = bin.Name
+		}
+		if sums != nil {
+			gotSum = sums.Name
+		}
+		if gotBin != tt.bin || gotSum != tt.sum {
+			t.Errorf("findAssets(%q) = %q, %q, want %q, %q", tt.names, gotBin, gotSum, tt.bin, tt.sum)
+		}
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("countdown")
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	tests := []struct {
+		sums string
+		ok   bool
+	}{
+		{hash + "  countdown_linux_amd64.tar.gz\n", true},
+		{"0000  countdown_darwin_amd64.tar.gz\n" + hash + " *countdown_linux_amd64.tar.gz\n", true},
+		{hash + "\n", true},
+		{hash + "  countdown_darwin_amd64.tar.gz\n", false},
+		{"0000  countdown_linux_amd64.tar.gz\n", false},
+	}
+	for _, tt := range tests {
+		err := verifyChecksum(data, "countdown_linux_amd64.tar.gz", tt.sums)
+		if (err == nil) != tt.ok {
+			t.Errorf("verifyChecksum with %q: %v, want ok %v", tt.sums, err, tt.ok)
+		}
+	}
+}
+
+func TestExtractBinary(t *testing.T) {
+	var tgz bytes.Buffer
+	gz := gzip.NewWriter(&tgz)
+	tw := tar.NewWriter(gz)
+	for _, f := range []string{"README.md", "countdown_linux_amd64/countdown"} {
+		_ = tw.WriteHeader(&tar.Header{Name: f, Mode: 0755, Size: int64(len(f))})
+		_, _ = tw.Write([]byte(f))
+	}
+	tw.Close()
+	gz.Close()
+
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	for _, f := range []string{"LICENSE", "countdown.exe"} {
+		w, _ := zw.Create(f)
+		_, _ = w.Write([]byte(f))
+	}
+	zw.Close()
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"countdown_linux_amd64.tar.gz", tgz.Bytes(), "countdown_linux_amd64/countdown"},
+		{"countdown_windows_amd64.zip", zipped.Bytes(), "countdown.exe"},
+		{"countdown_linux_amd64", []byte("binary"), "binary"},
+	}
+	for _, tt := range tests {
+		got, err := extractBinary(tt.name, tt.data)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("extractBinary(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := extractBinary("countdown.zip", tgz.Bytes()); err == nil {
+		t.Error("extractBinary of a .tar.gz named .zip: want an error")
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Set with -ldflags "-X main.version=v1.2.3 -X main.commit=... -X main.date=...".
+// When unset they are taken from the build info embedded by the go tool.
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+func buildInfo() (string, string, string) {
+	v, c, d := version, commit, date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v == "" {
+			v = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && c == "":
+				c = s.Value
+			case s.Key == "vcs.time" && d == "":
+				d = s.Value
+			}
+		}
+	}
+	if v == "" || v == "(devel)" {
+		v = "dev"
+	}
+	return v, c, d
+}
+
+func versionCommand(args []string) {
+	v, c, d := buildInfo()
+	fmt.Print("countdown ", v)
+	if c != "" {
+		if len(c) > 12 {
+			c = c[:12]
+		}
+		fmt.Print(" (", c)
+		if d != "" {
+			fmt.Print(", ", d)
+		}
+		fmt.Print(")")
+	}
+	fmt.Println()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// setupWizard asks for the basic settings on first run and writes them to
+// the config file at path.
+func setupWizard(path string) Config {
+	ask := func(question, def string) string {
+		stderr("%s [%s] ", question, def)
+		answer, err := stdin.ReadString('\n')
+		if err != nil && answer == "" {
+			stderr("\n")
+			os.Exit(2)
+		}
+		if answer = strings.TrimSpace(answer); answer == "" {
+			return def
+		}
+		return answer
+	}
+
+	stderr("Welcome to countdown! Let's set up %s.\n\n", path)
+
+	var config Config
+	config.LogPath = expandHome(ask("Where should sessions be logged?", defaultLogPath()))
+	config.Tag = ask("Default tag for sessions without -t?", "Unset")
+	switch strings.ToLower(ask("On completion: [n]othing, [b]ell only or [c]onfetti?", "n")) {
+	case "b", "bell":
+		config.BellOnly = true
+	case "c", "confetti":
+		config.Confetti = true
+	}
+
+	if err := os.MkdirAll(filepath.Dir(config.LogPath), 0700); err != nil {
+		stderr("error: %v\n", err)
+		os.Exit(2)
+	}
+	if err := saveConfig(path, config); err != nil {
+		stderr("error: writing config: %v\n", err)
+		os.Exit(2)
+	}
+	stderr("\nSaved %s.\n\n", path)
+	return config
+}
+
+func defaultLogPath() string {
+	name := "countdown.log"
+	if profile != "" {
+		name = "countdown-" + profile + ".log"
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "countdown", name)
+	}
+	return filepath.Join("~", ".local", "share", "countdown", name)
+}
+
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~"+string(filepath.Separator)) && path != "~" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/antonmedv/countdown/parse"
+	"gopkg.in/yaml.v3"
+)
+
+// stopStep ends a workflow; so does an empty transition.
+const stopStep = "stop"
+
+// Workflow is a set of named timers where the next one depends on how the
+// current one ended:
+//
+//	start: work
+//	steps:
+//	  work: {duration: 25m, tag: Work, on_complete: rest, on_abort: stop}
+//	  rest: {duration: 5m, break: true, on_complete: work}
+type Workflow struct {
+	Start string           `yaml:"start"`
+	Steps map[string]*Step `yaml:"steps"`
+}
+
+type Step struct {
+	Duration   string `yaml:"duration"`
+	Tag        string `yaml:"tag"`
+	Notes      string `yaml:"notes"`
+	Break      bool   `yaml:"break"`
+	OnComplete string `yaml:"on_complete"`
+	OnAbort    string `yaml:"on_abort"`
+
+	duration time.Duration
+}
+
+func loadWorkflow(path string) (*Workflow, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var wf Workflow
+	if err := yaml.Unmarshal(b, &wf); err != nil {
+		return nil, err
+	}
+	if _, ok := wf.Steps[wf.Start]; !ok {
+		return nil, fmt.Errorf("start step %q is not defined", wf.Start)
+	}
+	for name, step := range wf.Steps {
+		if step == nil {
+			return nil, fmt.Errorf("step %q is empty", name)
+		}
+		if step.duration, err = parse.Literal(step.Duration); err != nil || step.duration <= 0 {
+			return nil, fmt.Errorf("step %q: invalid duration %q", name, step.Duration)
+		}
+		for _, next := range []string{step.OnComplete, step.OnAbort} {
+			if _, ok := wf.Steps[next]; !ok && next != "" && next != stopStep {
+				return nil, fmt.Errorf("step %q: unknown next step %q", name, next)
+			}
+		}
+	}
+	return &wf, nil
+}
+
+// runWorkflow runs steps until a transition stops it, and reports whether
+// the last step completed.
+func runWorkflow(ctx context.Context, wf *Workflow, countUp bool, tag string, logPath string) bool {
+	name := wf.Start
+	for {
+		step := wf.Steps[name]
+		stepTag := step.Tag
+		if stepTag == "" {
+			stepTag = tag
+		}
+		isBreak = step.Break && dimBreaks
+		completed := countdown(ctx, step.duration, countUp, stepTag, step.Notes, logPath)
+		if ctx.Err() != nil {
+			// Stopped from outside, not aborted by the user.
+			return false
+		}
+
+		next := step.OnComplete
+		if !completed {
+			next = step.OnAbort
+		}
+		if next == "" || next == stopStep {
+			return completed
+		}
+		name = next
+	}
+}